@@ -84,6 +84,10 @@ func main() {
 			fmt.Printf("%s %s\n", AppName, version)
 			os.Exit(0)
 		}
+		// Let the cloud provider reload its config when the file changes
+		if cloudConfig := cmd.Flags().Lookup("cloud-config"); cloudConfig != nil {
+			ics.SetCloudConfigFile(cloudConfig.Value.String())
+		}
 		innerRun(cmd, args)
 	}
 
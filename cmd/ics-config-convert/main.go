@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The ics-config-convert tool converts an ics.conf gcfg INI cloud config to
+// the versioned YAML format. With -effective it instead prints the config the
+// CCM would run with, after environment variables and defaults are applied,
+// with secrets redacted.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics"
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+)
+
+func main() {
+	input := flag.String("config", "", "ics.conf to convert (default: stdin)")
+	output := flag.String("output", "", "file to write the YAML config to (default: stdout)")
+	effective := flag.Bool("effective", false, "print the effective config, with environment variables and defaults applied and secrets redacted")
+	flag.Parse()
+
+	var in io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var data []byte
+	var err error
+	if *effective {
+		data, err = effectiveConfig(in)
+	} else {
+		data, err = ics.ConvertCPIConfig(in)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := ioutil.WriteFile(*output, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// effectiveConfig reads a config in either format the way the CCM does and
+// returns it with secrets redacted.
+func effectiveConfig(in io.Reader) ([]byte, error) {
+	cfg, err := ics.ReadCPIConfig(in)
+	if err != nil {
+		return nil, err
+	}
+	return icscfg.MarshalRedactedYAMLConfig(cfg)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics"
+)
+
+const testConfig = `
+[Global]
+user = "admin"
+password = "secret"
+datacenters = "dc1"
+
+[VirtualCenter "10.0.0.1"]
+port = "8443"
+password = "other-secret"
+
+[Nodes]
+publish-labels = "host,cluster"
+
+[InstanceType "small"]
+cpu = 2
+memory = 4gb
+`
+
+func TestRoundTrip(t *testing.T) {
+	converted, err := ics.ConvertCPIConfig(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("Converting the config failed: %v", err)
+	}
+
+	fromGcfg, err := effectiveConfig(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("Reading the gcfg config failed: %v", err)
+	}
+	fromYAML, err := effectiveConfig(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatalf("Reading the converted config failed: %v\n%s", err, converted)
+	}
+	if !bytes.Equal(fromGcfg, fromYAML) {
+		t.Errorf("The effective configs differ:\ngcfg:\n%s\nYAML:\n%s", fromGcfg, fromYAML)
+	}
+
+	// The converted config keeps the secrets, the effective one does not
+	if !bytes.Contains(converted, []byte("other-secret")) {
+		t.Errorf("The converted config lost the passwords:\n%s", converted)
+	}
+	if bytes.Contains(fromYAML, []byte("password: secret")) || bytes.Contains(fromYAML, []byte("other-secret")) {
+		t.Errorf("The effective config shows a password:\n%s", fromYAML)
+	}
+	if !bytes.Contains(fromYAML, []byte("<redacted>")) {
+		t.Errorf("The effective config has no redacted value:\n%s", fromYAML)
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	vcfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+// Primary interface policies
+const (
+	// PrimaryInterfaceByDeviceIndex selects the first interface in device
+	// order.
+	PrimaryInterfaceByDeviceIndex = "device-index"
+	// PrimaryInterfaceByMACPrefix selects the first interface whose MAC
+	// address has the configured prefix.
+	PrimaryInterfaceByMACPrefix = "mac-prefix"
+	// PrimaryInterfaceByNetworkRegex selects the first interface whose
+	// network name matches the configured regular expression.
+	PrimaryInterfaceByNetworkRegex = "network-regex"
+	// PrimaryInterfaceByDefaultGateway selects the first interface with a
+	// default gateway.
+	PrimaryInterfaceByDefaultGateway = "default-gateway"
+)
+
+// AddressSelection is the policy used by the built-in address resolvers to
+// select the addresses of a node, from the config and the node annotations.
+// It is computed once per node discovery and shared by the resolvers.
+//
+// IP addresses in the excluded subnets are never used. In dual stack mode the
+// first IP address of each IP family is used instead of the first one, in the
+// order of the IP family priority.
+//
+// NICs are visited in device order, except for the primary interface which
+// comes first, so that the selected addresses and their order are stable.
+type AddressSelection struct {
+	ipFamily               []string
+	dualStack              bool
+	internalNetworkSubnets []*net.IPNet
+	externalNetworkSubnets []*net.IPNet
+	excludeNetworkSubnets  []*net.IPNet
+	internalVMNetworkName  string
+	externalVMNetworkName  string
+	primaryInterface       func(nic *icslib.NIC) bool
+	// invalid annotations of the node, by annotation
+	invalidAnnotations map[string]error
+}
+
+// newAddressSelection returns the address selection policy of the config.
+func newAddressSelection(cpiCfg *CPIConfig, ipFamily []string, dualStack bool) (*AddressSelection, error) {
+	sel := &AddressSelection{ipFamily: ipFamily, dualStack: dualStack}
+	if cpiCfg == nil {
+		return sel, nil
+	}
+
+	var err error
+	if sel.internalNetworkSubnets, err = parseCIDRList(cpiCfg.Nodes.InternalNetworkSubnetCIDR); err != nil {
+		return nil, err
+	}
+	if sel.externalNetworkSubnets, err = parseCIDRList(cpiCfg.Nodes.ExternalNetworkSubnetCIDR); err != nil {
+		return nil, err
+	}
+	if sel.excludeNetworkSubnets, err = parseCIDRList(cpiCfg.Nodes.ExcludeSubnetCIDR); err != nil {
+		return nil, err
+	}
+	sel.internalVMNetworkName = cpiCfg.Nodes.InternalVMNetworkName
+	sel.externalVMNetworkName = cpiCfg.Nodes.ExternalVMNetworkName
+
+	switch cpiCfg.Nodes.PrimaryInterfacePolicy {
+	case PrimaryInterfaceByMACPrefix:
+		prefix := strings.ToLower(cpiCfg.Nodes.PrimaryInterfaceMACPrefix)
+		sel.primaryInterface = func(nic *icslib.NIC) bool {
+			return strings.HasPrefix(nic.MAC, prefix)
+		}
+	case PrimaryInterfaceByNetworkRegex:
+		networkRegex, err := regexp.Compile(cpiCfg.Nodes.PrimaryInterfaceNetworkRegex)
+		if err != nil {
+			return nil, err
+		}
+		sel.primaryInterface = func(nic *icslib.NIC) bool {
+			return networkRegex.MatchString(nic.NetworkName)
+		}
+	case PrimaryInterfaceByDefaultGateway:
+		sel.primaryInterface = func(nic *icslib.NIC) bool {
+			return nic.Gateway != nil && !nic.Gateway.IsUnspecified()
+		}
+	}
+
+	return sel, nil
+}
+
+// addressSelection returns the address selection policy of a node running on
+// the iCenter with vcConfig.
+func (nm *NodeManager) addressSelection(vcConfig *vcfg.VirtualCenterConfig, node *v1.Node) (*AddressSelection, error) {
+	ipFamily := []string{vcfg.DefaultIPFamily}
+	dualStack := false
+	if vcConfig != nil {
+		ipFamily = vcConfig.IPFamilyPriority
+		dualStack = vcConfig.DualStack
+	}
+
+	sel, err := newAddressSelection(nm.config(), ipFamily, dualStack)
+	if err != nil {
+		return nil, err
+	}
+	sel.invalidAnnotations = applyAnnotations(sel, node)
+	return sel, nil
+}
+
+// configured returns true if subnets or a VM network name are set for one of
+// the roles.
+func (sel *AddressSelection) configured() bool {
+	return len(sel.internalNetworkSubnets) > 0 || len(sel.externalNetworkSubnets) > 0 ||
+		sel.internalVMNetworkName != "" || sel.externalVMNetworkName != ""
+}
+
+// orderNICs returns the vNICs in device order with the primary interface
+// first.
+func (sel *AddressSelection) orderNICs(vmNICs []icslib.NIC) []icslib.NIC {
+	nics := make([]icslib.NIC, 0, len(vmNICs))
+	for _, nic := range vmNICs {
+		if !nic.IsVirtual() {
+			klog.V(4).Infof("Skipping device on network %s because not a vNIC", nic.NetworkName)
+			continue
+		}
+		nics = append(nics, nic)
+	}
+	sort.SliceStable(nics, func(i, j int) bool {
+		return nics[i].DeviceIndex < nics[j].DeviceIndex
+	})
+
+	if sel.primaryInterface == nil {
+		return nics
+	}
+	for i := range nics {
+		if sel.primaryInterface(&nics[i]) {
+			klog.V(4).Infof("Primary interface is %s on network %s", nics[i].MAC, nics[i].NetworkName)
+			primary := nics[i]
+			copy(nics[1:i+1], nics[:i])
+			nics[0] = primary
+			break
+		}
+	}
+	return nics
+}
+
+// subnetsContain returns true if one of the subnets contains ip.
+func subnetsContain(subnets []*net.IPNet, ip string) bool {
+	parsedIP := net.ParseIP(ip)
+	for _, subnet := range subnets {
+		if subnet.Contains(parsedIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// nicIPs returns the usable IP addresses of the NIC, in the order of the IP
+// family priority.
+func (sel *AddressSelection) nicIPs(nic icslib.NIC) []string {
+	nicIPs := make([]string, 0, len(nic.IPs))
+	for _, ipNet := range nic.IPs {
+		nicIPs = append(nicIPs, ipNet.IP.String())
+	}
+
+	var ips []string
+	for _, family := range sel.ipFamily {
+		for _, ip := range returnIPsFromSpecificFamily(family, nicIPs) {
+			if subnetsContain(sel.excludeNetworkSubnets, ip) {
+				klog.V(4).Infof("Skipping IP %s because it is in an excluded subnet", ip)
+				continue
+			}
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// ipFamilyOf returns the IP family of a valid IP address.
+func ipFamilyOf(ip string) string {
+	if net.ParseIP(ip).To4() != nil {
+		return vcfg.IPv4Family
+	}
+	return vcfg.IPv6Family
+}
+
+// roleAddresses returns the addresses of the role selected on the NICs: with
+// subnets every IP address in one of them, else with a VM network name the
+// first IP address on that network, else the first IP address.
+func (sel *AddressSelection) roleAddresses(addressType v1.NodeAddressType, subnets []*net.IPNet, vmNetworkName string, nics []icslib.NIC) []v1.NodeAddress {
+	var addrs []v1.NodeAddress
+	var candidates []string
+	for _, nic := range nics {
+		if len(subnets) == 0 && vmNetworkName != "" && !strings.EqualFold(vmNetworkName, nic.NetworkName) {
+			klog.V(4).Infof("Skipping vNIC Network=%s for %s because it doesn't match network %s",
+				nic.NetworkName, addressType, vmNetworkName)
+			continue
+		}
+
+		for _, ip := range sel.nicIPs(nic) {
+			if len(subnets) == 0 {
+				candidates = append(candidates, ip)
+			} else if subnetsContain(subnets, ip) {
+				klog.V(2).Infof("Adding %s by AddressMatching: %s", addressType, ip)
+				addrs = append(addrs, v1.NodeAddress{Type: addressType, Address: ip})
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return addrs
+	}
+	if !sel.dualStack {
+		candidates = candidates[:1]
+	} else {
+		candidates = firstOfEachFamily(candidates, sel.ipFamily)
+	}
+	for _, ip := range candidates {
+		if vmNetworkName != "" {
+			klog.V(2).Infof("Adding %s by NetworkName: %s", addressType, ip)
+		} else {
+			klog.V(2).Infof("Adding %s: %s", addressType, ip)
+		}
+		addrs = append(addrs, v1.NodeAddress{Type: addressType, Address: ip})
+	}
+	return addrs
+}
+
+// firstOfEachFamily returns the first of the IP addresses of each IP family,
+// in the order of the IP family priority.
+func firstOfEachFamily(ips []string, ipFamily []string) []string {
+	var first []string
+	for _, family := range ipFamily {
+		for _, ip := range ips {
+			if ipFamilyOf(ip) == family {
+				first = append(first, ip)
+				break
+			}
+		}
+	}
+	return first
+}
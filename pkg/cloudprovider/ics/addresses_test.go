@@ -0,0 +1,252 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"reflect"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	v1 "k8s.io/api/core/v1"
+
+	vcfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+func TestParseCIDRList(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", value: ""},
+		{name: "single", value: "10.0.0.0/8", want: []string{"10.0.0.0/8"}},
+		{name: "list with spaces", value: " 10.0.0.0/8, fd00::/64 ,", want: []string{"10.0.0.0/8", "fd00::/64"}},
+		{name: "host bits masked", value: "192.168.1.7/24", want: []string{"192.168.1.0/24"}},
+		{name: "not a CIDR", value: "10.0.0.0/8,10.0.0.1", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subnets, err := parseCIDRList(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseCIDRList() error = %v, wantErr %v", err, test.wantErr)
+			}
+			var got []string
+			for _, subnet := range subnets {
+				got = append(got, subnet.String())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseCIDRList() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSubnetMatching(t *testing.T) {
+	vm := fakeVM(
+		vnic(0, "public", "192.168.1.5,172.17.0.1"),
+		vnic(1, "k8s", "10.1.1.5,10.2.0.5"),
+	)
+	nics := (&AddressSelection{}).orderNICs(vm.NICs())
+
+	tests := []struct {
+		name     string
+		internal string
+		exclude  string
+		want     []v1.NodeAddress
+	}{
+		{
+			name:     "every IP in the subnets",
+			internal: "10.1.0.0/16,10.2.0.0/16",
+			want:     []v1.NodeAddress{internalIP("10.1.1.5"), internalIP("10.2.0.5")},
+		},
+		{
+			name:     "excluded subnet wins",
+			internal: "10.0.0.0/8",
+			exclude:  "10.2.0.0/16",
+			want:     []v1.NodeAddress{internalIP("10.1.1.5")},
+		},
+		{
+			name:     "no IP in the subnets",
+			internal: "172.16.0.0/24",
+		},
+		{
+			name:    "excluded first IP",
+			exclude: "192.168.0.0/16,172.16.0.0/12",
+			want:    []v1.NodeAddress{internalIP("10.1.1.5")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &CPIConfig{}
+			cfg.Nodes.InternalNetworkSubnetCIDR = test.internal
+			cfg.Nodes.ExcludeSubnetCIDR = test.exclude
+			sel, err := newAddressSelection(cfg, []string{vcfg.IPv4Family}, false)
+			if err != nil {
+				t.Fatalf("newAddressSelection() failed: %v", err)
+			}
+			got := sel.roleAddresses(v1.NodeInternalIP, sel.internalNetworkSubnets, "", nics)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("roleAddresses() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIPFamilySelection(t *testing.T) {
+	dualStackVM := fakeVM(
+		vnic(0, "public", "192.168.1.5,fe80::5,2001:db8::5"),
+		vnic(1, "k8s", "10.1.1.5,2001:db8:1::5"),
+	)
+	ipv4VM := fakeVM(vnic(0, "public", "192.168.1.5"))
+	ipv6VM := fakeVM(vnic(0, "public", "fe80::5,2001:db8::5"))
+
+	tests := []struct {
+		name      string
+		vm        string
+		ipFamily  []string
+		dualStack bool
+		want      []string
+	}{
+		{name: "IPv4 only", vm: "dual", ipFamily: []string{vcfg.IPv4Family}, want: []string{"192.168.1.5"}},
+		{name: "IPv6 only", vm: "dual", ipFamily: []string{vcfg.IPv6Family}, want: []string{"2001:db8::5"}},
+		{name: "IPv6 only without IPv6 address", vm: "ipv4", ipFamily: []string{vcfg.IPv6Family}},
+		{name: "IPv6 first", vm: "dual", ipFamily: []string{vcfg.IPv6Family, vcfg.IPv4Family}, want: []string{"2001:db8::5"}},
+		{
+			name:      "dual stack",
+			vm:        "dual",
+			ipFamily:  []string{vcfg.IPv4Family, vcfg.IPv6Family},
+			dualStack: true,
+			want:      []string{"192.168.1.5", "2001:db8::5"},
+		},
+		{
+			name:      "dual stack IPv6 first",
+			vm:        "dual",
+			ipFamily:  []string{vcfg.IPv6Family, vcfg.IPv4Family},
+			dualStack: true,
+			want:      []string{"2001:db8::5", "192.168.1.5"},
+		},
+		{
+			name:      "dual stack on IPv4 only VM",
+			vm:        "ipv4",
+			ipFamily:  []string{vcfg.IPv4Family, vcfg.IPv6Family},
+			dualStack: true,
+			want:      []string{"192.168.1.5"},
+		},
+		{
+			name:      "dual stack on IPv6 only VM",
+			vm:        "ipv6",
+			ipFamily:  []string{vcfg.IPv4Family, vcfg.IPv6Family},
+			dualStack: true,
+			want:      []string{"2001:db8::5"},
+		},
+	}
+
+	vms := map[string]*icslib.VirtualMachine{"dual": dualStackVM, "ipv4": ipv4VM, "ipv6": ipv6VM}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vcConfig := &vcfg.VirtualCenterConfig{IPFamilyPriority: test.ipFamily, DualStack: test.dualStack}
+			sel := testSelection(t, nil, vcConfig, nil)
+			addrs, err := (&FirstIPResolver{}).ResolveAddresses(vms[test.vm], sel, nil)
+			if err != nil {
+				t.Fatalf("ResolveAddresses() failed: %v", err)
+			}
+
+			var want []v1.NodeAddress
+			for _, ip := range test.want {
+				want = append(want, externalIP(ip))
+			}
+			for _, ip := range test.want {
+				want = append(want, internalIP(ip))
+			}
+			if !reflect.DeepEqual(addrs, want) {
+				t.Errorf("ResolveAddresses() = %v, want %v", addrs, want)
+			}
+		})
+	}
+}
+
+func TestOrderNICs(t *testing.T) {
+	nics := []tp.Nic{
+		vnic(2, "storage", "10.2.0.5"),
+		vnic(0, "public", "192.168.1.5"),
+		vnic(1, "k8s", "10.1.1.5"),
+		{Name: "guest0", NetworkName: "docker", IP: "172.17.0.1"},
+	}
+	nics[0].Gateway = "10.2.0.1"
+
+	tests := []struct {
+		name   string
+		policy func(cfg *CPIConfig)
+		want   []string
+	}{
+		{
+			name:   "device index",
+			policy: func(cfg *CPIConfig) {},
+			want:   []string{"public", "k8s", "storage"},
+		},
+		{
+			name: "MAC prefix",
+			policy: func(cfg *CPIConfig) {
+				cfg.Nodes.PrimaryInterfacePolicy = PrimaryInterfaceByMACPrefix
+				cfg.Nodes.PrimaryInterfaceMACPrefix = "FA:16:3E:00:00:01"
+			},
+			want: []string{"k8s", "public", "storage"},
+		},
+		{
+			name: "network regex",
+			policy: func(cfg *CPIConfig) {
+				cfg.Nodes.PrimaryInterfacePolicy = PrimaryInterfaceByNetworkRegex
+				cfg.Nodes.PrimaryInterfaceNetworkRegex = "^stor"
+			},
+			want: []string{"storage", "public", "k8s"},
+		},
+		{
+			name: "default gateway",
+			policy: func(cfg *CPIConfig) {
+				cfg.Nodes.PrimaryInterfacePolicy = PrimaryInterfaceByDefaultGateway
+			},
+			want: []string{"storage", "public", "k8s"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &CPIConfig{}
+			test.policy(cfg)
+			sel, err := newAddressSelection(cfg, []string{vcfg.IPv4Family}, false)
+			if err != nil {
+				t.Fatalf("newAddressSelection() failed: %v", err)
+			}
+
+			// Every rotation of the NICs must give the same order.
+			for shift := range nics {
+				shuffled := append(append([]tp.Nic{}, nics[shift:]...), nics[:shift]...)
+				var got []string
+				for _, nic := range sel.orderNICs(fakeVM(shuffled...).NICs()) {
+					got = append(got, nic.NetworkName)
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("shift %d: orderNICs() = %v, want %v", shift, got, test.want)
+				}
+			}
+		})
+	}
+}
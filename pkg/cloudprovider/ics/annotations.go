@@ -0,0 +1,239 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	vcfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+)
+
+// Node annotations overriding, for a single node, the Nodes section of the
+// config used to select its addresses.
+const (
+	// AnnotationInternalNetwork overrides internal-vm-network-name.
+	AnnotationInternalNetwork = "ics.inspur.com/internal-network"
+	// AnnotationExternalNetwork overrides external-vm-network-name.
+	AnnotationExternalNetwork = "ics.inspur.com/external-network"
+	// AnnotationInternalCIDR overrides internal-network-subnet-cidr.
+	AnnotationInternalCIDR = "ics.inspur.com/internal-cidr"
+	// AnnotationExternalCIDR overrides external-network-subnet-cidr.
+	AnnotationExternalCIDR = "ics.inspur.com/external-cidr"
+	// AnnotationExcludeCIDR overrides exclude-subnet-cidr.
+	AnnotationExcludeCIDR = "ics.inspur.com/exclude-cidr"
+	// AnnotationIPFamily overrides the ip-family of the node's iCenter.
+	AnnotationIPFamily = "ics.inspur.com/ip-family"
+)
+
+// Node annotations recording the labels and taints set from the VM tags, so
+// that they are told apart from the ones set by hand.
+const (
+	// AnnotationManagedLabels is the comma-separated list of the label keys
+	// set from the VM tags.
+	AnnotationManagedLabels = "ics.inspur.com/managed-labels"
+	// AnnotationManagedTaints is the comma-separated list of the taints, as
+	// key:effect, set from the VM tags.
+	AnnotationManagedTaints = "ics.inspur.com/managed-taints"
+)
+
+// Node annotations publishing the disk controllers of the VM, so that disks
+// are not attached to a VM without free slots.
+const (
+	// AnnotationDiskControllers is the comma-separated list of the disk
+	// controllers of the VM, as bus:disks/slots.
+	AnnotationDiskControllers = "ics.inspur.com/disk-controllers"
+	// AnnotationFreeDiskSlots is the number of disks the VM can still take.
+	AnnotationFreeDiskSlots = "ics.inspur.com/free-disk-slots"
+)
+
+const (
+	// EventReasonInvalidAnnotation is the reason of the Events reporting
+	// invalid address selection annotations.
+	EventReasonInvalidAnnotation = "InvalidAnnotation"
+	// EventReasonLabelConflict is the reason of the Events reporting VM tag
+	// labels not set because the node already has the label.
+	EventReasonLabelConflict = "LabelConflict"
+	// EventReasonTaintConflict is the reason of the Events reporting VM tag
+	// taints not set because the node already has the taint.
+	EventReasonTaintConflict = "TaintConflict"
+)
+
+// addressAnnotations are the annotations taken into account when selecting
+// node addresses, including the addresses pinned for the AnnotationResolver.
+var addressAnnotations = []string{
+	AnnotationInternalNetwork,
+	AnnotationExternalNetwork,
+	AnnotationInternalCIDR,
+	AnnotationExternalCIDR,
+	AnnotationExcludeCIDR,
+	AnnotationIPFamily,
+	AnnotationInternalIP,
+	AnnotationExternalIP,
+}
+
+// applyAnnotations overrides the policy with the annotations of node. Invalid
+// values are ignored, leaving the config in effect, and returned by
+// annotation.
+func applyAnnotations(sel *AddressSelection, node *v1.Node) map[string]error {
+	invalid := make(map[string]error)
+	if node == nil {
+		return invalid
+	}
+
+	for _, annotation := range addressAnnotations {
+		value, ok := node.Annotations[annotation]
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch annotation {
+		case AnnotationInternalNetwork, AnnotationExternalNetwork:
+			if value != strings.TrimSpace(value) {
+				err = ErrInvalidNetworkName
+			} else if annotation == AnnotationInternalNetwork {
+				sel.internalVMNetworkName = value
+			} else {
+				sel.externalVMNetworkName = value
+			}
+		case AnnotationInternalCIDR, AnnotationExternalCIDR, AnnotationExcludeCIDR:
+			var subnets []*net.IPNet
+			if subnets, err = parseCIDRList(value); err != nil {
+				break
+			}
+			switch annotation {
+			case AnnotationInternalCIDR:
+				sel.internalNetworkSubnets = subnets
+			case AnnotationExternalCIDR:
+				sel.externalNetworkSubnets = subnets
+			default:
+				sel.excludeNetworkSubnets = subnets
+			}
+		case AnnotationIPFamily:
+			var ipFamily []string
+			if ipFamily, err = vcfg.ParseIPFamily(value); err == nil {
+				sel.ipFamily = ipFamily
+			}
+		default:
+			// Used by the annotation resolver
+			continue
+		}
+
+		if err != nil {
+			invalid[annotation] = err
+			continue
+		}
+		klog.V(4).Infof("Node %s overrides address selection with %s=%q", node.Name, annotation, value)
+	}
+	return invalid
+}
+
+// invalidAnnotationPrefix prefixes the keys of the invalid annotations
+// reported as Events.
+const invalidAnnotationPrefix = "annotations/"
+
+// reportInvalidAnnotations logs the invalid annotations of node and reports
+// them as Events, when their value changes, since nodes are resolved again on
+// every update.
+func (nm *NodeManager) reportInvalidAnnotations(node *v1.Node, invalid map[string]error) {
+	annotations := make([]string, 0, len(invalid))
+	for annotation := range invalid {
+		annotations = append(annotations, annotation)
+	}
+	sort.Strings(annotations)
+
+	current := make(map[string]bool, len(invalid))
+	for _, annotation := range annotations {
+		value := node.Annotations[annotation]
+		key := invalidAnnotationPrefix + annotation
+		current[key] = true
+		klog.Warningf("Ignoring annotation %s=%q of node %s: %v", annotation, value, node.Name, invalid[annotation])
+		nm.recordEventOnChange(node, key, value, v1.EventTypeWarning, EventReasonInvalidAnnotation,
+			"Ignoring annotation %s=%q: %v", annotation, value, invalid[annotation])
+	}
+	nm.resolveEvents(node, invalidAnnotationPrefix, current)
+}
+
+// addressAnnotationsChanged returns true if the address selection annotations
+// differ between the nodes.
+func addressAnnotationsChanged(oldNode, newNode *v1.Node) bool {
+	for _, annotation := range addressAnnotations {
+		oldValue, oldOK := oldNode.Annotations[annotation]
+		newValue, newOK := newNode.Annotations[annotation]
+		if oldOK != newOK || oldValue != newValue {
+			return true
+		}
+	}
+	return false
+}
+
+// recordEventOnChange records an Event on node like recordEvent, unless the
+// Event last recorded for the key had the same value. It reports conditions
+// seen on every sync, like conflicts, once rather than on every sync.
+func (nm *NodeManager) recordEventOnChange(node *v1.Node, key, value string, eventType, reason, messageFmt string, args ...interface{}) {
+	nm.reportedEventsLock.Lock()
+	if nm.reportedEvents == nil {
+		nm.reportedEvents = make(map[string]map[string]string)
+	}
+	reported := nm.reportedEvents[node.Name]
+	if reported == nil {
+		reported = make(map[string]string)
+		nm.reportedEvents[node.Name] = reported
+	}
+	last, ok := reported[key]
+	reported[key] = value
+	nm.reportedEventsLock.Unlock()
+
+	if ok && last == value {
+		return
+	}
+	nm.recordEvent(node, eventType, reason, messageFmt, args...)
+}
+
+// resolveEvents forgets the Events recorded on node by recordEventOnChange
+// for the keys with prefix that are not in current, so that they are
+// recorded again if their condition comes back.
+func (nm *NodeManager) resolveEvents(node *v1.Node, prefix string, current map[string]bool) {
+	nm.reportedEventsLock.Lock()
+	defer nm.reportedEventsLock.Unlock()
+	for key := range nm.reportedEvents[node.Name] {
+		if strings.HasPrefix(key, prefix) && !current[key] {
+			delete(nm.reportedEvents[node.Name], key)
+		}
+	}
+}
+
+// forgetEvents forgets the Events recorded by recordEventOnChange on the node
+// with the name.
+func (nm *NodeManager) forgetEvents(nodeName string) {
+	nm.reportedEventsLock.Lock()
+	defer nm.reportedEventsLock.Unlock()
+	delete(nm.reportedEvents, nodeName)
+}
+
+// recordEvent records an Event on node, if an event recorder is set.
+func (nm *NodeManager) recordEvent(node *v1.Node, eventType, reason, messageFmt string, args ...interface{}) {
+	if nm.eventRecorder == nil {
+		return
+	}
+	nm.eventRecorder.Eventf(node, eventType, reason, messageFmt, args...)
+}
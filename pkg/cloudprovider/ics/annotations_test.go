@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordEventOnChange(t *testing.T) {
+	type report struct {
+		key, value string
+	}
+	tests := []struct {
+		name string
+		// Reports of each sync
+		syncs [][]report
+		want  int
+	}{
+		{
+			name:  "reported once",
+			syncs: [][]report{{{"a", "1"}}, {{"a", "1"}}, {{"a", "1"}}},
+			want:  1,
+		},
+		{
+			name:  "reported again on change",
+			syncs: [][]report{{{"a", "1"}}, {{"a", "2"}}, {{"a", "2"}}},
+			want:  2,
+		},
+		{
+			name:  "reported again after being resolved",
+			syncs: [][]report{{{"a", "1"}}, {}, {{"a", "1"}}},
+			want:  2,
+		},
+		{
+			name:  "keys are independent",
+			syncs: [][]report{{{"a", "1"}, {"b", "1"}}, {{"a", "1"}, {"b", "1"}}},
+			want:  2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			nm := &NodeManager{eventRecorder: recorder}
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+
+			for _, reports := range test.syncs {
+				current := make(map[string]bool)
+				for _, r := range reports {
+					current["test/"+r.key] = true
+					nm.recordEventOnChange(node, "test/"+r.key, r.value, v1.EventTypeWarning, "Test", "%s=%s", r.key, r.value)
+				}
+				nm.resolveEvents(node, "test/", current)
+			}
+
+			if got := len(recorder.Events); got != test.want {
+				t.Errorf("recorded %d Events, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReportInvalidAnnotations(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	nm := &NodeManager{eventRecorder: recorder}
+	node := func(cidr string) *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:        "node",
+			Annotations: map[string]string{AnnotationInternalCIDR: cidr},
+		}}
+	}
+	invalid := map[string]error{AnnotationInternalCIDR: ErrInvalidCIDR}
+
+	for _, step := range []struct {
+		node    *v1.Node
+		invalid map[string]error
+		want    int
+	}{
+		{node("10.0.0.0/33"), invalid, 1},
+		// Node updates resolve the addresses again
+		{node("10.0.0.0/33"), invalid, 1},
+		{node("10.0.0.0/34"), invalid, 2},
+		{node("10.0.0.0/8"), nil, 2},
+		{node("10.0.0.0/34"), invalid, 3},
+	} {
+		nm.reportInvalidAnnotations(step.node, step.invalid)
+		if got := len(recorder.Events); got != step.want {
+			t.Fatalf("%s=%s: recorded %d Events, want %d", AnnotationInternalCIDR,
+				step.node.Annotations[AnnotationInternalCIDR], got, step.want)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	pb "github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics/proto"
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// Errors
+var (
+	// ErrInvalidAntiAffinitySelector is returned when the selector of an
+	// anti-affinity group is empty or not a valid label selector.
+	ErrInvalidAntiAffinitySelector = errors.New("Invalid anti-affinity selector")
+
+	// ErrInvalidAntiAffinitySyncPeriod is returned when the anti-affinity
+	// sync period is not a positive duration.
+	ErrInvalidAntiAffinitySyncPeriod = errors.New("Invalid anti-affinity sync period")
+
+	// ErrAntiAffinityGroupNotFound is returned when the requested
+	// anti-affinity group is not configured.
+	ErrAntiAffinityGroupNotFound = errors.New("Anti-affinity group not found")
+)
+
+// ConditionHostAntiAffinityViolated is the condition of the nodes of
+// anti-affinity groups, true when another node of one of their groups runs
+// on the same host.
+const ConditionHostAntiAffinityViolated v1.NodeConditionType = "HostAntiAffinityViolated"
+
+// Reasons of the HostAntiAffinityViolated condition.
+const (
+	conditionReasonSharedHost   = "SharedHost"
+	conditionReasonSeparateHost = "SeparateHost"
+)
+
+const (
+	// EventReasonHostAntiAffinityViolated is the reason of the Events
+	// reporting a node sharing its host with another node of its
+	// anti-affinity groups.
+	EventReasonHostAntiAffinityViolated = "HostAntiAffinityViolated"
+	// EventReasonHostAntiAffinityResolved is the reason of the Events
+	// reporting a node no longer sharing its host.
+	EventReasonHostAntiAffinityResolved = "HostAntiAffinityResolved"
+)
+
+const (
+	// defaultAntiAffinitySyncPeriod is the default period of the
+	// anti-affinity sync.
+	defaultAntiAffinitySyncPeriod = time.Minute
+
+	// antiAffinityKey is the only key of the anti-affinity queue: placement
+	// is evaluated across all nodes, so bursts of node syncs collapse into
+	// a single evaluation.
+	antiAffinityKey = "all"
+)
+
+// parseAntiAffinitySelector parses the label selector of an anti-affinity
+// group, which must select something.
+func parseAntiAffinitySelector(selector string) (labels.Selector, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, ErrInvalidAntiAffinitySelector
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, ErrInvalidAntiAffinitySelector
+	}
+	return parsed, nil
+}
+
+// validateAntiAffinity checks the anti-affinity groups and their sync
+// period.
+func (cfg *CPIConfig) validateAntiAffinity() []error {
+	var errs []error
+
+	if cfg.Nodes.AntiAffinitySyncPeriod == "" {
+		cfg.Nodes.AntiAffinitySyncPeriod = defaultAntiAffinitySyncPeriod.String()
+	}
+	if period, err := time.ParseDuration(cfg.Nodes.AntiAffinitySyncPeriod); err != nil || period <= 0 {
+		errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", "anti-affinity-sync-period"),
+			cfg.Nodes.AntiAffinitySyncPeriod, ErrInvalidAntiAffinitySyncPeriod))
+	}
+
+	for _, name := range cfg.antiAffinityGroups() {
+		group := cfg.AntiAffinity[name]
+		if group == nil {
+			group = &AntiAffinityConfig{}
+			cfg.AntiAffinity[name] = group
+		}
+		if _, err := parseAntiAffinitySelector(group.Selector); err != nil {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SubsectionPath("AntiAffinity", name, "selector"), group.Selector, err))
+		}
+	}
+
+	return errs
+}
+
+// antiAffinityGroups returns the sorted names of the anti-affinity groups.
+func (cfg *CPIConfig) antiAffinityGroups() []string {
+	names := make([]string, 0, len(cfg.AntiAffinity))
+	for name := range cfg.AntiAffinity {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// antiAffinitySyncPeriod returns the period of the anti-affinity sync.
+func (nm *NodeManager) antiAffinitySyncPeriod() time.Duration {
+	if cpiCfg := nm.config(); cpiCfg != nil {
+		if period, err := time.ParseDuration(cpiCfg.Nodes.AntiAffinitySyncPeriod); err == nil && period > 0 {
+			return period
+		}
+	}
+	return defaultAntiAffinitySyncPeriod
+}
+
+// hostPlacement is the host running the VM of a node.
+type hostPlacement struct {
+	tenantRef  string
+	vcServer   string
+	datacenter string
+	hostID     string
+	host       string
+}
+
+// hostViolation is a host running several nodes of an anti-affinity group.
+type hostViolation struct {
+	group     string
+	placement hostPlacement
+	// names of the nodes, sorted
+	nodes []string
+}
+
+// hostAntiAffinityController reports the nodes of an anti-affinity group
+// that run on the same host, like control-plane or etcd nodes gathered by a
+// DRS migration. Each node of a group has the HostAntiAffinityViolated
+// condition, and Events are recorded when it changes.
+//
+// Placement is evaluated periodically, as VMs move without notice, and when
+// nodes are discovered, updated or removed.
+type hostAntiAffinityController struct {
+	*nodeQueue
+	nodeManager *NodeManager
+	client      clientset.Interface
+	// placementOf returns the host running the VM of a node
+	placementOf func(ctx context.Context, nodeInfo *NodeInfo) (hostPlacement, error)
+
+	// Violations found by the last evaluation, and the groups evaluated
+	violations     []hostViolation
+	groups         map[string]bool
+	violationsLock sync.RWMutex
+}
+
+// newHostAntiAffinityController returns a host anti-affinity controller
+// patching nodes with client.
+func newHostAntiAffinityController(nm *NodeManager, client clientset.Interface) *hostAntiAffinityController {
+	c := &hostAntiAffinityController{
+		nodeManager: nm,
+		client:      client,
+		placementOf: nm.hostPlacement,
+	}
+	c.nodeQueue = newNodeQueue("antiaffinity", c.sync)
+	return c
+}
+
+// Run evaluates placement when queued, and every sync period, until stop is
+// closed.
+func (c *hostAntiAffinityController) Run(stop <-chan struct{}) {
+	c.nodeQueue.RunWithResync(stop, c.nodeManager.antiAffinitySyncPeriod, c.enqueue)
+}
+
+// enqueue queues an evaluation of placement. It is a no-op if c is nil.
+func (c *hostAntiAffinityController) enqueue() {
+	if c == nil {
+		return
+	}
+	c.nodeQueue.enqueue(antiAffinityKey)
+}
+
+// sync finds the hosts running several nodes of an anti-affinity group and
+// patches the HostAntiAffinityViolated condition of the nodes that differ.
+func (c *hostAntiAffinityController) sync(string) error {
+	nm := c.nodeManager
+	cpiCfg := nm.config()
+	if cpiCfg == nil {
+		return nil
+	}
+
+	groups := make(map[string]labels.Selector)
+	for _, name := range cpiCfg.antiAffinityGroups() {
+		selector, err := parseAntiAffinitySelector(cpiCfg.AntiAffinity[name].Selector)
+		if err != nil {
+			continue
+		}
+		groups[name] = selector
+	}
+
+	ctx := context.Background()
+	nodes := make(map[string]*v1.Node)
+	placements := make(map[string]hostPlacement)
+	// group -> tenantRef/hostID -> node names
+	members := make(map[string]map[string][]string)
+	memberOf := make(map[string][]string)
+	for _, uuid := range nm.registeredUUIDs() {
+		nodeInfo, node := nm.discoveredNode(uuid)
+		if node == nil {
+			continue
+		}
+		nodes[node.Name] = node
+
+		for _, name := range sortedGroups(groups) {
+			if !groups[name].Matches(labels.Set(node.Labels)) {
+				continue
+			}
+			placement, ok := placements[node.Name]
+			if !ok {
+				var err error
+				if placement, err = c.placementOf(ctx, nodeInfo); err != nil {
+					klog.Warningf("Failed to read the host of node %s, keeping its anti-affinity condition. err=%v", node.Name, err)
+					delete(nodes, node.Name)
+					break
+				}
+				placements[node.Name] = placement
+			}
+
+			if members[name] == nil {
+				members[name] = make(map[string][]string)
+			}
+			key := placement.tenantRef + "/" + placement.hostID
+			members[name][key] = append(members[name][key], node.Name)
+			memberOf[node.Name] = append(memberOf[node.Name], name)
+		}
+	}
+
+	var violations []hostViolation
+	// node name -> messages of the violations
+	shared := make(map[string][]string)
+	for _, name := range sortedGroups(groups) {
+		keys := make([]string, 0, len(members[name]))
+		for key := range members[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			names := members[name][key]
+			if len(names) < 2 {
+				continue
+			}
+			sort.Strings(names)
+			placement := placements[names[0]]
+			violations = append(violations, hostViolation{group: name, placement: placement, nodes: names})
+			for _, nodeName := range names {
+				shared[nodeName] = append(shared[nodeName], fmt.Sprintf("shares host %s with %s of group %s",
+					placement.host, strings.Join(without(names, nodeName), ", "), name))
+			}
+		}
+	}
+
+	c.violationsLock.Lock()
+	c.violations = violations
+	c.groups = make(map[string]bool, len(groups))
+	for name := range groups {
+		c.groups[name] = true
+	}
+	c.violationsLock.Unlock()
+
+	var errs []error
+	for name, node := range nodes {
+		var condition *v1.NodeCondition
+		switch {
+		case len(shared[name]) > 0:
+			condition = &v1.NodeCondition{
+				Type:    ConditionHostAntiAffinityViolated,
+				Status:  v1.ConditionTrue,
+				Reason:  conditionReasonSharedHost,
+				Message: "Node " + strings.Join(shared[name], "; "),
+			}
+		case len(memberOf[name]) > 0:
+			condition = &v1.NodeCondition{
+				Type:   ConditionHostAntiAffinityViolated,
+				Status: v1.ConditionFalse,
+				Reason: conditionReasonSeparateHost,
+				Message: fmt.Sprintf("No other node of group %s runs on host %s",
+					strings.Join(memberOf[name], ", "), placements[name].host),
+			}
+		}
+		if err := c.patchCondition(node, condition); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// patchCondition sets the HostAntiAffinityViolated condition of the node, or
+// removes it if condition is nil, and records an Event when it turns true or
+// back to false.
+func (c *hostAntiAffinityController) patchCondition(node *v1.Node, condition *v1.NodeCondition) error {
+	var current *v1.NodeCondition
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == ConditionHostAntiAffinityViolated {
+			current = &node.Status.Conditions[i]
+			break
+		}
+	}
+
+	var patch map[string]interface{}
+	switch {
+	case condition == nil && current == nil:
+		return nil
+	case condition == nil:
+		klog.V(2).Infof("Removing condition %s of node %s", ConditionHostAntiAffinityViolated, node.Name)
+		patch = map[string]interface{}{"type": ConditionHostAntiAffinityViolated, "$patch": "delete"}
+	case current != nil && current.Status == condition.Status &&
+		current.Reason == condition.Reason && current.Message == condition.Message:
+		return nil
+	default:
+		now := metav1.Now()
+		condition.LastHeartbeatTime = now
+		condition.LastTransitionTime = now
+		if current != nil && current.Status == condition.Status {
+			condition.LastTransitionTime = current.LastTransitionTime
+		}
+		klog.V(2).Infof("Setting condition %s of node %s to %s: %s", condition.Type, node.Name, condition.Status, condition.Message)
+		patch = map[string]interface{}{
+			"type":               condition.Type,
+			"status":             condition.Status,
+			"reason":             condition.Reason,
+			"message":            condition.Message,
+			"lastHeartbeatTime":  condition.LastHeartbeatTime,
+			"lastTransitionTime": condition.LastTransitionTime,
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"conditions": []interface{}{patch}},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.client.CoreV1().Nodes().Patch(node.Name, types.StrategicMergePatchType, data, "status"); err != nil {
+		return err
+	}
+
+	wasViolated := current != nil && current.Status == v1.ConditionTrue
+	switch {
+	case condition != nil && condition.Status == v1.ConditionTrue && !wasViolated:
+		c.nodeManager.recordEvent(node, v1.EventTypeWarning, EventReasonHostAntiAffinityViolated, "%s", condition.Message)
+	case (condition == nil || condition.Status != v1.ConditionTrue) && wasViolated:
+		c.nodeManager.recordEvent(node, v1.EventTypeNormal, EventReasonHostAntiAffinityResolved,
+			"Node no longer shares its host with another node of its anti-affinity groups")
+	}
+	return nil
+}
+
+// violationsOf returns the violations found by the last evaluation, of the
+// group or of all groups if group is empty.
+func (c *hostAntiAffinityController) violationsOf(group string) ([]hostViolation, error) {
+	c.violationsLock.RLock()
+	defer c.violationsLock.RUnlock()
+
+	if group == "" {
+		return c.violations, nil
+	}
+	if !c.groups[group] {
+		return nil, ErrAntiAffinityGroupNotFound
+	}
+	var violations []hostViolation
+	for _, violation := range c.violations {
+		if violation.group == group {
+			violations = append(violations, violation)
+		}
+	}
+	return violations, nil
+}
+
+// ExportHostViolations transforms the host anti-affinity violations of the
+// group, or of all groups if group is empty, to []*pb.HostViolation.
+func (nm *NodeManager) ExportHostViolations(group string, violations *[]*pb.HostViolation) error {
+	if nm.hostAntiAffinityController == nil {
+		if group != "" {
+			return ErrAntiAffinityGroupNotFound
+		}
+		return nil
+	}
+
+	found, err := nm.hostAntiAffinityController.violationsOf(group)
+	if err != nil {
+		return err
+	}
+	for _, violation := range found {
+		*violations = append(*violations, &pb.HostViolation{
+			Group:      violation.group,
+			Vcenter:    violation.placement.vcServer,
+			Datacenter: violation.placement.datacenter,
+			Host:       violation.placement.host,
+			Nodes:      append([]string(nil), violation.nodes...),
+		})
+	}
+	return nil
+}
+
+// hostPlacement returns the host running the VM of the node, read from
+// iCenter as the VM may have moved.
+func (nm *NodeManager) hostPlacement(ctx context.Context, nodeInfo *NodeInfo) (hostPlacement, error) {
+	vm, err := nm.currentVM(ctx, nodeInfo)
+	if err != nil {
+		return hostPlacement{}, err
+	}
+	if vm.HostID == "" {
+		return hostPlacement{}, icslib.ErrNoHostFound
+	}
+
+	placement := hostPlacement{
+		tenantRef: nodeInfo.tenantRef,
+		vcServer:  nodeInfo.vcServer,
+		hostID:    vm.HostID,
+		host:      vm.HostName,
+	}
+	if nodeInfo.dataCenter != nil && nodeInfo.dataCenter.Datacenter != nil {
+		placement.datacenter = nodeInfo.dataCenter.Name()
+	}
+	if placement.host == "" {
+		placement.host = placement.hostID
+	}
+	return placement, nil
+}
+
+// sortedGroups returns the sorted names of the groups.
+func sortedGroups(groups map[string]labels.Selector) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// without returns the names other than name.
+func without(names []string, name string) []string {
+	others := make([]string, 0, len(names))
+	for _, other := range names {
+		if other != name {
+			others = append(others, other)
+		}
+	}
+	return others
+}
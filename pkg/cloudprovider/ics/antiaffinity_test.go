@@ -0,0 +1,321 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	pb "github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics/proto"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// antiAffinityNode is a registered and discovered node of the anti-affinity
+// tests.
+type antiAffinityNode struct {
+	name   string
+	labels map[string]string
+	// iCenter and host of the VM of the node, which cannot be read if the
+	// host is empty
+	tenantRef string
+	host      string
+	// status of the HostAntiAffinityViolated condition before the sync
+	violated v1.ConditionStatus
+}
+
+// newAntiAffinityTestManager returns a node manager with the anti-affinity
+// groups selecting nodes, and a host anti-affinity controller reading the
+// placement of the nodes.
+func newAntiAffinityTestManager(groups map[string]string, nodes []antiAffinityNode) (*NodeManager, *fake.Clientset, *record.FakeRecorder) {
+	cfg := &CPIConfig{AntiAffinity: make(map[string]*AntiAffinityConfig)}
+	for name, selector := range groups {
+		cfg.AntiAffinity[name] = &AntiAffinityConfig{Selector: selector}
+	}
+	nm := newNodeManager(cfg, nil)
+	recorder := record.NewFakeRecorder(10)
+	nm.eventRecorder = recorder
+
+	client := fake.NewSimpleClientset()
+	placements := make(map[string]antiAffinityNode)
+	for _, testNode := range nodes {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: testNode.name, Labels: testNode.labels}}
+		if testNode.violated != "" {
+			node.Status.Conditions = []v1.NodeCondition{{Type: ConditionHostAntiAffinityViolated, Status: testNode.violated}}
+		}
+		client.Tracker().Add(node)
+
+		uuid := "uuid-" + testNode.name
+		nm.addNodeInfo(&NodeInfo{
+			tenantRef:  testNode.tenantRef,
+			vcServer:   testNode.tenantRef,
+			dataCenter: &icslib.Datacenter{Datacenter: &tp.Datacenter{Name: "dc1"}},
+			UUID:       uuid,
+			NodeName:   testNode.name,
+		})
+		nm.addNode(uuid, node)
+		placements[testNode.name] = testNode
+	}
+
+	nm.hostAntiAffinityController = newHostAntiAffinityController(nm, client)
+	nm.hostAntiAffinityController.placementOf = func(ctx context.Context, nodeInfo *NodeInfo) (hostPlacement, error) {
+		testNode := placements[nodeInfo.NodeName]
+		if testNode.host == "" {
+			return hostPlacement{}, errors.New("host not readable")
+		}
+		return hostPlacement{
+			tenantRef:  testNode.tenantRef,
+			vcServer:   testNode.tenantRef,
+			datacenter: "dc1",
+			hostID:     testNode.host,
+			host:       testNode.host + ".example.com",
+		}, nil
+	}
+	return nm, client, recorder
+}
+
+func TestHostAntiAffinitySync(t *testing.T) {
+	master := map[string]string{"role": "master"}
+	etcdMaster := map[string]string{"role": "master", "etcd": "true"}
+	groups := map[string]string{"masters": "role=master", "etcd": "etcd"}
+
+	// condition is the expected HostAntiAffinityViolated condition of a
+	// node, or none if status is empty
+	type condition struct {
+		status v1.ConditionStatus
+		reason string
+	}
+	type violation struct {
+		group string
+		host  string
+		nodes []string
+	}
+
+	tests := []struct {
+		name           string
+		nodes          []antiAffinityNode
+		wantConditions map[string]condition
+		wantViolations []violation
+		wantEvents     []string
+	}{
+		{
+			name: "nodes of a group on the same host",
+			nodes: []antiAffinityNode{
+				{name: "m1", labels: master, tenantRef: "vc", host: "host-1"},
+				{name: "m2", labels: master, tenantRef: "vc", host: "host-1"},
+				{name: "m3", labels: master, tenantRef: "vc", host: "host-2"},
+			},
+			wantConditions: map[string]condition{
+				"m1": {v1.ConditionTrue, conditionReasonSharedHost},
+				"m2": {v1.ConditionTrue, conditionReasonSharedHost},
+				"m3": {v1.ConditionFalse, conditionReasonSeparateHost},
+			},
+			wantViolations: []violation{{"masters", "host-1.example.com", []string{"m1", "m2"}}},
+			wantEvents: []string{
+				"Warning HostAntiAffinityViolated Node shares host host-1.example.com with m2 of group masters",
+				"Warning HostAntiAffinityViolated Node shares host host-1.example.com with m1 of group masters",
+			},
+		},
+		{
+			name: "nodes of a group on separate hosts",
+			nodes: []antiAffinityNode{
+				{name: "m1", labels: master, tenantRef: "vc", host: "host-1"},
+				{name: "m2", labels: master, tenantRef: "vc", host: "host-2"},
+			},
+			wantConditions: map[string]condition{
+				"m1": {v1.ConditionFalse, conditionReasonSeparateHost},
+				"m2": {v1.ConditionFalse, conditionReasonSeparateHost},
+			},
+		},
+		{
+			name: "same host ID in another iCenter",
+			nodes: []antiAffinityNode{
+				{name: "m1", labels: master, tenantRef: "vc1", host: "host-1"},
+				{name: "m2", labels: master, tenantRef: "vc2", host: "host-1"},
+			},
+			wantConditions: map[string]condition{
+				"m1": {v1.ConditionFalse, conditionReasonSeparateHost},
+				"m2": {v1.ConditionFalse, conditionReasonSeparateHost},
+			},
+		},
+		{
+			name: "nodes of different groups on the same host",
+			nodes: []antiAffinityNode{
+				{name: "m1", labels: master, tenantRef: "vc", host: "host-1"},
+				{name: "e1", labels: map[string]string{"etcd": "true"}, tenantRef: "vc", host: "host-1"},
+			},
+			wantConditions: map[string]condition{
+				"m1": {v1.ConditionFalse, conditionReasonSeparateHost},
+				"e1": {v1.ConditionFalse, conditionReasonSeparateHost},
+			},
+		},
+		{
+			name: "node of several groups",
+			nodes: []antiAffinityNode{
+				{name: "m1", labels: etcdMaster, tenantRef: "vc", host: "host-1"},
+				{name: "m2", labels: etcdMaster, tenantRef: "vc", host: "host-1"},
+				{name: "m3", labels: master, tenantRef: "vc", host: "host-2"},
+			},
+			wantConditions: map[string]condition{
+				"m1": {v1.ConditionTrue, conditionReasonSharedHost},
+				"m2": {v1.ConditionTrue, conditionReasonSharedHost},
+				"m3": {v1.ConditionFalse, conditionReasonSeparateHost},
+			},
+			wantViolations: []violation{
+				{"etcd", "host-1.example.com", []string{"m1", "m2"}},
+				{"masters", "host-1.example.com", []string{"m1", "m2"}},
+			},
+			wantEvents: []string{
+				"Warning HostAntiAffinityViolated Node shares host host-1.example.com with m2 of group etcd; shares host host-1.example.com with m2 of group masters",
+				"Warning HostAntiAffinityViolated Node shares host host-1.example.com with m1 of group etcd; shares host host-1.example.com with m1 of group masters",
+			},
+		},
+		{
+			name: "node of no group loses its condition",
+			nodes: []antiAffinityNode{
+				{name: "w1", labels: map[string]string{"role": "worker"}, tenantRef: "vc", host: "host-1", violated: v1.ConditionTrue},
+			},
+			wantConditions: map[string]condition{"w1": {}},
+			wantEvents: []string{
+				"Normal HostAntiAffinityResolved Node no longer shares its host with another node of its anti-affinity groups",
+			},
+		},
+		{
+			name: "resolved violation",
+			nodes: []antiAffinityNode{
+				{name: "m1", labels: master, tenantRef: "vc", host: "host-1", violated: v1.ConditionTrue},
+				{name: "m2", labels: master, tenantRef: "vc", host: "host-2", violated: v1.ConditionTrue},
+			},
+			wantConditions: map[string]condition{
+				"m1": {v1.ConditionFalse, conditionReasonSeparateHost},
+				"m2": {v1.ConditionFalse, conditionReasonSeparateHost},
+			},
+			wantEvents: []string{
+				"Normal HostAntiAffinityResolved Node no longer shares its host with another node of its anti-affinity groups",
+				"Normal HostAntiAffinityResolved Node no longer shares its host with another node of its anti-affinity groups",
+			},
+		},
+		{
+			name: "node whose host cannot be read keeps its condition",
+			nodes: []antiAffinityNode{
+				{name: "m1", labels: master, tenantRef: "vc", host: "host-1"},
+				{name: "m2", labels: master, tenantRef: "vc", violated: v1.ConditionTrue},
+			},
+			wantConditions: map[string]condition{
+				"m1": {v1.ConditionFalse, conditionReasonSeparateHost},
+				"m2": {status: v1.ConditionTrue},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nm, client, recorder := newAntiAffinityTestManager(groups, test.nodes)
+			c := nm.hostAntiAffinityController
+			if err := c.sync(antiAffinityKey); err != nil {
+				t.Fatalf("sync() failed: %v", err)
+			}
+
+			for name, want := range test.wantConditions {
+				node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("Failed to get node %s: %v", name, err)
+				}
+				var got condition
+				for _, current := range node.Status.Conditions {
+					if current.Type == ConditionHostAntiAffinityViolated {
+						got = condition{current.Status, current.Reason}
+					}
+				}
+				if got != want {
+					t.Errorf("Condition of node %s is %+v, want %+v", name, got, want)
+				}
+			}
+
+			found, err := c.violationsOf("")
+			if err != nil {
+				t.Fatalf("violationsOf() failed: %v", err)
+			}
+			var violations []violation
+			for _, v := range found {
+				violations = append(violations, violation{v.group, v.placement.host, v.nodes})
+			}
+			if !reflect.DeepEqual(violations, test.wantViolations) {
+				t.Errorf("Violations are %+v, want %+v", violations, test.wantViolations)
+			}
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			sort.Strings(events)
+			wantEvents := append([]string(nil), test.wantEvents...)
+			sort.Strings(wantEvents)
+			if !reflect.DeepEqual(events, wantEvents) {
+				t.Errorf("Recorded events %q, want %q", events, wantEvents)
+			}
+		})
+	}
+}
+
+func TestHostAntiAffinityViolationsOf(t *testing.T) {
+	nm, _, _ := newAntiAffinityTestManager(map[string]string{"masters": "role=master", "etcd": "etcd"}, []antiAffinityNode{
+		{name: "m1", labels: map[string]string{"role": "master"}, tenantRef: "vc", host: "host-1"},
+		{name: "m2", labels: map[string]string{"role": "master"}, tenantRef: "vc", host: "host-1"},
+	})
+	if err := nm.hostAntiAffinityController.sync(antiAffinityKey); err != nil {
+		t.Fatalf("sync() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		group   string
+		want    int
+		wantErr error
+	}{
+		{name: "all groups", group: "", want: 1},
+		{name: "group with a violation", group: "masters", want: 1},
+		{name: "group without violations", group: "etcd", want: 0},
+		{name: "unknown group", group: "workers", wantErr: ErrAntiAffinityGroupNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var violations []*pb.HostViolation
+			err := nm.ExportHostViolations(test.group, &violations)
+			if err != test.wantErr {
+				t.Fatalf("ExportHostViolations() = %v, want %v", err, test.wantErr)
+			}
+			if len(violations) != test.want {
+				t.Fatalf("Got %d violations, want %d", len(violations), test.want)
+			}
+			if test.want > 0 && !reflect.DeepEqual(violations[0], &pb.HostViolation{
+				Group: "masters", Vcenter: "vc", Datacenter: "dc1", Host: "host-1.example.com", Nodes: []string{"m1", "m2"},
+			}) {
+				t.Errorf("Violation is %+v", violations[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// Errors
+var (
+	// ErrDuplicateAntiAffinityRuleName is returned when anti-affinity groups
+	// managing DRS rules share a rule name.
+	ErrDuplicateAntiAffinityRuleName = errors.New("Duplicate anti-affinity rule name")
+)
+
+const (
+	// defaultAntiAffinityRulePrefix prefixes the group name to make the
+	// default DRS rule name.
+	defaultAntiAffinityRulePrefix = "kubernetes-"
+
+	// hostClusterTTL is how long the cluster of a host is cached.
+	hostClusterTTL = 10 * time.Minute
+
+	// antiAffinityRuleDescription is the description of the DRS rules made
+	// by the controller. It marks them as owned, so that the rules of
+	// removed groups are deleted even after a restart.
+	antiAffinityRuleDescription = "Managed by the ics cloud provider, do not edit"
+)
+
+// validateAntiAffinityRules sets the default DRS rule names and checks that
+// the groups managing rules do not share one.
+func (cfg *CPIConfig) validateAntiAffinityRules() []error {
+	var errs []error
+
+	groups := make(map[string]string)
+	for _, name := range cfg.antiAffinityGroups() {
+		group := cfg.AntiAffinity[name]
+		if group == nil {
+			continue
+		}
+		if group.RuleName == "" {
+			group.RuleName = defaultAntiAffinityRulePrefix + name
+		}
+		if !group.ManageRule {
+			continue
+		}
+		if _, ok := groups[group.RuleName]; ok {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SubsectionPath("AntiAffinity", name, "rule-name"),
+				group.RuleName, ErrDuplicateAntiAffinityRuleName))
+			continue
+		}
+		groups[group.RuleName] = name
+	}
+
+	return errs
+}
+
+// ruleCluster is an iCenter cluster where the DRS rules are reconciled.
+type ruleCluster struct {
+	tenantRef  string
+	vcServer   string
+	datacenter string
+	clusterID  string
+}
+
+// antiAffinityRuleController keeps a DRS anti-affinity rule for the VMs of
+// the anti-affinity groups with manage-rule set, in each iCenter cluster
+// running two of them or more. Rules are reconciled periodically, and when
+// nodes are discovered, updated or removed.
+//
+// Rules are recognized by their name and the antiAffinityRuleDescription
+// marking them as owned: a rule with the name of a group but without the
+// mark is reported and left alone. The marked rules of groups removed from
+// the config, or no longer managing rules, are deleted, as are the rules left
+// with a single VM. With dry-run set on the group of a rule, the changes to
+// the rule are only logged.
+type antiAffinityRuleController struct {
+	*nodeQueue
+	nodeManager *NodeManager
+
+	// DRS rule operations, of icslib unless replaced
+	listRules  func(ctx context.Context, connection *icslib.ICSConnection, clusterID string) ([]icslib.VMRule, error)
+	createRule func(ctx context.Context, connection *icslib.ICSConnection, rule *icslib.VMRule) error
+	updateRule func(ctx context.Context, connection *icslib.ICSConnection, rule *icslib.VMRule) error
+	deleteRule func(ctx context.Context, connection *icslib.ICSConnection, rule *icslib.VMRule) error
+
+	// The fields below are only used by the single queue worker.
+
+	// Clusters of the registered nodes seen since the start, by
+	// tenantRef/clusterID
+	clusters map[string]ruleCluster
+	// Cluster of the hosts, by tenantRef/hostID
+	hostClusters map[string]hostCluster
+}
+
+// hostCluster is the cached cluster ID of a host.
+type hostCluster struct {
+	clusterID string
+	read      time.Time
+}
+
+// newAntiAffinityRuleController returns a DRS anti-affinity rule controller.
+func newAntiAffinityRuleController(nm *NodeManager) *antiAffinityRuleController {
+	c := &antiAffinityRuleController{
+		nodeManager:  nm,
+		listRules:    icslib.ListVMRules,
+		createRule:   icslib.CreateVMRule,
+		updateRule:   icslib.UpdateVMRule,
+		deleteRule:   icslib.DeleteVMRule,
+		clusters:     make(map[string]ruleCluster),
+		hostClusters: make(map[string]hostCluster),
+	}
+	c.nodeQueue = newNodeQueue("antiaffinityrules", c.sync)
+	return c
+}
+
+// Run reconciles the rules when queued, and every anti-affinity sync period,
+// until stop is closed.
+func (c *antiAffinityRuleController) Run(stop <-chan struct{}) {
+	c.nodeQueue.RunWithResync(stop, c.nodeManager.antiAffinitySyncPeriod, c.enqueue)
+}
+
+// enqueue queues a reconciliation of the rules. It is a no-op if c is nil.
+func (c *antiAffinityRuleController) enqueue() {
+	if c == nil {
+		return
+	}
+	c.nodeQueue.enqueue(antiAffinityKey)
+}
+
+// sync reconciles the DRS rules of the clusters of the registered nodes.
+func (c *antiAffinityRuleController) sync(string) error {
+	nm := c.nodeManager
+	cpiCfg := nm.config()
+	if cpiCfg == nil {
+		return nil
+	}
+
+	// Rule name -> whether it is dry-run, of the managed rules and of all
+	// the rules named by a group
+	managed := make(map[string]bool)
+	dryRun := make(map[string]bool)
+	selectors := make(map[string]labels.Selector)
+	for _, name := range cpiCfg.antiAffinityGroups() {
+		group := cpiCfg.AntiAffinity[name]
+		if group.DryRun {
+			dryRun[group.RuleName] = true
+		}
+		if !group.ManageRule {
+			continue
+		}
+		selector, err := parseAntiAffinitySelector(group.Selector)
+		if err != nil {
+			continue
+		}
+		selectors[group.RuleName] = selector
+		managed[group.RuleName] = group.DryRun
+	}
+
+	ctx := context.Background()
+	// tenantRef/clusterID -> rule name -> VM IDs
+	desired := make(map[string]map[string][]string)
+	occupied := make(map[string]bool)
+	for _, uuid := range nm.registeredUUIDs() {
+		nodeInfo, node := nm.discoveredNode(uuid)
+		if node == nil || nodeInfo.vm == nil || nodeInfo.vm.VirtualMachine == nil {
+			continue
+		}
+		cluster, err := c.nodeCluster(ctx, nodeInfo)
+		if err != nil && len(managed) == 0 {
+			klog.V(4).Infof("Failed to find the cluster of node %s. err=%v", node.Name, err)
+			continue
+		}
+		if err != nil {
+			// Reconciling without the node could drop its VM from a rule
+			klog.Warningf("Failed to find the cluster of node %s, retrying. err=%v", node.Name, err)
+			return err
+		}
+		if cluster.clusterID == "" {
+			continue
+		}
+		key := cluster.tenantRef + "/" + cluster.clusterID
+		c.clusters[key] = cluster
+		occupied[key] = true
+
+		for ruleName, selector := range selectors {
+			if !selector.Matches(labels.Set(node.Labels)) {
+				continue
+			}
+			if desired[key] == nil {
+				desired[key] = make(map[string][]string)
+			}
+			desired[key][ruleName] = append(desired[key][ruleName], nodeInfo.vm.ID)
+		}
+	}
+
+	keys := make([]string, 0, len(c.clusters))
+	for key := range c.clusters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		conn, err := nm.tenantConnection(c.clusters[key].tenantRef)
+		if err == ErrICenterNotFound {
+			klog.V(2).Infof("No longer reconciling the anti-affinity rules of cluster %s, its iCenter was removed", key)
+			delete(c.clusters, key)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := c.reconcileCluster(ctx, conn, c.clusters[key], managed, dryRun, desired[key]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !occupied[key] {
+			delete(c.clusters, key)
+		}
+	}
+	if len(errs) > 0 && len(managed) == 0 {
+		// Only marked rules were cleaned up, which the next resync retries
+		klog.Warningf("Failed to clean up the anti-affinity rules. err=%v", utilerrors.NewAggregate(errs))
+		return nil
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// reconcileCluster creates, updates and deletes the managed rules of the
+// cluster so that each desired rule with two VMs or more exists, and deletes
+// the marked rules that are no longer managed. Only marked rules are changed:
+// the managed rules whose name is taken by an unmarked rule are skipped.
+// managed and dryRun hold the dry-run of the rules by name.
+func (c *antiAffinityRuleController) reconcileCluster(ctx context.Context, conn *icslib.ICSConnection, cluster ruleCluster,
+	managed map[string]bool, dryRun map[string]bool, desired map[string][]string) error {
+	rules, err := c.listRules(ctx, conn, cluster.clusterID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]icslib.VMRule)
+	conflicts := make(map[string]bool)
+	var errs []error
+	for _, rule := range rules {
+		_, isManaged := managed[rule.Name]
+		switch {
+		case rule.Description != antiAffinityRuleDescription:
+			if isManaged {
+				klog.Warningf("Not managing anti-affinity rule %s of cluster %s in datacenter %s of iCenter %s, "+
+					"a rule with this name was not made by the cloud provider", rule.Name, cluster.clusterID,
+					cluster.datacenter, cluster.vcServer)
+				conflicts[rule.Name] = true
+			}
+		case isManaged:
+			existing[rule.Name] = rule
+		case dryRun[rule.Name]:
+			klog.Infof("Dry-run: Deleting anti-affinity rule %s of cluster %s in datacenter %s of iCenter %s, it is no longer managed",
+				rule.Name, cluster.clusterID, cluster.datacenter, cluster.vcServer)
+		default:
+			rule := rule
+			klog.V(2).Infof("Deleting anti-affinity rule %s of cluster %s in datacenter %s of iCenter %s, it is no longer managed",
+				rule.Name, cluster.clusterID, cluster.datacenter, cluster.vcServer)
+			if err := c.deleteRule(ctx, conn, &rule); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	ruleNames := make([]string, 0, len(managed))
+	for ruleName := range managed {
+		ruleNames = append(ruleNames, ruleName)
+	}
+	sort.Strings(ruleNames)
+
+	for _, ruleName := range ruleNames {
+		if conflicts[ruleName] {
+			continue
+		}
+		vmIDs := uniqueSorted(desired[ruleName])
+		current, exists := existing[ruleName]
+
+		rule := icslib.VMRule{
+			ID:          current.ID,
+			Name:        ruleName,
+			Description: antiAffinityRuleDescription,
+			Type:        icslib.VMRuleAntiAffinity,
+			Enabled:     true,
+			ClusterID:   cluster.clusterID,
+			VMIDs:       vmIDs,
+		}
+
+		var action string
+		var apply func(context.Context, *icslib.ICSConnection, *icslib.VMRule) error
+		switch {
+		case len(vmIDs) >= 2 && !exists:
+			action, apply = "Creating", c.createRule
+		case len(vmIDs) >= 2 && (!current.Enabled || current.Type != rule.Type ||
+			current.Description != rule.Description ||
+			!reflect.DeepEqual(uniqueSorted(current.VMIDs), vmIDs)):
+			action, apply = "Updating", c.updateRule
+		case len(vmIDs) < 2 && exists:
+			rule.VMIDs = current.VMIDs
+			action, apply = "Deleting", c.deleteRule
+		default:
+			continue
+		}
+
+		if managed[ruleName] {
+			klog.Infof("Dry-run: %s anti-affinity rule %s of cluster %s in datacenter %s of iCenter %s with VMs %v",
+				action, ruleName, cluster.clusterID, cluster.datacenter, cluster.vcServer, rule.VMIDs)
+			continue
+		}
+		klog.V(2).Infof("%s anti-affinity rule %s of cluster %s in datacenter %s of iCenter %s with VMs %v",
+			action, ruleName, cluster.clusterID, cluster.datacenter, cluster.vcServer, rule.VMIDs)
+		if err := apply(ctx, conn, &rule); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// nodeCluster returns the cluster of the host currently running the VM of the
+// node.
+// The cluster ID is empty if the host is not in a cluster.
+func (c *antiAffinityRuleController) nodeCluster(ctx context.Context, nodeInfo *NodeInfo) (ruleCluster, error) {
+	cluster := ruleCluster{
+		tenantRef: nodeInfo.tenantRef,
+		vcServer:  nodeInfo.vcServer,
+	}
+	if nodeInfo.dataCenter != nil && nodeInfo.dataCenter.Datacenter != nil {
+		cluster.datacenter = nodeInfo.dataCenter.Name()
+	}
+
+	// The cached VM may have been migrated since it was discovered
+	vm, err := c.nodeManager.currentVM(ctx, nodeInfo)
+	if err != nil {
+		return cluster, err
+	}
+	hostKey := nodeInfo.tenantRef + "/" + vm.HostID
+	cached, ok := c.hostClusters[hostKey]
+	if !ok || time.Since(cached.read) > hostClusterTTL {
+		conn, err := c.nodeManager.connection(nodeInfo)
+		if err != nil {
+			return cluster, err
+		}
+		host, err := vm.HostSystem(ctx, conn)
+		if err != nil {
+			return cluster, err
+		}
+		cached = hostCluster{clusterID: host.ClusterID, read: time.Now()}
+		c.hostClusters[hostKey] = cached
+	}
+	cluster.clusterID = cached.clusterID
+	return cluster, nil
+}
+
+// uniqueSorted returns the sorted values without duplicates.
+func uniqueSorted(values []string) []string {
+	unique := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
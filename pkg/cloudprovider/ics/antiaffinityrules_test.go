@@ -0,0 +1,178 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+func TestReconcileCluster(t *testing.T) {
+	cluster := ruleCluster{tenantRef: "vc", vcServer: "vc", datacenter: "dc1", clusterID: "cluster-1"}
+	rule := func(id, name, description string, vmIDs ...string) icslib.VMRule {
+		return icslib.VMRule{ID: id, Name: name, Description: description, Type: icslib.VMRuleAntiAffinity,
+			Enabled: true, ClusterID: cluster.clusterID, VMIDs: vmIDs}
+	}
+	const masters = "kubernetes-masters"
+
+	tests := []struct {
+		name    string
+		rules   []icslib.VMRule
+		managed map[string]bool
+		dryRun  map[string]bool
+		desired map[string][]string
+		// calls as "action ID name VMs"
+		want []string
+	}{
+		{
+			name:    "create",
+			managed: map[string]bool{masters: false},
+			desired: map[string][]string{masters: {"vm-2", "vm-1", "vm-2"}},
+			want:    []string{"create  kubernetes-masters [vm-1 vm-2]"},
+		},
+		{
+			name:    "single VM needs no rule",
+			managed: map[string]bool{masters: false},
+			desired: map[string][]string{masters: {"vm-1"}},
+		},
+		{
+			name:    "adopt a marked rule",
+			rules:   []icslib.VMRule{rule("rule-1", masters, antiAffinityRuleDescription, "vm-2", "vm-1")},
+			managed: map[string]bool{masters: false},
+			desired: map[string][]string{masters: {"vm-1", "vm-2"}},
+		},
+		{
+			name:    "update the VMs",
+			rules:   []icslib.VMRule{rule("rule-1", masters, antiAffinityRuleDescription, "vm-1", "vm-2")},
+			managed: map[string]bool{masters: false},
+			desired: map[string][]string{masters: {"vm-1", "vm-3"}},
+			want:    []string{"update rule-1 kubernetes-masters [vm-1 vm-3]"},
+		},
+		{
+			name: "update a disabled rule",
+			rules: []icslib.VMRule{func() icslib.VMRule {
+				disabled := rule("rule-1", masters, antiAffinityRuleDescription, "vm-1", "vm-2")
+				disabled.Enabled = false
+				return disabled
+			}()},
+			managed: map[string]bool{masters: false},
+			desired: map[string][]string{masters: {"vm-1", "vm-2"}},
+			want:    []string{"update rule-1 kubernetes-masters [vm-1 vm-2]"},
+		},
+		{
+			name:    "delete a rule left with a single VM",
+			rules:   []icslib.VMRule{rule("rule-1", masters, antiAffinityRuleDescription, "vm-1", "vm-2")},
+			managed: map[string]bool{masters: false},
+			desired: map[string][]string{masters: {"vm-1"}},
+			want:    []string{"delete rule-1 kubernetes-masters [vm-1 vm-2]"},
+		},
+		{
+			name:    "delete a marked rule no longer managed",
+			rules:   []icslib.VMRule{rule("rule-1", "kubernetes-etcd", antiAffinityRuleDescription, "vm-1", "vm-2")},
+			managed: map[string]bool{},
+			want:    []string{"delete rule-1 kubernetes-etcd [vm-1 vm-2]"},
+		},
+		{
+			name:    "unmarked rules are left alone",
+			rules:   []icslib.VMRule{rule("rule-1", "kubernetes-etcd", "", "vm-1", "vm-2")},
+			managed: map[string]bool{},
+		},
+		{
+			name:    "unmarked rule with the managed name is left alone",
+			rules:   []icslib.VMRule{rule("rule-1", masters, "made by hand", "vm-1")},
+			managed: map[string]bool{masters: false},
+			desired: map[string][]string{masters: {"vm-1", "vm-2"}},
+		},
+		{
+			name:    "dry-run create",
+			managed: map[string]bool{masters: true},
+			dryRun:  map[string]bool{masters: true},
+			desired: map[string][]string{masters: {"vm-1", "vm-2"}},
+		},
+		{
+			name:    "dry-run update",
+			rules:   []icslib.VMRule{rule("rule-1", masters, antiAffinityRuleDescription, "vm-1", "vm-2")},
+			managed: map[string]bool{masters: true},
+			dryRun:  map[string]bool{masters: true},
+			desired: map[string][]string{masters: {"vm-1", "vm-3"}},
+		},
+		{
+			name:    "dry-run delete",
+			rules:   []icslib.VMRule{rule("rule-1", masters, antiAffinityRuleDescription, "vm-1", "vm-2")},
+			managed: map[string]bool{masters: true},
+			dryRun:  map[string]bool{masters: true},
+		},
+		{
+			name:    "dry-run cleanup of a rule no longer managed",
+			rules:   []icslib.VMRule{rule("rule-1", masters, antiAffinityRuleDescription, "vm-1", "vm-2")},
+			managed: map[string]bool{},
+			dryRun:  map[string]bool{masters: true},
+		},
+		{
+			name: "dry-run applies to its rule only",
+			rules: []icslib.VMRule{
+				rule("rule-1", masters, antiAffinityRuleDescription, "vm-1", "vm-2"),
+				rule("rule-2", "kubernetes-etcd", antiAffinityRuleDescription, "vm-3", "vm-4"),
+			},
+			managed: map[string]bool{masters: true, "kubernetes-etcd": false},
+			dryRun:  map[string]bool{masters: true},
+			want:    []string{"delete rule-2 kubernetes-etcd [vm-3 vm-4]"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newAntiAffinityRuleController(newNodeManager(nil, nil))
+			var calls []string
+			record := func(action string) func(context.Context, *icslib.ICSConnection, *icslib.VMRule) error {
+				return func(ctx context.Context, conn *icslib.ICSConnection, rule *icslib.VMRule) error {
+					if rule.ClusterID != cluster.clusterID {
+						t.Errorf("%s of rule %s in cluster %q", action, rule.Name, rule.ClusterID)
+					}
+					if action != "delete" && (rule.Description != antiAffinityRuleDescription ||
+						rule.Type != icslib.VMRuleAntiAffinity || !rule.Enabled) {
+						t.Errorf("%s of unmarked rule %+v", action, rule)
+					}
+					calls = append(calls, fmt.Sprintf("%s %s %s %v", action, rule.ID, rule.Name, rule.VMIDs))
+					return nil
+				}
+			}
+			c.listRules = func(ctx context.Context, conn *icslib.ICSConnection, clusterID string) ([]icslib.VMRule, error) {
+				if clusterID != cluster.clusterID {
+					return nil, icslib.ErrNoClusterFound
+				}
+				return test.rules, nil
+			}
+			c.createRule = record("create")
+			c.updateRule = record("update")
+			c.deleteRule = record("delete")
+
+			if err := c.reconcileCluster(context.Background(), &icslib.ICSConnection{}, cluster,
+				test.managed, test.dryRun, test.desired); err != nil {
+				t.Fatalf("reconcileCluster() failed: %v", err)
+			}
+			if !reflect.DeepEqual(calls, test.want) {
+				t.Errorf("Calls are\n%s\nwant\n%s", strings.Join(calls, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
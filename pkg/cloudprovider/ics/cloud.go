@@ -21,6 +21,9 @@ import (
 	"runtime"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
 	cloudprovider "k8s.io/cloud-provider"
@@ -73,13 +76,35 @@ func (vs *ICS) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, s
 		vs.connectionManager = connMgr
 		vs.nodeManager.connectionManager = connMgr
 
-		vs.informMgr.AddNodeListener(vs.nodeAdded, vs.nodeDeleted, nil)
+		eventBroadcaster := record.NewBroadcaster()
+		eventBroadcaster.StartLogging(klog.V(4).Infof)
+		eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: client.CoreV1().Events("")})
+		vs.nodeManager.eventRecorder = eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: ClientName})
+
+		vs.nodeManager.nodeLabeler = newNodeLabeler(vs.nodeManager, client)
+		go vs.nodeManager.nodeLabeler.Run(stop)
+		vs.nodeManager.vmTagSyncer = newVMTagSyncer(vs.nodeManager, client)
+		go vs.nodeManager.vmTagSyncer.Run(stop)
+		vs.nodeManager.powerStateController = newPowerStateController(vs.nodeManager, client)
+		go vs.nodeManager.powerStateController.Run(stop)
+		vs.nodeManager.hostAntiAffinityController = newHostAntiAffinityController(vs.nodeManager, client)
+		go vs.nodeManager.hostAntiAffinityController.Run(stop)
+		vs.nodeManager.antiAffinityRuleController = newAntiAffinityRuleController(vs.nodeManager)
+		go vs.nodeManager.antiAffinityRuleController.Run(stop)
+		vs.nodeManager.diskControllerSyncer = newDiskControllerSyncer(vs.nodeManager, client)
+		go vs.nodeManager.diskControllerSyncer.Run(stop)
+
+		go connMgr.SubscribeEvents(vs.nodeManager.handleEvent, stop)
+
+		vs.informMgr.AddNodeListener(vs.nodeAdded, vs.nodeDeleted, vs.nodeUpdated)
 
 		vs.informMgr.Listen()
 
 		//if running secrets, init them
 		connMgr.InitializeSecretLister()
 
+		vs.watchConfig(stop)
+
 		if !vs.cfg.Global.APIDisable {
 			klog.V(1).Info("Starting the API Server")
 			vs.server.Start()
@@ -171,6 +196,22 @@ func (vs *ICS) nodeAdded(obj interface{}) {
 	vs.nodeManager.RegisterNode(node)
 }
 
+// Notification handler when node is updated in k8s cluster.
+func (vs *ICS) nodeUpdated(oldObj, newObj interface{}) {
+	oldNode, ok := oldObj.(*v1.Node)
+	if oldNode == nil || !ok {
+		klog.Warningf("nodeUpdated: unrecognized object %+v", oldObj)
+		return
+	}
+	newNode, ok := newObj.(*v1.Node)
+	if newNode == nil || !ok {
+		klog.Warningf("nodeUpdated: unrecognized object %+v", newObj)
+		return
+	}
+
+	vs.nodeManager.UpdateNode(oldNode, newNode)
+}
+
 // Notification handler when node is removed from k8s cluster.
 func (vs *ICS) nodeDeleted(obj interface{}) {
 	node, ok := obj.(*v1.Node)
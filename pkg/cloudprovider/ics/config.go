@@ -17,49 +17,174 @@ limitations under the License.
 package ics
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strings"
 
 	"gopkg.in/gcfg.v1"
+	"k8s.io/klog"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+)
+
+// Errors
+var (
+	// ErrInvalidCIDR is returned when a node subnet is not a valid CIDR.
+	ErrInvalidCIDR = errors.New("Invalid CIDR")
+
+	// ErrInvalidPrimaryInterfacePolicy is returned when an unknown primary
+	// interface policy is configured.
+	ErrInvalidPrimaryInterfacePolicy = errors.New("Invalid primary interface policy")
+
+	// ErrPrimaryInterfaceMACPrefixMissing is returned when the mac-prefix
+	// policy is configured without a MAC prefix.
+	ErrPrimaryInterfaceMACPrefixMissing = errors.New("Primary interface MAC prefix is missing")
+
+	// ErrInvalidPrimaryInterfaceNetworkRegex is returned when the network-regex
+	// policy is configured without a valid regular expression.
+	ErrInvalidPrimaryInterfaceNetworkRegex = errors.New("Invalid primary interface network regex")
+
+	// ErrInvalidNetworkName is returned when a VM network name has leading
+	// or trailing whitespace.
+	ErrInvalidNetworkName = errors.New("Invalid VM network name")
 )
 
 // FromCPIEnv initializes the provided configuratoin object with values
 // obtained from environment variables. If an environment variable is set
 // for a property that's already initialized, the environment variable's value
 // takes precedence.
+// The whole configuration is validated and all problems are returned at once.
 func (cfg *CPIConfig) FromCPIEnv() error {
-	if err := cfg.FromEnv(); err != nil {
-		return err
+	errs := []error{cfg.FromEnv()}
+
+	for _, envVar := range []struct {
+		name  string
+		key   string
+		field *string
+	}{
+		{"ICS_NODES_INTERNAL_NETWORK_SUBNET_CIDR", "internal-network-subnet-cidr", &cfg.Nodes.InternalNetworkSubnetCIDR},
+		{"ICS_NODES_EXTERNAL_NETWORK_SUBNET_CIDR", "external-network-subnet-cidr", &cfg.Nodes.ExternalNetworkSubnetCIDR},
+		{"ICS_NODES_EXCLUDE_SUBNET_CIDR", "exclude-subnet-cidr", &cfg.Nodes.ExcludeSubnetCIDR},
+		{"ICS_NODES_INTERNAL_VM_NETWORK_NAME", "internal-vm-network-name", &cfg.Nodes.InternalVMNetworkName},
+		{"ICS_NODES_EXTERNAL_VM_NETWORK_NAME", "external-vm-network-name", &cfg.Nodes.ExternalVMNetworkName},
+		{"ICS_NODES_PRIMARY_INTERFACE_POLICY", "primary-interface-policy", &cfg.Nodes.PrimaryInterfacePolicy},
+		{"ICS_NODES_PRIMARY_INTERFACE_MAC_PREFIX", "primary-interface-mac-prefix", &cfg.Nodes.PrimaryInterfaceMACPrefix},
+		{"ICS_NODES_PRIMARY_INTERFACE_NETWORK_REGEX", "primary-interface-network-regex", &cfg.Nodes.PrimaryInterfaceNetworkRegex},
+		{"ICS_NODES_PUBLISH_LABELS", "publish-labels", &cfg.Nodes.PublishLabels},
+		{"ICS_NODES_VM_TAG_SYNC_PERIOD", "vm-tag-sync-period", &cfg.Nodes.VMTagSyncPeriod},
+		{"ICS_NODES_POWER_STATE_SYNC_PERIOD", "power-state-sync-period", &cfg.Nodes.PowerStateSyncPeriod},
+		{"ICS_NODES_ANTI_AFFINITY_SYNC_PERIOD", "anti-affinity-sync-period", &cfg.Nodes.AntiAffinitySyncPeriod},
+		{"ICS_NODES_DISK_SYNC_PERIOD", "disk-sync-period", &cfg.Nodes.DiskSyncPeriod},
+	} {
+		if _, err := icscfg.SetFromEnv(envVar.name, icscfg.SectionPath("Nodes", envVar.key), false, envVar.field); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if v := os.Getenv("ICS_NODES_INTERNAL_NETWORK_SUBNET_CIDR"); v != "" {
-		cfg.Nodes.InternalNetworkSubnetCIDR = v
+	errs = append(errs, cfg.validateNodes()...)
+	errs = append(errs, cfg.validateInstanceTypes()...)
+	errs = append(errs, cfg.validateVMTags()...)
+	errs = append(errs, cfg.validatePowerStateSyncPeriod()...)
+	errs = append(errs, cfg.validateAntiAffinity()...)
+	errs = append(errs, cfg.validateAntiAffinityRules()...)
+	errs = append(errs, cfg.validateDiskSyncPeriod()...)
+	return icscfg.NewAggregate(errs)
+}
+
+// validateNodes checks the subnet CIDRs and VM network names used to select
+// node addresses.
+func (cfg *CPIConfig) validateNodes() []error {
+	var errs []error
+
+	cidrs := map[string]string{
+		"internal-network-subnet-cidr": cfg.Nodes.InternalNetworkSubnetCIDR,
+		"external-network-subnet-cidr": cfg.Nodes.ExternalNetworkSubnetCIDR,
+		"exclude-subnet-cidr":          cfg.Nodes.ExcludeSubnetCIDR,
 	}
-	if v := os.Getenv("ICS_NODES_EXTERNAL_NETWORK_SUBNET_CIDR"); v != "" {
-		cfg.Nodes.ExternalNetworkSubnetCIDR = v
+	for _, key := range []string{"internal-network-subnet-cidr", "external-network-subnet-cidr", "exclude-subnet-cidr"} {
+		if _, err := parseCIDRList(cidrs[key]); err != nil {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", key), cidrs[key], err))
+		}
 	}
 
-	if v := os.Getenv("ICS_NODES_INTERNAL_VM_NETWORK_NAME"); v != "" {
-		cfg.Nodes.InternalVMNetworkName = v
+	networkNames := map[string]string{
+		"internal-vm-network-name": cfg.Nodes.InternalVMNetworkName,
+		"external-vm-network-name": cfg.Nodes.ExternalVMNetworkName,
 	}
-	if v := os.Getenv("ICS_NODES_EXTERNAL_VM_NETWORK_NAME"); v != "" {
-		cfg.Nodes.ExternalVMNetworkName = v
+	for _, key := range []string{"internal-vm-network-name", "external-vm-network-name"} {
+		if networkNames[key] != strings.TrimSpace(networkNames[key]) {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", key), networkNames[key], ErrInvalidNetworkName))
+		}
 	}
 
-	return nil
+	if cfg.Nodes.PrimaryInterfacePolicy == "" {
+		cfg.Nodes.PrimaryInterfacePolicy = PrimaryInterfaceByDeviceIndex
+	}
+	switch cfg.Nodes.PrimaryInterfacePolicy {
+	case PrimaryInterfaceByDeviceIndex, PrimaryInterfaceByDefaultGateway:
+	case PrimaryInterfaceByMACPrefix:
+		if cfg.Nodes.PrimaryInterfaceMACPrefix == "" {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", "primary-interface-mac-prefix"), "", ErrPrimaryInterfaceMACPrefixMissing))
+		}
+	case PrimaryInterfaceByNetworkRegex:
+		if _, err := regexp.Compile(cfg.Nodes.PrimaryInterfaceNetworkRegex); err != nil || cfg.Nodes.PrimaryInterfaceNetworkRegex == "" {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", "primary-interface-network-regex"),
+				cfg.Nodes.PrimaryInterfaceNetworkRegex, ErrInvalidPrimaryInterfaceNetworkRegex))
+		}
+	default:
+		errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", "primary-interface-policy"),
+			cfg.Nodes.PrimaryInterfacePolicy, ErrInvalidPrimaryInterfacePolicy))
+	}
+
+	if _, err := parsePublishLabels(cfg.Nodes.PublishLabels); err != nil {
+		errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", "publish-labels"), cfg.Nodes.PublishLabels, err))
+	}
+
+	return errs
+}
+
+// parseCIDRList parses a comma-separated list of CIDRs. Empty entries are
+// ignored.
+func parseCIDRList(value string) ([]*net.IPNet, error) {
+	var subnets []*net.IPNet
+	for _, cidr := range strings.Split(value, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, ErrInvalidCIDR
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
 }
 
 // ReadCPIConfig parses ics cloud config file and stores it into CPIConfig.
+// Both the gcfg INI and the versioned YAML format are accepted.
 // Environment variables are also checked
 func ReadCPIConfig(config io.Reader) (*CPIConfig, error) {
 	if config == nil {
 		return nil, fmt.Errorf("no ics cloud provider config file given")
 	}
 
+	data, err := ioutil.ReadAll(config)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &CPIConfig{}
 
-	if err := gcfg.FatalOnly(gcfg.ReadInto(cfg, config)); err != nil {
+	if icscfg.IsYAMLConfig(data) {
+		if err := icscfg.UnmarshalYAMLConfig(data, cfg); err != nil {
+			return nil, err
+		}
+	} else if err := gcfg.FatalOnly(gcfg.ReadStringInto(cfg, string(data))); err != nil {
 		return nil, err
 	}
 
@@ -68,5 +193,27 @@ func ReadCPIConfig(config io.Reader) (*CPIConfig, error) {
 		return nil, err
 	}
 
+	if klog.V(2) {
+		if effective, err := icscfg.MarshalRedactedYAMLConfig(cfg); err == nil {
+			klog.Infof("Effective cloud config:\n%s", effective)
+		}
+	}
+
 	return cfg, nil
 }
+
+// ConvertCPIConfig reads a gcfg INI cloud config and returns it in the
+// versioned YAML format. Environment variables are not applied.
+func ConvertCPIConfig(config io.Reader) ([]byte, error) {
+	if config == nil {
+		return nil, fmt.Errorf("no ics cloud provider config file given")
+	}
+
+	cfg := &CPIConfig{}
+
+	if err := gcfg.FatalOnly(gcfg.ReadInto(cfg, config)); err != nil {
+		return nil, err
+	}
+
+	return icscfg.MarshalYAMLConfig(cfg)
+}
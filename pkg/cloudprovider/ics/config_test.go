@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+)
+
+const testGcfgConfig = `
+[Global]
+user = "admin"
+password = "secret"
+datacenters = "dc1"
+
+[VirtualCenter "10.0.0.1"]
+port = "8443"
+
+[Nodes]
+internal-network-subnet-cidr = "10.0.0.0/8"
+exclude-subnet-cidr = "172.17.0.0/16"
+publish-labels = "host,cluster"
+
+[InstanceType "small"]
+cpu = 2
+memory = 4gb
+
+[VMTag "gpu-pool"]
+label = example.com/gpu-pool
+taint = nvidia.com/gpu=true:NoSchedule
+
+[AntiAffinity "control-plane"]
+selector = node-role.kubernetes.io/master
+manage-rule = true
+dry-run = true
+`
+
+func TestConvertCPIConfig(t *testing.T) {
+	data, err := ConvertCPIConfig(strings.NewReader(testGcfgConfig))
+	if err != nil {
+		t.Fatalf("ConvertCPIConfig failed: %v", err)
+	}
+	if !icscfg.IsYAMLConfig(data) {
+		t.Fatalf("The converted config is not detected as YAML:\n%s", data)
+	}
+
+	fromGcfg, err := ReadCPIConfig(strings.NewReader(testGcfgConfig))
+	if err != nil {
+		t.Fatalf("Reading the gcfg config failed: %v", err)
+	}
+	fromYAML, err := ReadCPIConfig(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Reading the converted config failed: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(fromGcfg, fromYAML) {
+		t.Errorf("The configs differ:\ngcfg: %+v\nYAML: %+v\n%s", fromGcfg, fromYAML, data)
+	}
+
+	if fromYAML.Nodes.ExcludeSubnetCIDR != "172.17.0.0/16" {
+		t.Errorf("Unexpected excluded subnets %q", fromYAML.Nodes.ExcludeSubnetCIDR)
+	}
+	if instanceType := fromYAML.InstanceType["small"]; instanceType == nil || instanceType.CPU != 2 || instanceType.Memory != "4gb" {
+		t.Errorf("Unexpected instance type %+v", instanceType)
+	}
+	if vmTag := fromYAML.VMTag["gpu-pool"]; vmTag == nil || len(vmTag.Taint) != 1 || vmTag.Taint[0] != "nvidia.com/gpu=true:NoSchedule" {
+		t.Errorf("Unexpected VM tag %+v", vmTag)
+	}
+	if group := fromYAML.AntiAffinity["control-plane"]; group == nil || !group.ManageRule || !group.DryRun {
+		t.Errorf("Unexpected anti-affinity group %+v", group)
+	}
+}
+
+func TestConvertCPIConfigRejectsYAML(t *testing.T) {
+	if _, err := ConvertCPIConfig(strings.NewReader("apiVersion: ics.cloudprovider/v1alpha1\n")); err == nil {
+		t.Error("ConvertCPIConfig accepted a YAML config")
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+
+	pb "github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics/proto"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// ExportDatastores lists the datastores and datastore clusters of the
+// iCenters and datacenters matching the request. Datastores are filtered by
+// zone and region, which are read from their tags in the categories of the
+// zone and region labels, and by storage policy, which is read from the disks
+// they hold. Datastore clusters are not filtered, but only hold the
+// datastores returned.
+func (nm *NodeManager) ExportDatastores(request *pb.ListDatastoresRequest, datastores *[]*pb.Datastore, clusters *[]*pb.DatastoreCluster) error {
+	if nm.connectionManager == nil {
+		return ErrICenterNotFound
+	}
+
+	var zoneCategory, regionCategory string
+	if cpiCfg := nm.config(); cpiCfg != nil {
+		zoneCategory, regionCategory = cpiCfg.Labels.Zone, cpiCfg.Labels.Region
+	}
+
+	ctx := context.Background()
+	pairs, err := nm.connectionManager.ListAllVCandDCPairs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	found := false
+	for _, pair := range pairs {
+		if request.Vcenter != "" && pair.VcServer != request.Vcenter {
+			continue
+		}
+		if request.Datacenter != "" && pair.DataCenter.Name() != request.Datacenter {
+			continue
+		}
+		found = true
+
+		dsList, err := pair.DataCenter.GetAllDatastores(ctx)
+		if err == icslib.ErrNoDatastoreFound {
+			continue
+		}
+		if err != nil {
+			klog.Errorf("Failed to list datastores of datacenter %s in iCenter %s. err: %v", pair.DataCenter.Name(), pair.VcServer, err)
+			errs = append(errs, err)
+			continue
+		}
+		dsClusters, err := pair.DataCenter.GetAllDatastoreClusters(ctx)
+		if err != nil && err != icslib.ErrNoDataStoreClustersFound {
+			klog.Errorf("Failed to list datastore clusters of datacenter %s in iCenter %s. err: %v", pair.DataCenter.Name(), pair.VcServer, err)
+			errs = append(errs, err)
+			continue
+		}
+		policies, err := pair.DataCenter.GetAllStoragePolicies(ctx)
+		if err != nil && err != icslib.ErrNoStoragePoliciesFound {
+			klog.Errorf("Failed to list storage policies of datacenter %s in iCenter %s. err: %v", pair.DataCenter.Name(), pair.VcServer, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		exportDatacenterDatastores(request, datacenterDatastores{
+			vcServer:       pair.VcServer,
+			datacenter:     pair.DataCenter.Name(),
+			datastores:     dsList,
+			clusters:       dsClusters,
+			policies:       policies,
+			zoneCategory:   zoneCategory,
+			regionCategory: regionCategory,
+		}, datastores, clusters)
+	}
+
+	if !found {
+		if request.Datacenter != "" {
+			return ErrDatacenterNotFound
+		}
+		if request.Vcenter != "" {
+			return ErrICenterNotFound
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// datacenterDatastores is the storage inventory of a datacenter, and the tag
+// categories of the zones and regions.
+type datacenterDatastores struct {
+	vcServer       string
+	datacenter     string
+	datastores     []*icslib.DatastoreInfo
+	clusters       []*icslib.DatastoreClusterInfo
+	policies       []*icslib.StoragePolicyInfo
+	zoneCategory   string
+	regionCategory string
+}
+
+// exportDatacenterDatastores appends the datastores and datastore clusters of
+// the inventory of a datacenter matching the request.
+func exportDatacenterDatastores(request *pb.ListDatastoresRequest, inventory datacenterDatastores,
+	datastores *[]*pb.Datastore, clusters *[]*pb.DatastoreCluster) {
+	clusterNames := make(map[string]string, len(inventory.clusters))
+	for _, dsCluster := range inventory.clusters {
+		clusterNames[dsCluster.ID] = dsCluster.Name
+	}
+	// datastore ID -> policy names, sorted as the policies
+	policies := make(map[string][]string)
+	for _, policy := range inventory.policies {
+		for _, id := range policy.DatastoreIDs {
+			policies[id] = append(policies[id], policy.Name)
+		}
+	}
+
+	exported := make(map[string]string)
+	for _, ds := range inventory.datastores {
+		zone, region := ds.ZoneRegion(inventory.zoneCategory, inventory.regionCategory)
+		if request.Zone != "" && !strings.EqualFold(zone, request.Zone) {
+			continue
+		}
+		if request.Region != "" && !strings.EqualFold(region, request.Region) {
+			continue
+		}
+		if request.StoragePolicy != "" && !containsFold(policies[ds.ID], request.StoragePolicy) {
+			continue
+		}
+
+		pbDatastore := &pb.Datastore{
+			Id:              ds.ID,
+			Name:            ds.Name,
+			Vcenter:         inventory.vcServer,
+			Datacenter:      inventory.datacenter,
+			Type:            ds.Type,
+			Capacity:        ds.Capacity,
+			FreeSpace:       ds.FreeSpace,
+			Hosts:           make([]string, 0, len(ds.Hosts)),
+			Cluster:         clusterNames[ds.ClusterID],
+			Zone:            zone,
+			Region:          region,
+			StoragePolicies: append([]string(nil), policies[ds.ID]...),
+		}
+		for _, host := range ds.Hosts {
+			if host.Name != "" {
+				pbDatastore.Hosts = append(pbDatastore.Hosts, host.Name)
+			} else {
+				pbDatastore.Hosts = append(pbDatastore.Hosts, host.ID)
+			}
+		}
+		*datastores = append(*datastores, pbDatastore)
+		exported[ds.ID] = ds.Name
+	}
+
+	filtered := request.Zone != "" || request.Region != "" || request.StoragePolicy != ""
+	for _, dsCluster := range inventory.clusters {
+		pbCluster := &pb.DatastoreCluster{
+			Id:         dsCluster.ID,
+			Name:       dsCluster.Name,
+			Vcenter:    inventory.vcServer,
+			Datacenter: inventory.datacenter,
+			Capacity:   dsCluster.Capacity,
+			FreeSpace:  dsCluster.FreeSpace,
+			Datastores: make([]string, 0, len(dsCluster.DatastoreIDs)),
+		}
+		for _, id := range dsCluster.DatastoreIDs {
+			if name, ok := exported[id]; ok {
+				pbCluster.Datastores = append(pbCluster.Datastores, name)
+			}
+		}
+		if len(pbCluster.Datastores) == 0 && filtered {
+			continue
+		}
+		*clusters = append(*clusters, pbCluster)
+	}
+}
+
+// containsFold returns true if values holds value, ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics/proto"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+func TestExportDatacenterDatastores(t *testing.T) {
+	inventory := datacenterDatastores{
+		vcServer:   "vc",
+		datacenter: "dc1",
+		datastores: []*icslib.DatastoreInfo{
+			{ID: "ds-1", Name: "datastore1", Type: "NFS", Capacity: 100, FreeSpace: 40,
+				Hosts: []icslib.DatastoreHost{{ID: "host-1", Name: "esx-1"}, {ID: "host-2"}}, ClusterID: "pod-1",
+				Tags: []interface{}{"k8s-zone=zone-a", "k8s-region=region-1"}},
+			{ID: "ds-2", Name: "datastore2", Type: "LOCAL", Capacity: 50, FreeSpace: 10, ClusterID: "pod-1",
+				Tags: []interface{}{"k8s-zone=zone-b", "k8s-region=region-1"}},
+			{ID: "ds-3", Name: "datastore3", Capacity: 10, FreeSpace: 10},
+		},
+		clusters: []*icslib.DatastoreClusterInfo{
+			{ID: "pod-1", Name: "pod1", Capacity: 150, FreeSpace: 50, DatastoreIDs: []string{"ds-1", "ds-2"}},
+		},
+		policies: []*icslib.StoragePolicyInfo{
+			{Name: "THICK", DatastoreIDs: []string{"ds-1"}},
+			{Name: "THIN", DatastoreIDs: []string{"ds-1", "ds-2"}},
+		},
+		zoneCategory:   "k8s-zone",
+		regionCategory: "k8s-region",
+	}
+	ds1 := &pb.Datastore{Id: "ds-1", Name: "datastore1", Vcenter: "vc", Datacenter: "dc1", Type: "NFS", Capacity: 100,
+		FreeSpace: 40, Hosts: []string{"esx-1", "host-2"}, Cluster: "pod1", Zone: "zone-a", Region: "region-1",
+		StoragePolicies: []string{"THICK", "THIN"}}
+	ds2 := &pb.Datastore{Id: "ds-2", Name: "datastore2", Vcenter: "vc", Datacenter: "dc1", Type: "LOCAL", Capacity: 50,
+		FreeSpace: 10, Hosts: []string{}, Cluster: "pod1", Zone: "zone-b", Region: "region-1",
+		StoragePolicies: []string{"THIN"}}
+	ds3 := &pb.Datastore{Id: "ds-3", Name: "datastore3", Vcenter: "vc", Datacenter: "dc1", Capacity: 10,
+		FreeSpace: 10, Hosts: []string{}}
+	pod := func(datastores ...string) *pb.DatastoreCluster {
+		return &pb.DatastoreCluster{Id: "pod-1", Name: "pod1", Vcenter: "vc", Datacenter: "dc1", Capacity: 150,
+			FreeSpace: 50, Datastores: datastores}
+	}
+
+	tests := []struct {
+		name           string
+		request        *pb.ListDatastoresRequest
+		wantDatastores []*pb.Datastore
+		wantClusters   []*pb.DatastoreCluster
+	}{
+		{
+			name:           "all datastores",
+			request:        &pb.ListDatastoresRequest{},
+			wantDatastores: []*pb.Datastore{ds1, ds2, ds3},
+			wantClusters:   []*pb.DatastoreCluster{pod("datastore1", "datastore2")},
+		},
+		{
+			name:           "zone",
+			request:        &pb.ListDatastoresRequest{Zone: "ZONE-B"},
+			wantDatastores: []*pb.Datastore{ds2},
+			wantClusters:   []*pb.DatastoreCluster{pod("datastore2")},
+		},
+		{
+			name:           "region",
+			request:        &pb.ListDatastoresRequest{Region: "region-1"},
+			wantDatastores: []*pb.Datastore{ds1, ds2},
+			wantClusters:   []*pb.DatastoreCluster{pod("datastore1", "datastore2")},
+		},
+		{
+			name:           "storage policy",
+			request:        &pb.ListDatastoresRequest{StoragePolicy: "thick"},
+			wantDatastores: []*pb.Datastore{ds1},
+			wantClusters:   []*pb.DatastoreCluster{pod("datastore1")},
+		},
+		{
+			name:    "no match drops the clusters",
+			request: &pb.ListDatastoresRequest{StoragePolicy: "ENCRYPTED"},
+		},
+		{
+			name:           "zone and storage policy",
+			request:        &pb.ListDatastoresRequest{Zone: "zone-b", StoragePolicy: "THIN"},
+			wantDatastores: []*pb.Datastore{ds2},
+			wantClusters:   []*pb.DatastoreCluster{pod("datastore2")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var datastores []*pb.Datastore
+			var clusters []*pb.DatastoreCluster
+			exportDatacenterDatastores(test.request, inventory, &datastores, &clusters)
+			if !reflect.DeepEqual(datastores, test.wantDatastores) {
+				t.Errorf("Datastores are %+v, want %+v", datastores, test.wantDatastores)
+			}
+			if !reflect.DeepEqual(clusters, test.wantClusters) {
+				t.Errorf("Clusters are %+v, want %+v", clusters, test.wantClusters)
+			}
+		})
+	}
+}
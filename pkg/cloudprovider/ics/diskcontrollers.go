@@ -0,0 +1,191 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	pb "github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics/proto"
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// Errors
+var (
+	// ErrInvalidDiskSyncPeriod is returned when the disk sync period is not a
+	// positive duration.
+	ErrInvalidDiskSyncPeriod = errors.New("Invalid disk sync period")
+)
+
+// defaultDiskSyncPeriod is the default period of the disk controller sync.
+const defaultDiskSyncPeriod = time.Minute
+
+// validateDiskSyncPeriod checks the disk sync period.
+func (cfg *CPIConfig) validateDiskSyncPeriod() []error {
+	if cfg.Nodes.DiskSyncPeriod == "" {
+		cfg.Nodes.DiskSyncPeriod = defaultDiskSyncPeriod.String()
+	}
+	if period, err := time.ParseDuration(cfg.Nodes.DiskSyncPeriod); err != nil || period <= 0 {
+		return []error{icscfg.NewFieldError(icscfg.SectionPath("Nodes", "disk-sync-period"),
+			cfg.Nodes.DiskSyncPeriod, ErrInvalidDiskSyncPeriod)}
+	}
+	return nil
+}
+
+// diskSyncPeriod returns the period of the disk controller sync.
+func (nm *NodeManager) diskSyncPeriod() time.Duration {
+	if cpiCfg := nm.config(); cpiCfg != nil {
+		if period, err := time.ParseDuration(cpiCfg.Nodes.DiskSyncPeriod); err == nil && period > 0 {
+			return period
+		}
+	}
+	return defaultDiskSyncPeriod
+}
+
+// freeDiskSlots returns the number of disks the controllers can still take.
+func freeDiskSlots(controllers []icslib.DiskController) int {
+	free := 0
+	for _, controller := range controllers {
+		free += controller.FreeSlots()
+	}
+	return free
+}
+
+// formatDiskControllers returns the value of the disk-controllers annotation.
+func formatDiskControllers(controllers []icslib.DiskController) string {
+	items := make([]string, 0, len(controllers))
+	for _, controller := range controllers {
+		items = append(items, fmt.Sprintf("%s:%d/%d", controller.Bus, controller.Disks, controller.Slots))
+	}
+	return strings.Join(items, ",")
+}
+
+// exportDiskControllers sets the disk controllers of the node info on the
+// gRPC node. The caller holds nodeInfoLock.
+func exportDiskControllers(nodeInfo *NodeInfo, node *pb.Node) {
+	node.DiskControllers = make([]*pb.DiskController, 0, len(nodeInfo.diskControllers))
+	for _, controller := range nodeInfo.diskControllers {
+		node.DiskControllers = append(node.DiskControllers, &pb.DiskController{
+			Bus:   controller.Bus,
+			Disks: int32(controller.Disks),
+			Slots: int32(controller.Slots),
+		})
+	}
+	node.FreeDiskSlots = int32(freeDiskSlots(nodeInfo.diskControllers))
+}
+
+// diskControllerSyncer records the disk controllers of the VMs of the nodes,
+// read from iCenter, and publishes them in the disk-controllers and
+// free-disk-slots annotations. Nodes are synced periodically, as disks are
+// attached without notice, and when they are discovered or updated.
+type diskControllerSyncer struct {
+	*nodeQueue
+	nodeManager *NodeManager
+	client      clientset.Interface
+}
+
+// newDiskControllerSyncer returns a disk controller syncer patching nodes
+// with client.
+func newDiskControllerSyncer(nm *NodeManager, client clientset.Interface) *diskControllerSyncer {
+	s := &diskControllerSyncer{
+		nodeManager: nm,
+		client:      client,
+	}
+	s.nodeQueue = newNodeQueue("diskcontrollers", s.sync)
+	return s
+}
+
+// Run syncs the queued nodes, and all registered nodes every sync period,
+// until stop is closed.
+func (s *diskControllerSyncer) Run(stop <-chan struct{}) {
+	s.nodeQueue.RunWithResync(stop, s.nodeManager.diskSyncPeriod, s.enqueueAll)
+}
+
+// enqueue queues the node with the UUID. It is a no-op if s is nil.
+func (s *diskControllerSyncer) enqueue(uuid string) {
+	if s == nil {
+		return
+	}
+	s.nodeQueue.enqueue(uuid)
+}
+
+// enqueueAll queues the registered nodes. It is a no-op if s is nil.
+func (s *diskControllerSyncer) enqueueAll() {
+	if s == nil {
+		return
+	}
+	for _, uuid := range s.nodeManager.registeredUUIDs() {
+		s.nodeQueue.enqueue(uuid)
+	}
+}
+
+// sync reads the disks of the VM of the node with the UUID from iCenter,
+// records its disk controllers and patches the annotations of the node that
+// differ.
+func (s *diskControllerSyncer) sync(uuid string) error {
+	nm := s.nodeManager
+	nodeInfo, node := nm.discoveredNode(uuid)
+	if node == nil {
+		return nil
+	}
+
+	vm, err := nm.currentVM(context.Background(), nodeInfo)
+	if err != nil {
+		return err
+	}
+	controllers := vm.DiskControllers()
+
+	nm.nodeInfoLock.Lock()
+	nodeInfo.diskControllers = controllers
+	nm.nodeInfoLock.Unlock()
+
+	annotationsPatch := make(map[string]interface{})
+	for annotation, value := range map[string]string{
+		AnnotationDiskControllers: formatDiskControllers(controllers),
+		AnnotationFreeDiskSlots:   strconv.Itoa(freeDiskSlots(controllers)),
+	} {
+		if current, ok := node.Annotations[annotation]; !ok || current != value {
+			annotationsPatch[annotation] = value
+		}
+	}
+	if len(annotationsPatch) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": node.ResourceVersion,
+			"annotations":     annotationsPatch,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Patching disk controller annotations of node %s: %s", node.Name, data)
+	_, err = s.client.CoreV1().Nodes().Patch(node.Name, types.MergePatchType, data)
+	return err
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"testing"
+
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+func TestFormatDiskControllers(t *testing.T) {
+	tests := []struct {
+		name        string
+		controllers []icslib.DiskController
+		want        string
+		wantFree    int
+	}{
+		{
+			name:        "default bus",
+			controllers: []icslib.DiskController{{Bus: icslib.DiskBusVirtio, Disks: 2, Slots: 16}},
+			want:        "VIRTIO:2/16",
+			wantFree:    14,
+		},
+		{
+			name: "several buses",
+			controllers: []icslib.DiskController{
+				{Bus: icslib.DiskBusIDE, Disks: 4, Slots: 4},
+				{Bus: "NVME", Disks: 1},
+				{Bus: icslib.DiskBusVirtio, Disks: 1, Slots: 16},
+			},
+			want:     "IDE:4/4,NVME:1/0,VIRTIO:1/16",
+			wantFree: 15,
+		},
+		{
+			name:     "no controllers",
+			want:     "",
+			wantFree: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := formatDiskControllers(test.controllers); got != test.want {
+				t.Errorf("formatDiskControllers() = %q, want %q", got, test.want)
+			}
+			if got := freeDiskSlots(test.controllers); got != test.wantFree {
+				t.Errorf("freeDiskSlots() = %d, want %d", got, test.wantFree)
+			}
+		})
+	}
+}
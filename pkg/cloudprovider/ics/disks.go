@@ -0,0 +1,48 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+
+	"k8s.io/klog"
+
+	pb "github.com/inspur-ics/cloud-provider-ics/pkg/cloudprovider/ics/proto"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// GetDisk finds the ICS volume with the ID across the iCenters and
+// datacenters, the same way nodes are found.
+func (nm *NodeManager) GetDisk(id string, disk *pb.Disk) error {
+	if nm.connectionManager == nil {
+		return icslib.ErrNoDiskIDFound
+	}
+
+	fcdDI, err := nm.connectionManager.WhichVCandDCByFCDId(context.Background(), id)
+	if err != nil {
+		klog.Errorf("GetDisk failed err=%s", err)
+		return err
+	}
+
+	disk.Id = fcdDI.FCDInfo.ID
+	disk.Name = fcdDI.FCDInfo.Name
+	disk.Vcenter = fcdDI.VcServer
+	disk.Datacenter = fcdDI.DataCenter.Name()
+	disk.Datastore = fcdDI.FCDInfo.DatastoreName()
+	disk.Size = fcdDI.FCDInfo.Size
+	return nil
+}
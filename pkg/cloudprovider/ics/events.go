@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// handleEvent updates the nodes concerned by an iCenter event, so that their
+// addresses, zone, labels and taints converge without waiting for the
+// periodic syncs.
+func (nm *NodeManager) handleEvent(tenantRef string, event icslib.Event) {
+	for _, uuid := range nm.nodesOfEvent(tenantRef, event) {
+		switch event.Type {
+		case icslib.EventVMMigrated, icslib.EventVMReconfigured, icslib.EventVMIPChanged:
+			// The cached VM holds the host and addresses, discovery refreshes it
+			klog.V(2).Infof("VM of node with UUID=%s: %s, re-discovering it", uuid, event.Type)
+			nm.rediscoverNode(uuid)
+		default:
+			klog.V(2).Infof("%s %s of node with UUID=%s: %s", event.TargetType, event.TargetID, uuid, event.Type)
+			nm.syncNode(uuid)
+		}
+	}
+}
+
+// nodesOfEvent returns the UUIDs of the discovered nodes concerned by an
+// iCenter event. Events are matched to nodes only when their target has the
+// type expected for the event, as VM and host IDs may overlap.
+func (nm *NodeManager) nodesOfEvent(tenantRef string, event icslib.Event) []string {
+	switch event.Type {
+	case icslib.EventHostMaintenance:
+		if event.TargetType == icslib.EventTargetHost {
+			return nm.nodesOnHost(tenantRef, event.TargetID)
+		}
+	case icslib.EventVMPoweredOn, icslib.EventVMPoweredOff, icslib.EventVMDeleted,
+		icslib.EventVMMigrated, icslib.EventVMReconfigured, icslib.EventVMIPChanged:
+		if event.TargetType != icslib.EventTargetVM {
+			break
+		}
+		if uuid := nm.nodeOfVM(tenantRef, event.TargetID); uuid != "" {
+			return []string{uuid}
+		}
+	}
+	return nil
+}
+
+// nodeOfVM returns the UUID of the discovered node running on the VM with
+// the iCenter ID, or an empty string.
+func (nm *NodeManager) nodeOfVM(tenantRef string, vmID string) string {
+	nm.nodeInfoLock.RLock()
+	defer nm.nodeInfoLock.RUnlock()
+
+	for uuid, nodeInfo := range nm.nodeUUIDMap {
+		if nodeInfo.tenantRef == tenantRef && nodeInfo.vm != nil && nodeInfo.vm.VirtualMachine != nil &&
+			(nodeInfo.vm.ID == vmID || strings.EqualFold(nodeInfo.vm.UUID, vmID)) {
+			return uuid
+		}
+	}
+	return ""
+}
+
+// nodesOnHost returns the UUIDs of the discovered nodes running on the host
+// with the iCenter ID.
+func (nm *NodeManager) nodesOnHost(tenantRef string, hostID string) []string {
+	nm.nodeInfoLock.RLock()
+	defer nm.nodeInfoLock.RUnlock()
+
+	var uuids []string
+	for uuid, nodeInfo := range nm.nodeUUIDMap {
+		if nodeInfo.tenantRef == tenantRef && nodeInfo.vm != nil && nodeInfo.vm.VirtualMachine != nil &&
+			nodeInfo.vm.HostID == hostID {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"reflect"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+func TestNodesOfEvent(t *testing.T) {
+	nm := newNodeManager(nil, nil)
+	// The VM of node1 has the ID of the host of node2
+	for _, node := range []struct{ uuid, vmID, hostID string }{
+		{"uuid-1", "id-1", "host-1"},
+		{"uuid-2", "vm-2", "id-1"},
+	} {
+		nm.addNodeInfo(&NodeInfo{
+			tenantRef:  "vc",
+			vcServer:   "vc",
+			dataCenter: &icslib.Datacenter{Datacenter: &tp.Datacenter{Name: "dc1"}},
+			vm:         &icslib.VirtualMachine{VirtualMachine: &tp.VirtualMachine{ID: node.vmID, HostID: node.hostID}},
+			UUID:       node.uuid,
+			NodeName:   node.uuid,
+		})
+	}
+
+	tests := []struct {
+		name      string
+		tenantRef string
+		event     icslib.Event
+		want      []string
+	}{
+		{
+			name:      "VM event",
+			tenantRef: "vc",
+			event:     icslib.Event{Type: icslib.EventVMPoweredOff, TargetType: icslib.EventTargetVM, TargetID: "id-1"},
+			want:      []string{"uuid-1"},
+		},
+		{
+			name:      "VM event of another iCenter",
+			tenantRef: "other",
+			event:     icslib.Event{Type: icslib.EventVMPoweredOff, TargetType: icslib.EventTargetVM, TargetID: "id-1"},
+		},
+		{
+			name:      "VM event on a host target",
+			tenantRef: "vc",
+			event:     icslib.Event{Type: icslib.EventVMMigrated, TargetType: icslib.EventTargetHost, TargetID: "id-1"},
+		},
+		{
+			name:      "VM event without a target type",
+			tenantRef: "vc",
+			event:     icslib.Event{Type: icslib.EventVMDeleted, TargetID: "id-1"},
+		},
+		{
+			name:      "host event",
+			tenantRef: "vc",
+			event:     icslib.Event{Type: icslib.EventHostMaintenance, TargetType: icslib.EventTargetHost, TargetID: "id-1"},
+			want:      []string{"uuid-2"},
+		},
+		{
+			name:      "host event on a VM target",
+			tenantRef: "vc",
+			event:     icslib.Event{Type: icslib.EventHostMaintenance, TargetType: icslib.EventTargetVM, TargetID: "id-1"},
+		},
+		{
+			name:      "other event",
+			tenantRef: "vc",
+			event:     icslib.Event{Type: icslib.EventOther, TargetType: icslib.EventTargetVM, TargetID: "id-1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := nm.nodesOfEvent(test.tenantRef, test.event)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("nodesOfEvent() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -168,13 +168,15 @@ func (i *instances) InstanceExistsByProviderID(ctx context.Context, providerID s
 	return false, nil
 }
 
-// InstanceShutdownByProviderID returns true if the instance is in safe state to detach volumes
+// InstanceShutdownByProviderID returns true if the instance is in safe state to detach volumes,
+// that is powered off
 func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID string) (bool, error) {
 	klog.V(4).Info("instances.InstanceShutdownByProviderID() called")
 
 	// Check if node has been discovered already
 	uid := GetUUIDFromProviderID(providerID)
-	if _, ok := i.nodeManager.nodeUUIDMap[uid]; !ok {
+	nodeInfo := i.nodeManager.nodeInfoByUUID(uid)
+	if nodeInfo == nil {
 		// IF the uuid is not cached, we end up here
 		klog.V(2).Info("instances.InstanceShutdownByProviderID() NOT CACHED")
 		if err := i.nodeManager.DiscoverNode(uid, cm.FindVMByUUID); err != nil {
@@ -183,10 +185,18 @@ func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID
 			return false, err
 		}
 		klog.V(2).Infof("instances.InstanceShutdownByProviderID() EXISTS with %q", uid)
+		if nodeInfo = i.nodeManager.nodeInfoByUUID(uid); nodeInfo == nil {
+			return false, ErrVMNotFound
+		}
 	}
 
-	active, err := i.nodeManager.nodeUUIDMap[uid].vm.IsActive(ctx)
-	klog.V(2).Infof("VM=%s IsActive=%t", uid, active)
-	// invert the return value
-	return !active, err
+	vm, err := i.nodeManager.currentVM(ctx, nodeInfo)
+	if err != nil {
+		klog.Errorf("Failed to read VM=%s from iCenter. err=%v", uid, err)
+		return false, err
+	}
+	klog.V(2).Infof("VM=%s PowerState=%s", uid, vm.PowerState())
+	// only a powered off VM is shut down, VMs in an unknown state may still
+	// use their volumes
+	return vm.IsPoweredOff(), nil
 }
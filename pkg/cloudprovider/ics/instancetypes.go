@@ -0,0 +1,220 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// Errors
+var (
+	// ErrInvalidInstanceTypeName is returned when the name of an instance
+	// type is not a valid label value.
+	ErrInvalidInstanceTypeName = errors.New("Instance type name is not a valid label value")
+
+	// ErrInvalidInstanceTypeCPU is returned when an instance type does not
+	// have a positive number of vCPUs.
+	ErrInvalidInstanceTypeCPU = errors.New("Invalid instance type CPU count")
+
+	// ErrInvalidInstanceTypeMemory is returned when the memory size of an
+	// instance type cannot be parsed.
+	ErrInvalidInstanceTypeMemory = errors.New("Invalid instance type memory size")
+)
+
+const (
+	// instanceTypeTolerance is the largest relative difference of the CPU
+	// count and of the memory size between a VM and its instance type.
+	instanceTypeTolerance = 0.25
+
+	// unknownGuestOS is the OS of VMs without a guest OS type.
+	unknownGuestOS = "unknown"
+)
+
+var (
+	memorySizeRegexp  = regexp.MustCompile(`^(?i)\s*(\d+)\s*(mb|m|gb|g)?\s*$`)
+	labelInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// parseMemorySize returns the size in MB of a memory size like 512mb or 4gb.
+// Plain numbers are MB.
+func parseMemorySize(value string) (int, error) {
+	match := memorySizeRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return 0, ErrInvalidInstanceTypeMemory
+	}
+	size, err := strconv.Atoi(match[1])
+	if err != nil || size <= 0 {
+		return 0, ErrInvalidInstanceTypeMemory
+	}
+	if unit := strings.ToLower(match[2]); unit == "gb" || unit == "g" {
+		size *= 1024
+	}
+	return size, nil
+}
+
+// validateInstanceTypes checks the instance type catalog.
+func (cfg *CPIConfig) validateInstanceTypes() []error {
+	var errs []error
+
+	names := make([]string, 0, len(cfg.InstanceType))
+	for name := range cfg.InstanceType {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		instanceType := cfg.InstanceType[name]
+		if instanceType == nil {
+			instanceType = &InstanceTypeConfig{}
+			cfg.InstanceType[name] = instanceType
+		}
+		if len(validation.IsValidLabelValue(name)) > 0 || name == "" {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SubsectionPath("InstanceType", name, "name"), name, ErrInvalidInstanceTypeName))
+		}
+		if instanceType.CPU <= 0 {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SubsectionPath("InstanceType", name, "cpu"),
+				strconv.Itoa(instanceType.CPU), ErrInvalidInstanceTypeCPU))
+		}
+		if _, err := parseMemorySize(instanceType.Memory); err != nil {
+			errs = append(errs, icscfg.NewFieldError(icscfg.SubsectionPath("InstanceType", name, "memory"), instanceType.Memory, err))
+		}
+	}
+
+	return errs
+}
+
+// guestOSName returns the shorthand name of a guest OS type, through
+// GuestOSLookup if it is known. Guest OS types are matched exactly first,
+// and then regardless of case, in the order of the sorted identifiers.
+func guestOSName(guestOSType string) string {
+	guestOSType = strings.TrimSpace(guestOSType)
+	if guestOSType == "" {
+		return unknownGuestOS
+	}
+	if name, ok := GuestOSLookup[guestOSType]; ok {
+		return name
+	}
+
+	ids := make([]string, 0, len(GuestOSLookup))
+	for id := range GuestOSLookup {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if strings.EqualFold(id, guestOSType) {
+			return GuestOSLookup[id]
+		}
+	}
+	return guestOSType
+}
+
+// toLabelValue turns value into a valid label value by replacing invalid
+// characters with dashes and truncating it.
+func toLabelValue(value string) string {
+	value = labelInvalidChars.ReplaceAllString(value, "-")
+	if len(value) > validation.LabelValueMaxLength {
+		value = value[:validation.LabelValueMaxLength]
+	}
+	return strings.Trim(value, "-_.")
+}
+
+// formatMemorySize returns a memory size in MB in GB if it is a whole number
+// of GB, so that sub-GB sizes are not truncated.
+func formatMemorySize(memoryMB int) string {
+	if memoryMB > 0 && memoryMB%1024 == 0 {
+		return fmt.Sprintf("%dgb", memoryMB/1024)
+	}
+	return fmt.Sprintf("%dmb", memoryMB)
+}
+
+// instanceType returns the name of the instance type closest to the VM, or
+// else a name built from the VM's CPU count, memory size and guest OS. The
+// returned name is always a valid label value.
+//
+// Nodes discovered by earlier releases reported ics-vm.cpu-N.mem-Mgb.os-<raw
+// guest OS type>. Their instance type label changes when they are discovered
+// again after an upgrade, for example from
+// ics-vm.cpu-2.mem-4gb.os-centos7_64Guest to ics-vm.cpu-2.mem-4gb.os-centos7.
+func (nm *NodeManager) instanceType(vm *icslib.VirtualMachine) string {
+	if cpiCfg := nm.config(); cpiCfg != nil {
+		if name, ok := closestInstanceType(cpiCfg.InstanceType, vm.CPUNum, vm.Memory); ok {
+			return name
+		}
+	}
+
+	return toLabelValue(fmt.Sprintf("ics-vm.cpu-%d.mem-%s.os-%s",
+		vm.CPUNum,
+		formatMemorySize(vm.Memory),
+		strings.ToLower(guestOSName(vm.GuestosType)),
+	))
+}
+
+// closestInstanceType returns the instance type with the smallest relative
+// difference of CPU count and memory size to the VM, among the ones within
+// instanceTypeTolerance.
+func closestInstanceType(instanceTypes map[string]*InstanceTypeConfig, cpu int, memoryMB int) (string, bool) {
+	if cpu <= 0 || memoryMB <= 0 {
+		return "", false
+	}
+
+	names := make([]string, 0, len(instanceTypes))
+	for name := range instanceTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	closest := ""
+	closestDistance := math.Inf(1)
+	for _, name := range names {
+		instanceType := instanceTypes[name]
+		if instanceType == nil || instanceType.CPU <= 0 {
+			continue
+		}
+		memory, err := parseMemorySize(instanceType.Memory)
+		if err != nil {
+			continue
+		}
+
+		cpuDistance := math.Abs(float64(cpu-instanceType.CPU)) / float64(instanceType.CPU)
+		memoryDistance := math.Abs(float64(memoryMB-memory)) / float64(memory)
+		if cpuDistance > instanceTypeTolerance || memoryDistance > instanceTypeTolerance {
+			continue
+		}
+		if distance := cpuDistance + memoryDistance; distance < closestDistance {
+			closest = name
+			closestDistance = distance
+		}
+	}
+
+	if closest == "" {
+		return "", false
+	}
+	klog.V(4).Infof("VM with %d vCPUs and %dMB of memory matches instance type %s", cpu, memoryMB, closest)
+	return closest, true
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"strings"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+func TestParseMemorySize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+		err      bool
+	}{
+		{value: "512", expected: 512},
+		{value: "512mb", expected: 512},
+		{value: "512M", expected: 512},
+		{value: "4gb", expected: 4096},
+		{value: " 4 GB ", expected: 4096},
+		{value: "2g", expected: 2048},
+		{value: "", err: true},
+		{value: "0gb", err: true},
+		{value: "-1gb", err: true},
+		{value: "1.5gb", err: true},
+		{value: "4tb", err: true},
+		{value: "99999999999999999999", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			size, err := parseMemorySize(test.value)
+			if test.err {
+				if err != ErrInvalidInstanceTypeMemory {
+					t.Errorf("parseMemorySize returned %d, %v, expected %v", size, err, ErrInvalidInstanceTypeMemory)
+				}
+				return
+			}
+			if err != nil || size != test.expected {
+				t.Errorf("parseMemorySize returned %d, %v, expected %d", size, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestToLabelValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "valid", value: "ics-vm.cpu-2.mem-4gb.os-centos7", expected: "ics-vm.cpu-2.mem-4gb.os-centos7"},
+		{name: "spaces and parentheses", value: "os-CentOS 7 (64-bit)", expected: "os-CentOS-7-64-bit"},
+		{name: "slashes", value: "os-other/linux", expected: "os-other-linux"},
+		{name: "leading and trailing", value: "_.-value-._", expected: "value"},
+		{name: "empty", value: "", expected: ""},
+		{name: "long", value: strings.Repeat("a", 70), expected: strings.Repeat("a", validation.LabelValueMaxLength)},
+		{name: "truncated before a dash", value: strings.Repeat("a", 62) + "-b", expected: strings.Repeat("a", 62)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := toLabelValue(test.value)
+			if actual != test.expected {
+				t.Errorf("toLabelValue returned %q, expected %q", actual, test.expected)
+			}
+			if errs := validation.IsValidLabelValue(actual); len(errs) > 0 {
+				t.Errorf("%q is not a valid label value: %v", actual, errs)
+			}
+		})
+	}
+}
+
+func TestGuestOSName(t *testing.T) {
+	tests := []struct {
+		guestOSType string
+		expected    string
+	}{
+		{guestOSType: "centos7_64Guest", expected: "centos7"},
+		{guestOSType: " centos7_64Guest ", expected: "centos7"},
+		{guestOSType: "CENTOS7_64GUEST", expected: "centos7"},
+		{guestOSType: "", expected: unknownGuestOS},
+		{guestOSType: "plan9Guest", expected: "plan9Guest"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.guestOSType, func(t *testing.T) {
+			if actual := guestOSName(test.guestOSType); actual != test.expected {
+				t.Errorf("guestOSName returned %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestGuestOSNameDeterministic(t *testing.T) {
+	saved := GuestOSLookup
+	defer func() { GuestOSLookup = saved }()
+	GuestOSLookup = map[string]string{
+		"osGuest": "first",
+		"OSGUEST": "second",
+		"OsGuest": "third",
+	}
+
+	// The first identifier in sorted order wins for case-insensitive matches
+	for i := 0; i < 20; i++ {
+		if actual := guestOSName("osguest"); actual != "second" {
+			t.Fatalf("guestOSName returned %q, expected second", actual)
+		}
+	}
+	if actual := guestOSName("OsGuest"); actual != "third" {
+		t.Errorf("guestOSName returned %q for an exact match, expected third", actual)
+	}
+}
+
+func TestClosestInstanceType(t *testing.T) {
+	instanceTypes := map[string]*InstanceTypeConfig{
+		"small":   {CPU: 2, Memory: "4gb"},
+		"medium":  {CPU: 4, Memory: "8gb"},
+		"medium2": {CPU: 4, Memory: "8192"},
+		"large":   {CPU: 8, Memory: "16gb"},
+		"broken":  {CPU: 2, Memory: "lots"},
+		"nil":     nil,
+	}
+
+	tests := []struct {
+		name     string
+		cpu      int
+		memoryMB int
+		expected string
+	}{
+		{name: "exact", cpu: 2, memoryMB: 4096, expected: "small"},
+		{name: "within tolerance", cpu: 2, memoryMB: 3584, expected: "small"},
+		{name: "closest", cpu: 5, memoryMB: 9216, expected: "medium"},
+		{name: "tie broken by name", cpu: 4, memoryMB: 8192, expected: "medium"},
+		{name: "CPU out of tolerance", cpu: 3, memoryMB: 4096},
+		{name: "memory out of tolerance", cpu: 2, memoryMB: 6144},
+		{name: "no CPU", cpu: 0, memoryMB: 4096},
+		{name: "no memory", cpu: 2, memoryMB: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name, ok := closestInstanceType(instanceTypes, test.cpu, test.memoryMB)
+			if ok != (test.expected != "") || name != test.expected {
+				t.Errorf("closestInstanceType returned %q, %t, expected %q", name, ok, test.expected)
+			}
+		})
+	}
+}
+
+func TestInstanceType(t *testing.T) {
+	cfg := &CPIConfig{}
+	cfg.InstanceType = map[string]*InstanceTypeConfig{"small": {CPU: 2, Memory: "4gb"}}
+	nm := &NodeManager{cpiCfg: cfg}
+	vm := func(cpu int, memoryMB int, guestOSType string) *icslib.VirtualMachine {
+		return &icslib.VirtualMachine{VirtualMachine: &tp.VirtualMachine{CPUNum: cpu, Memory: memoryMB, GuestosType: guestOSType}}
+	}
+
+	tests := []struct {
+		name     string
+		vm       *icslib.VirtualMachine
+		expected string
+	}{
+		{name: "catalog", vm: vm(2, 4096, "centos7_64Guest"), expected: "small"},
+		{name: "whole GB", vm: vm(8, 16384, "centos7_64Guest"), expected: "ics-vm.cpu-8.mem-16gb.os-centos7"},
+		{name: "sub-GB", vm: vm(1, 512, "ubuntu64Guest"), expected: "ics-vm.cpu-1.mem-512mb.os-ubuntu"},
+		{name: "unknown guest OS", vm: vm(1, 1536, "CentOS 7 (64-bit)"), expected: "ics-vm.cpu-1.mem-1536mb.os-centos-7-64-bit"},
+		{name: "no guest OS", vm: vm(1, 1024, ""), expected: "ics-vm.cpu-1.mem-1gb.os-unknown"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := nm.instanceType(test.vm); actual != test.expected {
+				t.Errorf("instanceType returned %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestValidateInstanceTypes(t *testing.T) {
+	cfg := &CPIConfig{}
+	cfg.InstanceType = map[string]*InstanceTypeConfig{
+		"small":        {CPU: 2, Memory: "4gb"},
+		"no-cpu":       {Memory: "4gb"},
+		"bad-memory":   {CPU: 2, Memory: "4tb"},
+		"not a label!": {CPU: 2, Memory: "4gb"},
+		"empty":        nil,
+	}
+
+	expected := []string{
+		`InstanceType["bad-memory"].memory`,
+		`InstanceType["empty"].cpu`,
+		`InstanceType["empty"].memory`,
+		`InstanceType["no-cpu"].cpu`,
+		`InstanceType["not a label!"].name`,
+	}
+	errs := cfg.validateInstanceTypes()
+	if len(errs) != len(expected) {
+		t.Fatalf("validateInstanceTypes returned %v, expected errors for %v", errs, expected)
+	}
+	for i, err := range errs {
+		fieldErr, ok := err.(*icscfg.FieldError)
+		if !ok || fieldErr.Path != expected[i] {
+			t.Errorf("Error %d is %v, expected one for %s", i, err, expected[i])
+		}
+	}
+	if cfg.InstanceType["empty"] == nil {
+		t.Error("The empty instance type was not replaced")
+	}
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Errors
+var (
+	// ErrUnknownNodeLabel is returned when publish-labels lists a label that
+	// is not one of the node labels.
+	ErrUnknownNodeLabel = errors.New("Unknown node label")
+)
+
+// Node labels published from the VM of the node, when allowed by
+// publish-labels.
+const (
+	// LabelHost is the name of the host running the VM.
+	LabelHost = "ics.inspur.com/host"
+	// LabelCluster is the name of the cluster of the host running the VM.
+	LabelCluster = "ics.inspur.com/cluster"
+	// LabelDatacenter is the name of the datacenter of the VM.
+	LabelDatacenter = "ics.inspur.com/datacenter"
+	// LabelICenter is the iCenter managing the VM.
+	LabelICenter = "ics.inspur.com/icenter"
+	// LabelGuestOSFamily is the guest OS of the VM without its version, like
+	// centos or windows.
+	LabelGuestOSFamily = "ics.inspur.com/guest-os-family"
+	// LabelHardwareVersion is the machine model emulated for the VM.
+	LabelHardwareVersion = "ics.inspur.com/hardware-version"
+)
+
+// nodeLabelPrefix is the prefix of the node labels, stripped off to name
+// them in publish-labels.
+const nodeLabelPrefix = "ics.inspur.com/"
+
+// nodeLabels are the labels managed by the node labeler.
+var nodeLabels = []string{
+	LabelHost,
+	LabelCluster,
+	LabelDatacenter,
+	LabelICenter,
+	LabelGuestOSFamily,
+	LabelHardwareVersion,
+}
+
+// parsePublishLabels returns the labels listed by publish-labels, a
+// comma-separated list of label names without their prefix.
+func parsePublishLabels(value string) (map[string]bool, error) {
+	published := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		label := nodeLabelPrefix + name
+		known := false
+		for _, nodeLabel := range nodeLabels {
+			if label == nodeLabel {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, ErrUnknownNodeLabel
+		}
+		published[label] = true
+	}
+	return published, nil
+}
+
+// guestOSFamily returns the guest OS shorthand name without its version, like
+// centos for centos7.
+func guestOSFamily(guestOSType string) string {
+	name := strings.ToLower(guestOSName(guestOSType))
+	family := strings.TrimRight(name, "0123456789_")
+	if family == "" {
+		return name
+	}
+	return family
+}
+
+// nodeMetadataChanged returns true if the labels, annotations or taints
+// differ between the nodes, as opposed to status updates, which come every
+// few seconds and do not need the node to be synced.
+func nodeMetadataChanged(oldNode, newNode *v1.Node) bool {
+	return !reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+		!reflect.DeepEqual(oldNode.Annotations, newNode.Annotations) ||
+		!reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints)
+}
+
+// nodeLabeler keeps the ics.inspur.com/* labels of the nodes in sync with
+// their VM. Nodes are queued when they are discovered or updated, and labels
+// not allowed by publish-labels are removed.
+type nodeLabeler struct {
+	*nodeQueue
+	nodeManager *NodeManager
+	client      clientset.Interface
+}
+
+// newNodeLabeler returns a node labeler patching nodes with client.
+func newNodeLabeler(nm *NodeManager, client clientset.Interface) *nodeLabeler {
+	l := &nodeLabeler{
+		nodeManager: nm,
+		client:      client,
+	}
+	l.nodeQueue = newNodeQueue("nodelabels", l.sync)
+	return l
+}
+
+// enqueue queues the node with the UUID. It is a no-op if l is nil.
+func (l *nodeLabeler) enqueue(uuid string) {
+	if l == nil {
+		return
+	}
+	l.nodeQueue.enqueue(uuid)
+}
+
+// sync patches the labels of the node with the UUID that differ from the
+// ones of its VM.
+func (l *nodeLabeler) sync(uuid string) error {
+	nm := l.nodeManager
+	nodeInfo, node := nm.discoveredNode(uuid)
+	if node == nil {
+		return nil
+	}
+
+	desired, err := nm.nodeLabels(nodeInfo)
+	if err != nil {
+		return err
+	}
+
+	patch := make(map[string]interface{})
+	for _, label := range nodeLabels {
+		current, ok := node.Labels[label]
+		value, publish := desired[label]
+		if publish && (!ok || current != value) {
+			patch[label] = value
+		} else if !publish && ok {
+			patch[label] = nil
+		}
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": patch},
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Patching labels of node %s: %s", node.Name, data)
+	_, err = l.client.CoreV1().Nodes().Patch(node.Name, types.MergePatchType, data)
+	return err
+}
+
+// nodeLabels returns the labels allowed by publish-labels, from the VM of
+// the node. Labels with an empty value are left out.
+func (nm *NodeManager) nodeLabels(nodeInfo *NodeInfo) (map[string]string, error) {
+	labels := make(map[string]string)
+
+	cpiCfg := nm.config()
+	if cpiCfg == nil || nodeInfo.vm == nil || nodeInfo.vm.VirtualMachine == nil {
+		return labels, nil
+	}
+	published, err := parsePublishLabels(cpiCfg.Nodes.PublishLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := nodeInfo.vm
+	values := map[string]func() (string, error){
+		LabelHost: func() (string, error) { return vm.HostName, nil },
+		LabelCluster: func() (string, error) {
+			host, err := nm.vmHost(context.Background(), nodeInfo)
+			if err != nil {
+				return "", err
+			}
+			return host.ClusterName, nil
+		},
+		LabelDatacenter: func() (string, error) {
+			if nodeInfo.dataCenter == nil || nodeInfo.dataCenter.Datacenter == nil {
+				return "", nil
+			}
+			return nodeInfo.dataCenter.Name(), nil
+		},
+		LabelICenter:         func() (string, error) { return nodeInfo.vcServer, nil },
+		LabelGuestOSFamily:   func() (string, error) { return guestOSFamily(vm.GuestosType), nil },
+		LabelHardwareVersion: func() (string, error) { return vm.GuestOsInfo.Model, nil },
+	}
+
+	for _, label := range nodeLabels {
+		if !published[label] {
+			continue
+		}
+		value, err := values[label]()
+		if err != nil {
+			return nil, err
+		}
+		if value = toLabelValue(value); value != "" {
+			labels[label] = value
+		}
+	}
+	return labels, nil
+}
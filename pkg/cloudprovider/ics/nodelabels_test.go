@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeMetadataChanged(t *testing.T) {
+	node := func(labels, annotations map[string]string, taints []v1.Taint, ready v1.ConditionStatus) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: labels, Annotations: annotations},
+			Spec:       v1.NodeSpec{Taints: taints},
+			Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: ready},
+			}},
+		}
+	}
+	labels := map[string]string{"role": "worker"}
+	annotations := map[string]string{AnnotationInternalNetwork: "VM Network"}
+	taints := []v1.Taint{{Key: TaintShutdown, Effect: v1.TaintEffectNoSchedule}}
+	oldNode := node(labels, annotations, taints, v1.ConditionTrue)
+
+	tests := []struct {
+		name    string
+		newNode *v1.Node
+		want    bool
+	}{
+		{
+			name:    "status update",
+			newNode: node(labels, annotations, taints, v1.ConditionFalse),
+			want:    false,
+		},
+		{
+			name:    "label edited",
+			newNode: node(map[string]string{"role": "master"}, annotations, taints, v1.ConditionTrue),
+			want:    true,
+		},
+		{
+			name:    "annotation removed",
+			newNode: node(labels, nil, taints, v1.ConditionTrue),
+			want:    true,
+		},
+		{
+			name:    "taint removed",
+			newNode: node(labels, annotations, nil, v1.ConditionTrue),
+			want:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := nodeMetadataChanged(oldNode, test.newNode); got != test.want {
+				t.Errorf("nodeMetadataChanged() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
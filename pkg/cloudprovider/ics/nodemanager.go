@@ -19,7 +19,6 @@ package ics
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	//	"fmt"
 	"net"
@@ -51,7 +50,7 @@ var (
 )
 
 func newNodeManager(cpiCfg *CPIConfig, cm *cm.ConnectionManager) *NodeManager {
-	return &NodeManager{
+	nm := &NodeManager{
 		nodeNameMap:       make(map[string]*NodeInfo),
 		nodeUUIDMap:       make(map[string]*NodeInfo),
 		nodeRegUUIDMap:    make(map[string]*v1.Node),
@@ -59,22 +58,54 @@ func newNodeManager(cpiCfg *CPIConfig, cm *cm.ConnectionManager) *NodeManager {
 		connectionManager: cm,
 		cpiCfg:            cpiCfg,
 	}
+	nm.addressResolver = DefaultAddressResolver()
+	return nm
+}
+
+// SetAddressResolver replaces the resolver of node addresses. It must be
+// called before nodes are discovered.
+func (nm *NodeManager) SetAddressResolver(resolver AddressResolver) {
+	nm.addressResolver = resolver
 }
 
 // RegisterNode is the handler for when a node is added to a K8s cluster.
 func (nm *NodeManager) RegisterNode(node *v1.Node) {
 	klog.V(4).Info("RegisterNode ENTER: ", node.Name)
 	uuid := node.Status.NodeInfo.SystemUUID
-	nm.DiscoverNode(uuid, cm.FindVMByUUID)
+	// Registered first so that discovery sees the node's annotations
 	nm.addNode(uuid, node)
+	nm.DiscoverNode(uuid, cm.FindVMByUUID)
 	klog.V(4).Info("RegisterNode LEAVE: ", node.Name)
 }
 
+// UpdateNode is the handler for when a node is updated in a K8s cluster. The
+// node is discovered again if its address selection annotations changed, and
+// its labels and taints are reconciled.
+func (nm *NodeManager) UpdateNode(oldNode, newNode *v1.Node) {
+	uuid := newNode.Status.NodeInfo.SystemUUID
+	nm.addNode(uuid, newNode)
+	// Restores the labels and taints of the node if they were edited
+	if nodeMetadataChanged(oldNode, newNode) {
+		nm.syncNode(uuid)
+	}
+	if !addressAnnotationsChanged(oldNode, newNode) {
+		return
+	}
+
+	klog.V(2).Infof("Address selection annotations of node %s changed", newNode.Name)
+	nm.rediscoverNode(uuid)
+}
+
 // UnregisterNode is the handler for when a node is removed from a K8s cluster.
 func (nm *NodeManager) UnregisterNode(node *v1.Node) {
 	klog.V(4).Info("UnregisterNode ENTER: ", node.Name)
         uuid := node.Status.NodeInfo.SystemUUID
 	nm.removeNode(uuid, node)
+	nm.forgetEvents(node.Name)
+	// The node may have shared its host with other nodes of its groups, and
+	// its VM may be in a DRS rule
+	nm.hostAntiAffinityController.enqueue()
+	nm.antiAffinityRuleController.enqueue()
 	klog.V(4).Info("UnregisterNode LEAVE: ", node.Name)
 }
 
@@ -87,6 +118,24 @@ func (nm *NodeManager) addNodeInfo(node *NodeInfo) {
 	nm.nodeInfoLock.Unlock()
 }
 
+// removeNodeInfo drops a discovered node from the cache.
+func (nm *NodeManager) removeNodeInfo(node *NodeInfo) {
+	nm.nodeInfoLock.Lock()
+	klog.V(4).Info("removeNodeInfo NodeName: ", node.NodeName, ", UUID: ", node.UUID)
+	if nm.nodeNameMap[node.NodeName] == node {
+		delete(nm.nodeNameMap, node.NodeName)
+	}
+	if nm.nodeUUIDMap[node.UUID] == node {
+		delete(nm.nodeUUIDMap, node.UUID)
+	}
+	if vc := nm.vcList[node.vcServer]; vc != nil {
+		if dc := vc.dcList[node.dataCenter.Name()]; dc != nil && dc.vmList[node.UUID] == node {
+			delete(dc.vmList, node.UUID)
+		}
+	}
+	nm.nodeInfoLock.Unlock()
+}
+
 func (nm *NodeManager) addNode(uuid string, node *v1.Node) {
 	nm.nodeRegInfoLock.Lock()
 	klog.V(4).Info("addNode NodeName: ", node.GetName(), ", UID: ", uuid)
@@ -101,6 +150,23 @@ func (nm *NodeManager) removeNode(uuid string, node *v1.Node) {
 	nm.nodeRegInfoLock.Unlock()
 }
 
+// registeredNode returns the registered node with the UUID, or else with the
+// name, or nil if there is none.
+func (nm *NodeManager) registeredNode(uuid string, nodeName string) *v1.Node {
+	nm.nodeRegInfoLock.RLock()
+	defer nm.nodeRegInfoLock.RUnlock()
+
+	if node, ok := nm.nodeRegUUIDMap[uuid]; ok {
+		return node
+	}
+	for _, node := range nm.nodeRegUUIDMap {
+		if strings.EqualFold(node.Name, nodeName) {
+			return node
+		}
+	}
+	return nil
+}
+
 func (nm *NodeManager) shakeOutNodeIDLookup(ctx context.Context, nodeID string, searchBy cm.FindVM) (*cm.VMDiscoveryInfo, error) {
 	// Search by NodeName
 	if searchBy == cm.FindVMByName {
@@ -171,46 +237,16 @@ func (nm *NodeManager) DiscoverNode(nodeID string, searchBy cm.FindVM) error {
 	if vmDI.TenantRef != "" {
 		tenantRef = vmDI.TenantRef
 	}
-	vcInstance := nm.connectionManager.IcsInstanceMap[tenantRef]
+	vcInstance := nm.connectionManager.Instance(tenantRef)
 
-	ipFamily := []string{vcfg.DefaultIPFamily}
+	var vcConfig *vcfg.VirtualCenterConfig
 	if vcInstance != nil {
-		ipFamily = vcInstance.Cfg.IPFamilyPriority
+		vcConfig = vcInstance.Cfg
 	} else {
 		klog.Warningf("Unable to find vcInstance for %s. Defaulting to ipv4.", tenantRef)
 	}
 
-	var internalNetworkSubnet *net.IPNet
-	var externalNetworkSubnet *net.IPNet
-	var internalVMNetworkName string
-	var externalVMNetworkName string
-
-	if nm.cpiCfg != nil {
-		if nm.cpiCfg.Nodes.InternalNetworkSubnetCIDR != "" {
-			_, internalNetworkSubnet, err = net.ParseCIDR(nm.cpiCfg.Nodes.InternalNetworkSubnetCIDR)
-			if err != nil {
-				return err
-			}
-		}
-		if nm.cpiCfg.Nodes.ExternalNetworkSubnetCIDR != "" {
-			_, externalNetworkSubnet, err = net.ParseCIDR(nm.cpiCfg.Nodes.ExternalNetworkSubnetCIDR)
-			if err != nil {
-				return err
-			}
-		}
-		internalVMNetworkName = nm.cpiCfg.Nodes.InternalVMNetworkName
-		externalVMNetworkName = nm.cpiCfg.Nodes.ExternalVMNetworkName
-	}
-
-	var addressMatchingEnabled bool
-	if internalNetworkSubnet != nil && externalNetworkSubnet != nil {
-		addressMatchingEnabled = true
-	}
-
-//ics
-	found := false
 	addrs := []v1.NodeAddress{}
-
 	klog.V(2).Infof("Adding Hostname: %s", dstVM.VMHostName)
 	v1helper.AddToNodeAddresses(&addrs,
 		v1.NodeAddress{
@@ -218,130 +254,37 @@ func (nm *NodeManager) DiscoverNode(nodeID string, searchBy cm.FindVM) error {
 			Address: dstVM.VMHostName,
 		},
 	)
-//ics block
-	for _, v := range dstVM.Nics {
-		/*
-		if v.DeviceConfigId == -1 {
-			klog.V(4).Info("Skipping device because not a vNIC")
-			continue
-		}
-		*/
 
-		klog.V(6).Infof("internalVMNetworkName = %s", internalVMNetworkName)
-		klog.V(6).Infof("externalVMNetworkName = %s", externalVMNetworkName)
-		klog.V(6).Infof("v.NetworkName = %s", v.NetworkName)
-
-		if (internalVMNetworkName != "" && !strings.EqualFold(internalVMNetworkName, v.NetworkName)) &&
-			(externalVMNetworkName != "" && !strings.EqualFold(externalVMNetworkName, v.NetworkName)) {
-			klog.V(4).Infof("Skipping device because vNIC Network=%s doesn't match internal=%s or external=%s network names",
-				v.NetworkName, internalVMNetworkName, externalVMNetworkName)
-			continue
-		}
-
-		// Only return a single IP address based on the preference of IPFamily
-		// Must break out of loop in the event of ipv6,ipv4 where the NIC does
-		// contain a valid IPv6 and IPV4 address
-		var iplist []string
-		iplist = append(iplist, v.IP)
-		for _, family := range ipFamily {
-			ips := returnIPsFromSpecificFamily(family, iplist)
-
-			if addressMatchingEnabled {
-				for _, ip := range ips {
-					parsedIP := net.ParseIP(ip)
-					if parsedIP == nil {
-						return fmt.Errorf("can't parse IP: %s", ip)
-					}
-
-					if internalNetworkSubnet != nil && internalNetworkSubnet.Contains(parsedIP) {
-						klog.V(2).Infof("Adding Internal IP by AddressMatching: %s", ip)
-						v1helper.AddToNodeAddresses(&addrs,
-							v1.NodeAddress{
-								Type:    v1.NodeInternalIP,
-								Address: ip,
-							},
-						)
-					}
-
-					if externalNetworkSubnet != nil && externalNetworkSubnet.Contains(parsedIP) {
-						klog.V(2).Infof("Adding External IP by AddressMatching: %s", ip)
-						v1helper.AddToNodeAddresses(&addrs,
-							v1.NodeAddress{
-								Type:    v1.NodeExternalIP,
-								Address: ip,
-							},
-						)
-					}
-				}
-			} else if internalVMNetworkName != "" && strings.EqualFold(internalVMNetworkName, v.NetworkName) {
-				for _, ip := range ips {
-					klog.V(2).Infof("Adding Internal IP by NetworkName: %s", ip)
-					v1helper.AddToNodeAddresses(&addrs,
-						v1.NodeAddress{
-							Type:    v1.NodeInternalIP,
-							Address: ip,
-						},
-					)
-					found = true
-					break
-				}
-			} else if externalVMNetworkName != "" && strings.EqualFold(externalVMNetworkName, v.NetworkName) {
-				for _, ip := range ips {
-					klog.V(2).Infof("Adding External IP by NetworkName: %s", ip)
-					v1helper.AddToNodeAddresses(&addrs,
-						v1.NodeAddress{
-							Type:    v1.NodeExternalIP,
-							Address: ip,
-						},
-					)
-					found = true
-					break
-				}
-			} else {
-				for _, ip := range ips {
-					klog.V(2).Infof("Adding IP: %s", ip)
-					v1helper.AddToNodeAddresses(&addrs,
-						v1.NodeAddress{
-							Type:    v1.NodeExternalIP,
-							Address: ip,
-						}, v1.NodeAddress{
-							Type:    v1.NodeInternalIP,
-							Address: ip,
-						},
-					)
-					found = true
-					break
-				}
-			}
-
-			if found {
-				break
-			}
-		}
+	node := nm.registeredNode(vmDI.UUID, vmDI.NodeName)
+	sel, err := nm.addressSelection(vcConfig, node)
+	if err != nil {
+		return err
 	}
-//ics block
-	if !found {
-		klog.Warningf("Unable to find a suitable IP address. ipFamily: %s", ipFamily)
+	resolvedAddrs, err := nm.addressResolver.ResolveAddresses(vmDI.VM, sel, node)
+	if node != nil {
+		nm.reportInvalidAnnotations(node, sel.invalidAnnotations)
 	}
+	if err != nil {
+		return err
+	}
+	if len(resolvedAddrs) == 0 {
+		klog.Warningf("Unable to find a suitable IP address for node %s", nodeID)
+	}
+	v1helper.AddToNodeAddresses(&addrs, resolvedAddrs...)
 
 	klog.V(2).Infof("Found node %s as vm=%+v in vc=%s and datacenter=%s",
 		nodeID, vmDI.VM, vmDI.VcServer, vmDI.DataCenter.Name())
 	klog.V(2).Info("Hostname: ", dstVM.VMHostName, " UUID: ", dstVM.HostID)
 
-	os := "unknown"
-	os = dstVM.GuestosType
-
 	// store instance type in nodeinfo map
-	instanceType := fmt.Sprintf("ics-vm.cpu-%d.mem-%dgb.os-%s",
-		dstVM.CPUNum,
-		(dstVM.Memory / 1024),
-		os,
-	)
+	instanceType := nm.instanceType(vmDI.VM)
 
 	nodeInfo := &NodeInfo{tenantRef: tenantRef, dataCenter: vmDI.DataCenter, vm: vmDI.VM, vcServer: vmDI.VcServer,
-		UUID: vmDI.UUID, NodeName: vmDI.NodeName, NodeType: instanceType, NodeAddresses: addrs}
+		UUID: vmDI.UUID, NodeName: vmDI.NodeName, NodeType: instanceType, NodeAddresses: addrs,
+		diskControllers: vmDI.VM.DiskControllers()}
 
 	nm.addNodeInfo(nodeInfo)
+	nm.syncNode(nodeInfo.UUID)
 
 	return nil
 }
@@ -361,6 +304,10 @@ func (nm *NodeManager) GetNode(UUID string, node *pb.Node) error {
 	node.Addresses = make([]string, 0)
 	node.Uuid = nodeInfo.UUID
 
+	nm.nodeInfoLock.RLock()
+	exportDiskControllers(nodeInfo, node)
+	nm.nodeInfoLock.RUnlock()
+
 	for _, address := range nodeInfo.NodeAddresses {
 		switch address.Type {
 		case v1.NodeExternalIP:
@@ -424,6 +371,7 @@ func (nm *NodeManager) datacenterToNodeList(vmList map[string]*NodeInfo, nodeLis
 			Addresses:  make([]string, 0),
 			Uuid:       node.UUID,
 		}
+		exportDiskControllers(node, pbNode)
 		for _, address := range node.NodeAddresses {
 			switch address.Type {
 			case v1.NodeExternalIP:
@@ -495,3 +443,50 @@ func (nm *NodeManager) FindNodeInfo(UUID string) (*NodeInfo, error) {
 	klog.V(4).Infof("FindNodeInfo( %s ) FOUND", UUIDlower)
 	return nodeInfo, nil
 }
+
+// nodeInfoByUUID returns the cached NodeInfo of the node with the UUID, or
+// nil if it is not discovered.
+func (nm *NodeManager) nodeInfoByUUID(uuid string) *NodeInfo {
+	nm.nodeInfoLock.RLock()
+	defer nm.nodeInfoLock.RUnlock()
+	return nm.nodeUUIDMap[uuid]
+}
+
+// connection returns the connection to the iCenter of the node.
+func (nm *NodeManager) connection(nodeInfo *NodeInfo) (*icslib.ICSConnection, error) {
+	return nm.tenantConnection(nodeInfo.tenantRef)
+}
+
+// tenantConnection returns the connection to the iCenter with tenantRef.
+func (nm *NodeManager) tenantConnection(tenantRef string) (*icslib.ICSConnection, error) {
+	if nm.connectionManager == nil {
+		return nil, ErrICenterNotFound
+	}
+	vcInstance := nm.connectionManager.Instance(tenantRef)
+	if vcInstance == nil {
+		return nil, ErrICenterNotFound
+	}
+	return vcInstance.Conn, nil
+}
+
+// vmHost returns the host running the VM of the node.
+func (nm *NodeManager) vmHost(ctx context.Context, nodeInfo *NodeInfo) (*icslib.Host, error) {
+	conn, err := nm.connection(nodeInfo)
+	if err != nil {
+		return nil, err
+	}
+	return nodeInfo.vm.HostSystem(ctx, conn)
+}
+
+// currentVM reads the VM of the node from iCenter, as the cached one may be
+// stale.
+func (nm *NodeManager) currentVM(ctx context.Context, nodeInfo *NodeInfo) (*icslib.VirtualMachine, error) {
+	if nodeInfo.vm == nil || nodeInfo.vm.VirtualMachine == nil {
+		return nil, ErrVMNotFound
+	}
+	conn, err := nm.connection(nodeInfo)
+	if err != nil {
+		return nil, err
+	}
+	return icslib.GetVMByID(ctx, conn, nodeInfo.vm.ID)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+)
+
+// nodeQueueRetryPeriod is the period of the node queue worker.
+const nodeQueueRetryPeriod = time.Second
+
+// nodeQueue is a rate limited work queue of nodes, keyed by lowercased UUID,
+// shared by the node controllers. Failed syncs are retried with backoff.
+type nodeQueue struct {
+	name  string
+	queue workqueue.RateLimitingInterface
+	sync  func(uuid string) error
+}
+
+// newNodeQueue returns a node queue syncing the nodes with sync.
+func newNodeQueue(name string, sync func(uuid string) error) *nodeQueue {
+	return &nodeQueue{
+		name:  name,
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name),
+		sync:  sync,
+	}
+}
+
+// Run syncs the queued nodes until stop is closed.
+func (q *nodeQueue) Run(stop <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer q.queue.ShutDown()
+
+	klog.V(1).Infof("Starting the %s controller", q.name)
+	go wait.Until(q.worker, nodeQueueRetryPeriod, stop)
+	<-stop
+	klog.V(1).Infof("Stopping the %s controller", q.name)
+}
+
+// RunWithResync is Run, calling resync every period as well. The period is
+// read again after each resync so config reloads apply.
+func (q *nodeQueue) RunWithResync(stop <-chan struct{}, period func() time.Duration, resync func()) {
+	go func() {
+		for {
+			timer := time.NewTimer(period())
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+				resync()
+			}
+		}
+	}()
+	q.Run(stop)
+}
+
+// enqueue queues the node with the UUID.
+func (q *nodeQueue) enqueue(uuid string) {
+	if uuid == "" {
+		return
+	}
+	q.queue.Add(strings.ToLower(uuid))
+}
+
+func (q *nodeQueue) worker() {
+	for q.processNextItem() {
+	}
+}
+
+func (q *nodeQueue) processNextItem() bool {
+	key, quit := q.queue.Get()
+	if quit {
+		return false
+	}
+	defer q.queue.Done(key)
+
+	uuid := key.(string)
+	if err := q.sync(uuid); err != nil {
+		klog.Warningf("Failed to sync node with UUID=%s in the %s controller, retrying. err=%v", uuid, q.name, err)
+		q.queue.AddRateLimited(key)
+		return true
+	}
+	q.queue.Forget(key)
+	return true
+}
+
+// discoveredNode returns the NodeInfo and the registered node with the UUID,
+// or nils if the node is either not discovered or not registered.
+func (nm *NodeManager) discoveredNode(uuid string) (*NodeInfo, *v1.Node) {
+	nm.nodeInfoLock.RLock()
+	nodeInfo := nm.nodeUUIDMap[uuid]
+	nm.nodeInfoLock.RUnlock()
+	if nodeInfo == nil {
+		klog.V(4).Infof("Node with UUID=%s is not discovered", uuid)
+		return nil, nil
+	}
+	node := nm.registeredNode(uuid, nodeInfo.NodeName)
+	if node == nil {
+		klog.V(4).Infof("Node with UUID=%s is not registered", uuid)
+		return nil, nil
+	}
+	return nodeInfo, node
+}
+
+// registeredUUIDs returns the UUIDs of the registered nodes.
+func (nm *NodeManager) registeredUUIDs() []string {
+	nm.nodeRegInfoLock.RLock()
+	defer nm.nodeRegInfoLock.RUnlock()
+
+	uuids := make([]string, 0, len(nm.nodeRegUUIDMap))
+	for uuid := range nm.nodeRegUUIDMap {
+		uuids = append(uuids, uuid)
+	}
+	return uuids
+}
+
+// syncNode queues the node with the UUID in the node controllers, once they
+// are started.
+func (nm *NodeManager) syncNode(uuid string) {
+	nm.nodeLabeler.enqueue(uuid)
+	nm.vmTagSyncer.enqueue(uuid)
+	nm.powerStateController.enqueue(uuid)
+	nm.diskControllerSyncer.enqueue(uuid)
+	nm.hostAntiAffinityController.enqueue()
+	nm.antiAffinityRuleController.enqueue()
+}
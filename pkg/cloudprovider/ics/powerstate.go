@@ -0,0 +1,209 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// Errors
+var (
+	// ErrInvalidPowerStateSyncPeriod is returned when the power state sync
+	// period is not a positive duration.
+	ErrInvalidPowerStateSyncPeriod = errors.New("Invalid power state sync period")
+)
+
+// Node taints driven by the power state of the VM and its host.
+const (
+	// TaintShutdown is set on nodes whose VM is powered off. It is the taint
+	// of the cloud node lifecycle controller, which agrees on its meaning
+	// through InstanceShutdownByProviderID.
+	TaintShutdown = "node.cloudprovider.kubernetes.io/shutdown"
+	// TaintHostMaintenance is set on nodes whose VM runs on a host in
+	// maintenance mode.
+	TaintHostMaintenance = "ics.inspur.com/host-maintenance"
+)
+
+const (
+	// EventReasonPoweredOff is the reason of the Events reporting a VM that
+	// was powered off.
+	EventReasonPoweredOff = "PoweredOff"
+	// EventReasonHostMaintenance is the reason of the Events reporting the
+	// host of a VM entering maintenance mode.
+	EventReasonHostMaintenance = "HostMaintenance"
+)
+
+// defaultPowerStateSyncPeriod is the default period of the power state sync.
+const defaultPowerStateSyncPeriod = time.Minute
+
+// validatePowerStateSyncPeriod checks the power state sync period.
+func (cfg *CPIConfig) validatePowerStateSyncPeriod() []error {
+	if cfg.Nodes.PowerStateSyncPeriod == "" {
+		cfg.Nodes.PowerStateSyncPeriod = defaultPowerStateSyncPeriod.String()
+	}
+	if period, err := time.ParseDuration(cfg.Nodes.PowerStateSyncPeriod); err != nil || period <= 0 {
+		return []error{icscfg.NewFieldError(icscfg.SectionPath("Nodes", "power-state-sync-period"),
+			cfg.Nodes.PowerStateSyncPeriod, ErrInvalidPowerStateSyncPeriod)}
+	}
+	return nil
+}
+
+// powerStateSyncPeriod returns the period of the power state sync.
+func (nm *NodeManager) powerStateSyncPeriod() time.Duration {
+	if cpiCfg := nm.config(); cpiCfg != nil {
+		if period, err := time.ParseDuration(cpiCfg.Nodes.PowerStateSyncPeriod); err == nil && period > 0 {
+			return period
+		}
+	}
+	return defaultPowerStateSyncPeriod
+}
+
+// powerStateController taints the nodes whose VM is powered off, and the ones
+// whose host is in maintenance mode. Nodes are synced periodically and when
+// they are discovered or updated.
+//
+// A VM in an unknown state, like suspended or being started, keeps the
+// shutdown taint it has, or does not have. So does a VM whose host cannot be
+// read keep the maintenance taint.
+type powerStateController struct {
+	*nodeQueue
+	nodeManager *NodeManager
+	client      clientset.Interface
+}
+
+// newPowerStateController returns a power state controller patching nodes
+// with client.
+func newPowerStateController(nm *NodeManager, client clientset.Interface) *powerStateController {
+	c := &powerStateController{
+		nodeManager: nm,
+		client:      client,
+	}
+	c.nodeQueue = newNodeQueue("powerstate", c.sync)
+	return c
+}
+
+// Run syncs the queued nodes, and all registered nodes every sync period,
+// until stop is closed.
+func (c *powerStateController) Run(stop <-chan struct{}) {
+	c.nodeQueue.RunWithResync(stop, c.nodeManager.powerStateSyncPeriod, c.enqueueAll)
+}
+
+// enqueue queues the node with the UUID. It is a no-op if c is nil.
+func (c *powerStateController) enqueue(uuid string) {
+	if c == nil {
+		return
+	}
+	c.nodeQueue.enqueue(uuid)
+}
+
+// enqueueAll queues the registered nodes. It is a no-op if c is nil.
+func (c *powerStateController) enqueueAll() {
+	if c == nil {
+		return
+	}
+	for _, uuid := range c.nodeManager.registeredUUIDs() {
+		c.nodeQueue.enqueue(uuid)
+	}
+}
+
+// sync reads the power state of the VM of the node with the UUID, and the
+// state of its host, from iCenter and patches the taints of the node.
+func (c *powerStateController) sync(uuid string) error {
+	nm := c.nodeManager
+	nodeInfo, node := nm.discoveredNode(uuid)
+	if node == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	vm, err := nm.currentVM(ctx, nodeInfo)
+	if err != nil {
+		return err
+	}
+
+	desired := make(map[string]bool)
+	known := make(map[string]bool)
+	switch state := vm.PowerState(); state {
+	case icslib.PowerStateOff:
+		desired[TaintShutdown], known[TaintShutdown] = true, true
+	case icslib.PowerStateOn:
+		known[TaintShutdown] = true
+	default:
+		klog.V(4).Infof("VM of node %s is %s, keeping its shutdown taint", node.Name, state)
+	}
+
+	if conn, err := nm.connection(nodeInfo); err != nil {
+		klog.Warningf("Keeping the host maintenance taint of node %s. err=%v", node.Name, err)
+	} else if host, err := vm.HostSystem(ctx, conn); err != nil {
+		klog.Warningf("Failed to read the host of node %s, keeping its maintenance taint. err=%v", node.Name, err)
+	} else {
+		desired[TaintHostMaintenance], known[TaintHostMaintenance] = host.InMaintenance(), true
+	}
+
+	var taints []v1.Taint
+	present := make(map[string]bool)
+	changed := false
+	for _, taint := range node.Spec.Taints {
+		if known[taint.Key] && taint.Effect == v1.TaintEffectNoSchedule {
+			present[taint.Key] = true
+			if !desired[taint.Key] {
+				klog.V(2).Infof("Removing taint %s of node %s", taint.Key, node.Name)
+				changed = true
+				continue
+			}
+		}
+		taints = append(taints, taint)
+	}
+	for _, key := range []string{TaintShutdown, TaintHostMaintenance} {
+		if !desired[key] || present[key] {
+			continue
+		}
+		klog.V(2).Infof("Adding taint %s to node %s", key, node.Name)
+		taints = append(taints, v1.Taint{Key: key, Effect: v1.TaintEffectNoSchedule})
+		changed = true
+
+		if key == TaintShutdown {
+			nm.recordEvent(node, v1.EventTypeNormal, EventReasonPoweredOff, "VM %s is powered off", vm.Name)
+		} else {
+			nm.recordEvent(node, v1.EventTypeWarning, EventReasonHostMaintenance, "Host %s of VM %s is in maintenance mode", vm.HostName, vm.Name)
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"resourceVersion": node.ResourceVersion},
+		"spec":     map[string]interface{}{"taints": taints},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.client.CoreV1().Nodes().Patch(node.Name, types.MergePatchType, data)
+	return err
+}
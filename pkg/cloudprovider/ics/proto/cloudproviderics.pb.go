@@ -25,15 +25,17 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type Node struct {
-	Vcenter              string   `protobuf:"bytes,1,opt,name=vcenter,proto3" json:"vcenter,omitempty"`
-	Datacenter           string   `protobuf:"bytes,2,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
-	Name                 string   `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
-	Dnsnames             []string `protobuf:"bytes,4,rep,name=dnsnames,proto3" json:"dnsnames,omitempty"`
-	Addresses            []string `protobuf:"bytes,5,rep,name=addresses,proto3" json:"addresses,omitempty"`
-	Uuid                 string   `protobuf:"bytes,6,opt,name=uuid,proto3" json:"uuid,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Vcenter              string            `protobuf:"bytes,1,opt,name=vcenter,proto3" json:"vcenter,omitempty"`
+	Datacenter           string            `protobuf:"bytes,2,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Name                 string            `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Dnsnames             []string          `protobuf:"bytes,4,rep,name=dnsnames,proto3" json:"dnsnames,omitempty"`
+	Addresses            []string          `protobuf:"bytes,5,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Uuid                 string            `protobuf:"bytes,6,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	DiskControllers      []*DiskController `protobuf:"bytes,7,rep,name=disk_controllers,json=diskControllers,proto3" json:"disk_controllers,omitempty"`
+	FreeDiskSlots        int32             `protobuf:"varint,8,opt,name=free_disk_slots,json=freeDiskSlots,proto3" json:"free_disk_slots,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *Node) Reset()         { *m = Node{} }
@@ -103,6 +105,75 @@ func (m *Node) GetUuid() string {
 	return ""
 }
 
+func (m *Node) GetDiskControllers() []*DiskController {
+	if m != nil {
+		return m.DiskControllers
+	}
+	return nil
+}
+
+func (m *Node) GetFreeDiskSlots() int32 {
+	if m != nil {
+		return m.FreeDiskSlots
+	}
+	return 0
+}
+
+type DiskController struct {
+	Bus                  string   `protobuf:"bytes,1,opt,name=bus,proto3" json:"bus,omitempty"`
+	Disks                int32    `protobuf:"varint,2,opt,name=disks,proto3" json:"disks,omitempty"`
+	Slots                int32    `protobuf:"varint,3,opt,name=slots,proto3" json:"slots,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DiskController) Reset()         { *m = DiskController{} }
+func (m *DiskController) String() string { return proto.CompactTextString(m) }
+func (*DiskController) ProtoMessage()    {}
+func (*DiskController) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{1}
+}
+
+func (m *DiskController) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiskController.Unmarshal(m, b)
+}
+func (m *DiskController) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiskController.Marshal(b, m, deterministic)
+}
+func (m *DiskController) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiskController.Merge(m, src)
+}
+func (m *DiskController) XXX_Size() int {
+	return xxx_messageInfo_DiskController.Size(m)
+}
+func (m *DiskController) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiskController.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiskController proto.InternalMessageInfo
+
+func (m *DiskController) GetBus() string {
+	if m != nil {
+		return m.Bus
+	}
+	return ""
+}
+
+func (m *DiskController) GetDisks() int32 {
+	if m != nil {
+		return m.Disks
+	}
+	return 0
+}
+
+func (m *DiskController) GetSlots() int32 {
+	if m != nil {
+		return m.Slots
+	}
+	return 0
+}
+
 type GetNodeRequest struct {
 	Uuid                 string   `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -114,7 +185,7 @@ func (m *GetNodeRequest) Reset()         { *m = GetNodeRequest{} }
 func (m *GetNodeRequest) String() string { return proto.CompactTextString(m) }
 func (*GetNodeRequest) ProtoMessage()    {}
 func (*GetNodeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_630e23fe7cf01247, []int{1}
+	return fileDescriptor_630e23fe7cf01247, []int{2}
 }
 
 func (m *GetNodeRequest) XXX_Unmarshal(b []byte) error {
@@ -154,7 +225,7 @@ func (m *GetNodeReply) Reset()         { *m = GetNodeReply{} }
 func (m *GetNodeReply) String() string { return proto.CompactTextString(m) }
 func (*GetNodeReply) ProtoMessage()    {}
 func (*GetNodeReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_630e23fe7cf01247, []int{2}
+	return fileDescriptor_630e23fe7cf01247, []int{3}
 }
 
 func (m *GetNodeReply) XXX_Unmarshal(b []byte) error {
@@ -201,7 +272,7 @@ func (m *ListNodesRequest) Reset()         { *m = ListNodesRequest{} }
 func (m *ListNodesRequest) String() string { return proto.CompactTextString(m) }
 func (*ListNodesRequest) ProtoMessage()    {}
 func (*ListNodesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_630e23fe7cf01247, []int{3}
+	return fileDescriptor_630e23fe7cf01247, []int{4}
 }
 
 func (m *ListNodesRequest) XXX_Unmarshal(b []byte) error {
@@ -248,7 +319,7 @@ func (m *ListNodesReply) Reset()         { *m = ListNodesReply{} }
 func (m *ListNodesReply) String() string { return proto.CompactTextString(m) }
 func (*ListNodesReply) ProtoMessage()    {}
 func (*ListNodesReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_630e23fe7cf01247, []int{4}
+	return fileDescriptor_630e23fe7cf01247, []int{5}
 }
 
 func (m *ListNodesReply) XXX_Unmarshal(b []byte) error {
@@ -283,6 +354,668 @@ func (m *ListNodesReply) GetError() string {
 	return ""
 }
 
+type HostViolation struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Vcenter              string   `protobuf:"bytes,2,opt,name=vcenter,proto3" json:"vcenter,omitempty"`
+	Datacenter           string   `protobuf:"bytes,3,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Host                 string   `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	Nodes                []string `protobuf:"bytes,5,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HostViolation) Reset()         { *m = HostViolation{} }
+func (m *HostViolation) String() string { return proto.CompactTextString(m) }
+func (*HostViolation) ProtoMessage()    {}
+func (*HostViolation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{6}
+}
+
+func (m *HostViolation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HostViolation.Unmarshal(m, b)
+}
+func (m *HostViolation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HostViolation.Marshal(b, m, deterministic)
+}
+func (m *HostViolation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HostViolation.Merge(m, src)
+}
+func (m *HostViolation) XXX_Size() int {
+	return xxx_messageInfo_HostViolation.Size(m)
+}
+func (m *HostViolation) XXX_DiscardUnknown() {
+	xxx_messageInfo_HostViolation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HostViolation proto.InternalMessageInfo
+
+func (m *HostViolation) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *HostViolation) GetVcenter() string {
+	if m != nil {
+		return m.Vcenter
+	}
+	return ""
+}
+
+func (m *HostViolation) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *HostViolation) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *HostViolation) GetNodes() []string {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+type ListHostViolationsRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListHostViolationsRequest) Reset()         { *m = ListHostViolationsRequest{} }
+func (m *ListHostViolationsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListHostViolationsRequest) ProtoMessage()    {}
+func (*ListHostViolationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{7}
+}
+
+func (m *ListHostViolationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListHostViolationsRequest.Unmarshal(m, b)
+}
+func (m *ListHostViolationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListHostViolationsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListHostViolationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListHostViolationsRequest.Merge(m, src)
+}
+func (m *ListHostViolationsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListHostViolationsRequest.Size(m)
+}
+func (m *ListHostViolationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListHostViolationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListHostViolationsRequest proto.InternalMessageInfo
+
+func (m *ListHostViolationsRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+type ListHostViolationsReply struct {
+	Violations           []*HostViolation `protobuf:"bytes,1,rep,name=violations,proto3" json:"violations,omitempty"`
+	Error                string           `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *ListHostViolationsReply) Reset()         { *m = ListHostViolationsReply{} }
+func (m *ListHostViolationsReply) String() string { return proto.CompactTextString(m) }
+func (*ListHostViolationsReply) ProtoMessage()    {}
+func (*ListHostViolationsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{8}
+}
+
+func (m *ListHostViolationsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListHostViolationsReply.Unmarshal(m, b)
+}
+func (m *ListHostViolationsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListHostViolationsReply.Marshal(b, m, deterministic)
+}
+func (m *ListHostViolationsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListHostViolationsReply.Merge(m, src)
+}
+func (m *ListHostViolationsReply) XXX_Size() int {
+	return xxx_messageInfo_ListHostViolationsReply.Size(m)
+}
+func (m *ListHostViolationsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListHostViolationsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListHostViolationsReply proto.InternalMessageInfo
+
+func (m *ListHostViolationsReply) GetViolations() []*HostViolation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
+func (m *ListHostViolationsReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type Disk struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Vcenter              string   `protobuf:"bytes,3,opt,name=vcenter,proto3" json:"vcenter,omitempty"`
+	Datacenter           string   `protobuf:"bytes,4,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Datastore            string   `protobuf:"bytes,5,opt,name=datastore,proto3" json:"datastore,omitempty"`
+	Size                 float64  `protobuf:"fixed64,6,opt,name=size,proto3" json:"size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Disk) Reset()         { *m = Disk{} }
+func (m *Disk) String() string { return proto.CompactTextString(m) }
+func (*Disk) ProtoMessage()    {}
+func (*Disk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{9}
+}
+
+func (m *Disk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Disk.Unmarshal(m, b)
+}
+func (m *Disk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Disk.Marshal(b, m, deterministic)
+}
+func (m *Disk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Disk.Merge(m, src)
+}
+func (m *Disk) XXX_Size() int {
+	return xxx_messageInfo_Disk.Size(m)
+}
+func (m *Disk) XXX_DiscardUnknown() {
+	xxx_messageInfo_Disk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Disk proto.InternalMessageInfo
+
+func (m *Disk) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Disk) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Disk) GetVcenter() string {
+	if m != nil {
+		return m.Vcenter
+	}
+	return ""
+}
+
+func (m *Disk) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *Disk) GetDatastore() string {
+	if m != nil {
+		return m.Datastore
+	}
+	return ""
+}
+
+func (m *Disk) GetSize() float64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type GetDiskRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetDiskRequest) Reset()         { *m = GetDiskRequest{} }
+func (m *GetDiskRequest) String() string { return proto.CompactTextString(m) }
+func (*GetDiskRequest) ProtoMessage()    {}
+func (*GetDiskRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{10}
+}
+
+func (m *GetDiskRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetDiskRequest.Unmarshal(m, b)
+}
+func (m *GetDiskRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDiskRequest.Marshal(b, m, deterministic)
+}
+func (m *GetDiskRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDiskRequest.Merge(m, src)
+}
+func (m *GetDiskRequest) XXX_Size() int {
+	return xxx_messageInfo_GetDiskRequest.Size(m)
+}
+func (m *GetDiskRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDiskRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDiskRequest proto.InternalMessageInfo
+
+func (m *GetDiskRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type GetDiskReply struct {
+	Disk                 *Disk    `protobuf:"bytes,1,opt,name=disk,proto3" json:"disk,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetDiskReply) Reset()         { *m = GetDiskReply{} }
+func (m *GetDiskReply) String() string { return proto.CompactTextString(m) }
+func (*GetDiskReply) ProtoMessage()    {}
+func (*GetDiskReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{11}
+}
+
+func (m *GetDiskReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetDiskReply.Unmarshal(m, b)
+}
+func (m *GetDiskReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDiskReply.Marshal(b, m, deterministic)
+}
+func (m *GetDiskReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDiskReply.Merge(m, src)
+}
+func (m *GetDiskReply) XXX_Size() int {
+	return xxx_messageInfo_GetDiskReply.Size(m)
+}
+func (m *GetDiskReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDiskReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDiskReply proto.InternalMessageInfo
+
+func (m *GetDiskReply) GetDisk() *Disk {
+	if m != nil {
+		return m.Disk
+	}
+	return nil
+}
+
+func (m *GetDiskReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type Datastore struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Vcenter              string   `protobuf:"bytes,3,opt,name=vcenter,proto3" json:"vcenter,omitempty"`
+	Datacenter           string   `protobuf:"bytes,4,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Type                 string   `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Capacity             float64  `protobuf:"fixed64,6,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	FreeSpace            float64  `protobuf:"fixed64,7,opt,name=free_space,json=freeSpace,proto3" json:"free_space,omitempty"`
+	Hosts                []string `protobuf:"bytes,8,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	Cluster              string   `protobuf:"bytes,9,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Zone                 string   `protobuf:"bytes,10,opt,name=zone,proto3" json:"zone,omitempty"`
+	Region               string   `protobuf:"bytes,11,opt,name=region,proto3" json:"region,omitempty"`
+	StoragePolicies      []string `protobuf:"bytes,12,rep,name=storage_policies,json=storagePolicies,proto3" json:"storage_policies,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Datastore) Reset()         { *m = Datastore{} }
+func (m *Datastore) String() string { return proto.CompactTextString(m) }
+func (*Datastore) ProtoMessage()    {}
+func (*Datastore) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{12}
+}
+
+func (m *Datastore) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Datastore.Unmarshal(m, b)
+}
+func (m *Datastore) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Datastore.Marshal(b, m, deterministic)
+}
+func (m *Datastore) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Datastore.Merge(m, src)
+}
+func (m *Datastore) XXX_Size() int {
+	return xxx_messageInfo_Datastore.Size(m)
+}
+func (m *Datastore) XXX_DiscardUnknown() {
+	xxx_messageInfo_Datastore.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Datastore proto.InternalMessageInfo
+
+func (m *Datastore) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Datastore) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Datastore) GetVcenter() string {
+	if m != nil {
+		return m.Vcenter
+	}
+	return ""
+}
+
+func (m *Datastore) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *Datastore) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Datastore) GetCapacity() float64 {
+	if m != nil {
+		return m.Capacity
+	}
+	return 0
+}
+
+func (m *Datastore) GetFreeSpace() float64 {
+	if m != nil {
+		return m.FreeSpace
+	}
+	return 0
+}
+
+func (m *Datastore) GetHosts() []string {
+	if m != nil {
+		return m.Hosts
+	}
+	return nil
+}
+
+func (m *Datastore) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *Datastore) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+func (m *Datastore) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+func (m *Datastore) GetStoragePolicies() []string {
+	if m != nil {
+		return m.StoragePolicies
+	}
+	return nil
+}
+
+type DatastoreCluster struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Vcenter              string   `protobuf:"bytes,3,opt,name=vcenter,proto3" json:"vcenter,omitempty"`
+	Datacenter           string   `protobuf:"bytes,4,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Capacity             float64  `protobuf:"fixed64,5,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	FreeSpace            float64  `protobuf:"fixed64,6,opt,name=free_space,json=freeSpace,proto3" json:"free_space,omitempty"`
+	Datastores           []string `protobuf:"bytes,7,rep,name=datastores,proto3" json:"datastores,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DatastoreCluster) Reset()         { *m = DatastoreCluster{} }
+func (m *DatastoreCluster) String() string { return proto.CompactTextString(m) }
+func (*DatastoreCluster) ProtoMessage()    {}
+func (*DatastoreCluster) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{13}
+}
+
+func (m *DatastoreCluster) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DatastoreCluster.Unmarshal(m, b)
+}
+func (m *DatastoreCluster) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DatastoreCluster.Marshal(b, m, deterministic)
+}
+func (m *DatastoreCluster) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DatastoreCluster.Merge(m, src)
+}
+func (m *DatastoreCluster) XXX_Size() int {
+	return xxx_messageInfo_DatastoreCluster.Size(m)
+}
+func (m *DatastoreCluster) XXX_DiscardUnknown() {
+	xxx_messageInfo_DatastoreCluster.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DatastoreCluster proto.InternalMessageInfo
+
+func (m *DatastoreCluster) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *DatastoreCluster) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DatastoreCluster) GetVcenter() string {
+	if m != nil {
+		return m.Vcenter
+	}
+	return ""
+}
+
+func (m *DatastoreCluster) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *DatastoreCluster) GetCapacity() float64 {
+	if m != nil {
+		return m.Capacity
+	}
+	return 0
+}
+
+func (m *DatastoreCluster) GetFreeSpace() float64 {
+	if m != nil {
+		return m.FreeSpace
+	}
+	return 0
+}
+
+func (m *DatastoreCluster) GetDatastores() []string {
+	if m != nil {
+		return m.Datastores
+	}
+	return nil
+}
+
+type ListDatastoresRequest struct {
+	Vcenter              string   `protobuf:"bytes,1,opt,name=vcenter,proto3" json:"vcenter,omitempty"`
+	Datacenter           string   `protobuf:"bytes,2,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Zone                 string   `protobuf:"bytes,3,opt,name=zone,proto3" json:"zone,omitempty"`
+	Region               string   `protobuf:"bytes,4,opt,name=region,proto3" json:"region,omitempty"`
+	StoragePolicy        string   `protobuf:"bytes,5,opt,name=storage_policy,json=storagePolicy,proto3" json:"storage_policy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListDatastoresRequest) Reset()         { *m = ListDatastoresRequest{} }
+func (m *ListDatastoresRequest) String() string { return proto.CompactTextString(m) }
+func (*ListDatastoresRequest) ProtoMessage()    {}
+func (*ListDatastoresRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{14}
+}
+
+func (m *ListDatastoresRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDatastoresRequest.Unmarshal(m, b)
+}
+func (m *ListDatastoresRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDatastoresRequest.Marshal(b, m, deterministic)
+}
+func (m *ListDatastoresRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDatastoresRequest.Merge(m, src)
+}
+func (m *ListDatastoresRequest) XXX_Size() int {
+	return xxx_messageInfo_ListDatastoresRequest.Size(m)
+}
+func (m *ListDatastoresRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDatastoresRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDatastoresRequest proto.InternalMessageInfo
+
+func (m *ListDatastoresRequest) GetVcenter() string {
+	if m != nil {
+		return m.Vcenter
+	}
+	return ""
+}
+
+func (m *ListDatastoresRequest) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *ListDatastoresRequest) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+func (m *ListDatastoresRequest) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+func (m *ListDatastoresRequest) GetStoragePolicy() string {
+	if m != nil {
+		return m.StoragePolicy
+	}
+	return ""
+}
+
+type ListDatastoresReply struct {
+	Datastores           []*Datastore        `protobuf:"bytes,1,rep,name=datastores,proto3" json:"datastores,omitempty"`
+	Clusters             []*DatastoreCluster `protobuf:"bytes,2,rep,name=clusters,proto3" json:"clusters,omitempty"`
+	Error                string              `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *ListDatastoresReply) Reset()         { *m = ListDatastoresReply{} }
+func (m *ListDatastoresReply) String() string { return proto.CompactTextString(m) }
+func (*ListDatastoresReply) ProtoMessage()    {}
+func (*ListDatastoresReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_630e23fe7cf01247, []int{15}
+}
+
+func (m *ListDatastoresReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDatastoresReply.Unmarshal(m, b)
+}
+func (m *ListDatastoresReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDatastoresReply.Marshal(b, m, deterministic)
+}
+func (m *ListDatastoresReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDatastoresReply.Merge(m, src)
+}
+func (m *ListDatastoresReply) XXX_Size() int {
+	return xxx_messageInfo_ListDatastoresReply.Size(m)
+}
+func (m *ListDatastoresReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDatastoresReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDatastoresReply proto.InternalMessageInfo
+
+func (m *ListDatastoresReply) GetDatastores() []*Datastore {
+	if m != nil {
+		return m.Datastores
+	}
+	return nil
+}
+
+func (m *ListDatastoresReply) GetClusters() []*DatastoreCluster {
+	if m != nil {
+		return m.Clusters
+	}
+	return nil
+}
+
+func (m *ListDatastoresReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 type VersionRequest struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -293,7 +1026,7 @@ func (m *VersionRequest) Reset()         { *m = VersionRequest{} }
 func (m *VersionRequest) String() string { return proto.CompactTextString(m) }
 func (*VersionRequest) ProtoMessage()    {}
 func (*VersionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_630e23fe7cf01247, []int{5}
+	return fileDescriptor_630e23fe7cf01247, []int{16}
 }
 
 func (m *VersionRequest) XXX_Unmarshal(b []byte) error {
@@ -325,7 +1058,7 @@ func (m *VersionReply) Reset()         { *m = VersionReply{} }
 func (m *VersionReply) String() string { return proto.CompactTextString(m) }
 func (*VersionReply) ProtoMessage()    {}
 func (*VersionReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_630e23fe7cf01247, []int{6}
+	return fileDescriptor_630e23fe7cf01247, []int{17}
 }
 
 func (m *VersionReply) XXX_Unmarshal(b []byte) error {
@@ -355,10 +1088,21 @@ func (m *VersionReply) GetVersion() string {
 
 func init() {
 	proto.RegisterType((*Node)(nil), "cloudproviderics.Node")
+	proto.RegisterType((*DiskController)(nil), "cloudproviderics.DiskController")
 	proto.RegisterType((*GetNodeRequest)(nil), "cloudproviderics.GetNodeRequest")
 	proto.RegisterType((*GetNodeReply)(nil), "cloudproviderics.GetNodeReply")
 	proto.RegisterType((*ListNodesRequest)(nil), "cloudproviderics.ListNodesRequest")
 	proto.RegisterType((*ListNodesReply)(nil), "cloudproviderics.ListNodesReply")
+	proto.RegisterType((*HostViolation)(nil), "cloudproviderics.HostViolation")
+	proto.RegisterType((*ListHostViolationsRequest)(nil), "cloudproviderics.ListHostViolationsRequest")
+	proto.RegisterType((*ListHostViolationsReply)(nil), "cloudproviderics.ListHostViolationsReply")
+	proto.RegisterType((*Disk)(nil), "cloudproviderics.Disk")
+	proto.RegisterType((*GetDiskRequest)(nil), "cloudproviderics.GetDiskRequest")
+	proto.RegisterType((*GetDiskReply)(nil), "cloudproviderics.GetDiskReply")
+	proto.RegisterType((*Datastore)(nil), "cloudproviderics.Datastore")
+	proto.RegisterType((*DatastoreCluster)(nil), "cloudproviderics.DatastoreCluster")
+	proto.RegisterType((*ListDatastoresRequest)(nil), "cloudproviderics.ListDatastoresRequest")
+	proto.RegisterType((*ListDatastoresReply)(nil), "cloudproviderics.ListDatastoresReply")
 	proto.RegisterType((*VersionRequest)(nil), "cloudproviderics.VersionRequest")
 	proto.RegisterType((*VersionReply)(nil), "cloudproviderics.VersionReply")
 }
@@ -366,31 +1110,64 @@ func init() {
 func init() { proto.RegisterFile("cloudproviderics.proto", fileDescriptor_630e23fe7cf01247) }
 
 var fileDescriptor_630e23fe7cf01247 = []byte{
-	// 370 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x93, 0xc1, 0x6a, 0xc2, 0x40,
-	0x10, 0x86, 0x8d, 0x46, 0x6d, 0xa6, 0x22, 0x61, 0x29, 0x12, 0x44, 0x24, 0x2c, 0x3d, 0x48, 0x29,
-	0x39, 0xd8, 0x37, 0xb0, 0x07, 0x29, 0xd8, 0x12, 0x6c, 0xe9, 0x3d, 0xcd, 0xee, 0x61, 0x41, 0xb3,
-	0xe9, 0x4e, 0x22, 0xf8, 0x06, 0x7d, 0x8e, 0x3e, 0x69, 0xd9, 0x4d, 0x4c, 0xa3, 0xb1, 0x1e, 0x7a,
-	0xdb, 0x99, 0xf9, 0x98, 0xff, 0x9f, 0x3f, 0x04, 0x46, 0xf1, 0x46, 0xe6, 0x2c, 0x55, 0x72, 0x27,
-	0x18, 0x57, 0x22, 0xc6, 0x20, 0x55, 0x32, 0x93, 0xc4, 0x3d, 0xed, 0xd3, 0x6f, 0x0b, 0xec, 0x17,
-	0xc9, 0x38, 0xf1, 0xa0, 0xbf, 0x8b, 0x79, 0x92, 0x71, 0xe5, 0x59, 0xbe, 0x35, 0x73, 0xd6, 0x87,
-	0x92, 0x4c, 0x01, 0x58, 0x94, 0x45, 0xe5, 0xb0, 0x6d, 0x86, 0xb5, 0x0e, 0x21, 0x60, 0x27, 0xd1,
-	0x96, 0x7b, 0x1d, 0x33, 0x31, 0x6f, 0x32, 0x86, 0x2b, 0x96, 0xa0, 0x7e, 0xa2, 0x67, 0xfb, 0x9d,
-	0x99, 0xb3, 0xae, 0x6a, 0x32, 0x01, 0x27, 0x62, 0x4c, 0x71, 0x44, 0x8e, 0x5e, 0xd7, 0x0c, 0x7f,
-	0x1b, 0x7a, 0x5b, 0x9e, 0x0b, 0xe6, 0xf5, 0x8a, 0x6d, 0xfa, 0x4d, 0x6f, 0x61, 0xb8, 0xe4, 0x99,
-	0xb6, 0xb9, 0xe6, 0x9f, 0x39, 0xc7, 0xac, 0xa2, 0xac, 0x1a, 0x15, 0xc2, 0xa0, 0xa2, 0xd2, 0xcd,
-	0x9e, 0xdc, 0x81, 0x9d, 0x48, 0xc6, 0x0d, 0x73, 0x3d, 0x1f, 0x05, 0x8d, 0x4c, 0x0c, 0x6a, 0x18,
-	0x72, 0x03, 0x5d, 0xae, 0x94, 0x3c, 0x9c, 0x57, 0x14, 0x74, 0x05, 0xee, 0x4a, 0xa0, 0x59, 0x89,
-	0x07, 0xe5, 0x7f, 0xe7, 0x44, 0xdf, 0x60, 0x58, 0xdb, 0xa6, 0x1d, 0xde, 0x43, 0x57, 0xab, 0xa3,
-	0x67, 0xf9, 0x9d, 0x0b, 0x16, 0x0b, 0xe8, 0x0f, 0x8f, 0x2e, 0x0c, 0xdf, 0xb9, 0x42, 0x21, 0x93,
-	0xd2, 0x21, 0x9d, 0xc1, 0xa0, 0xea, 0x68, 0x15, 0xed, 0xb8, 0xa8, 0x2b, 0xc7, 0x45, 0x39, 0xff,
-	0x6a, 0x83, 0xfb, 0xa8, 0x25, 0xc3, 0x52, 0xf2, 0x29, 0x46, 0xf2, 0x0c, 0xfd, 0x32, 0x46, 0xe2,
-	0x37, 0x0d, 0x1d, 0x7f, 0x87, 0xf1, 0xf4, 0x02, 0x91, 0x6e, 0xf6, 0xb4, 0x45, 0x5e, 0xc1, 0xa9,
-	0xae, 0x26, 0xb4, 0x89, 0x9f, 0x06, 0x3c, 0xf6, 0x2f, 0x32, 0xc5, 0xd2, 0x10, 0x60, 0xc9, 0xb3,
-	0xf2, 0xca, 0x73, 0x36, 0x8f, 0x23, 0x39, 0x67, 0xb3, 0x1e, 0x11, 0x6d, 0x2d, 0xe6, 0x30, 0x89,
-	0xe5, 0x36, 0x10, 0x09, 0xa6, 0xb9, 0x3a, 0xa6, 0x03, 0x11, 0xe3, 0xa2, 0x91, 0x53, 0x68, 0x7d,
-	0xf4, 0xcc, 0x4f, 0xf5, 0xf0, 0x13, 0x00, 0x00, 0xff, 0xff, 0x82, 0x31, 0xa0, 0x2b, 0x6e, 0x03,
-	0x00, 0x00,
+	// 903 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xef, 0x6e, 0xe3, 0x44,
+	0x10, 0xc7, 0xb1, 0x93, 0xd4, 0xd3, 0x36, 0xb5, 0x16, 0x28, 0x26, 0x94, 0x10, 0x59, 0x1c, 0xe4,
+	0x00, 0x45, 0x22, 0x7c, 0x44, 0x02, 0xe9, 0x7a, 0xd2, 0x81, 0x38, 0x4e, 0x91, 0x8b, 0xfa, 0xb5,
+	0x72, 0xed, 0xa5, 0xac, 0xea, 0x7a, 0x8d, 0xd7, 0xae, 0x94, 0xbe, 0x01, 0x2f, 0x00, 0x2f, 0xc0,
+	0x07, 0x24, 0x1e, 0x82, 0x07, 0xe0, 0xa5, 0xd0, 0xec, 0x6e, 0x36, 0x76, 0xe2, 0x18, 0xc4, 0xa9,
+	0xdf, 0x76, 0x66, 0xe7, 0xcf, 0x6f, 0x7e, 0x9e, 0xd9, 0x31, 0x9c, 0xc6, 0x29, 0xaf, 0x92, 0xbc,
+	0xe0, 0xf7, 0x2c, 0xa1, 0x05, 0x8b, 0xc5, 0x3c, 0x2f, 0x78, 0xc9, 0x89, 0xb7, 0xad, 0x0f, 0x7e,
+	0xeb, 0x81, 0xf3, 0x8a, 0x27, 0x94, 0xf8, 0x30, 0xbc, 0x8f, 0x69, 0x56, 0xd2, 0xc2, 0xb7, 0xa6,
+	0xd6, 0xcc, 0x0d, 0xd7, 0x22, 0x99, 0x00, 0x24, 0x51, 0x19, 0xe9, 0xcb, 0x9e, 0xbc, 0xac, 0x69,
+	0x08, 0x01, 0x27, 0x8b, 0xee, 0xa8, 0x6f, 0xcb, 0x1b, 0x79, 0x26, 0x63, 0x38, 0x48, 0x32, 0x81,
+	0x47, 0xe1, 0x3b, 0x53, 0x7b, 0xe6, 0x86, 0x46, 0x26, 0x67, 0xe0, 0x46, 0x49, 0x52, 0x50, 0x21,
+	0xa8, 0xf0, 0xfb, 0xf2, 0x72, 0xa3, 0xc0, 0x68, 0x55, 0xc5, 0x12, 0x7f, 0xa0, 0xa2, 0xe1, 0x99,
+	0x7c, 0x07, 0x5e, 0xc2, 0xc4, 0xed, 0x55, 0xcc, 0xb3, 0xb2, 0xe0, 0x69, 0x4a, 0x0b, 0xe1, 0x0f,
+	0xa7, 0xf6, 0xec, 0x70, 0x31, 0x9d, 0xef, 0x54, 0xfa, 0x9c, 0x89, 0xdb, 0x73, 0x63, 0x18, 0x9e,
+	0x24, 0x0d, 0x59, 0x90, 0x8f, 0xe0, 0xe4, 0xc7, 0x82, 0xd2, 0x2b, 0x19, 0x51, 0xa4, 0xbc, 0x14,
+	0xfe, 0xc1, 0xd4, 0x9a, 0xf5, 0xc3, 0x63, 0x54, 0xa3, 0xf7, 0x05, 0x2a, 0x83, 0x57, 0x30, 0x6a,
+	0x86, 0x22, 0x1e, 0xd8, 0xd7, 0x95, 0xd0, 0xf4, 0xe0, 0x91, 0xbc, 0x05, 0x7d, 0x0c, 0x23, 0x24,
+	0x2b, 0xfd, 0x50, 0x09, 0xa8, 0x55, 0x71, 0x6d, 0xa5, 0x95, 0x42, 0xf0, 0x21, 0x8c, 0x5e, 0xd0,
+	0x12, 0xb9, 0x0e, 0xe9, 0xcf, 0x15, 0x15, 0xa5, 0x29, 0xd5, 0xda, 0x94, 0x1a, 0x2c, 0xe1, 0xc8,
+	0x58, 0xe5, 0xe9, 0x8a, 0x7c, 0x02, 0x4e, 0xc6, 0x13, 0x2a, 0x6d, 0x0e, 0x17, 0xa7, 0xbb, 0xe5,
+	0x4a, 0x53, 0x69, 0x83, 0x79, 0x69, 0x51, 0xf0, 0xf5, 0x37, 0x52, 0x42, 0xf0, 0x12, 0xbc, 0x97,
+	0x4c, 0xc8, 0x90, 0x62, 0x9d, 0xf9, 0x7f, 0x7f, 0xec, 0xe0, 0x07, 0x18, 0xd5, 0xa2, 0x21, 0xc2,
+	0xcf, 0xa0, 0x8f, 0xd9, 0x91, 0x17, 0xbb, 0x03, 0xa2, 0x32, 0xda, 0x83, 0xf1, 0x17, 0x0b, 0x8e,
+	0xbf, 0xe1, 0xa2, 0xbc, 0x64, 0x3c, 0x8d, 0x4a, 0xc6, 0x33, 0xb4, 0xbb, 0x29, 0x78, 0x95, 0x6b,
+	0x7c, 0x4a, 0xa8, 0xe3, 0xee, 0x75, 0xe1, 0xb6, 0xdb, 0x9a, 0xf4, 0x27, 0x2e, 0x4a, 0xdf, 0x51,
+	0x5c, 0xe3, 0x19, 0x73, 0x28, 0xe4, 0xaa, 0x09, 0x95, 0x10, 0x7c, 0x0e, 0xef, 0x62, 0x85, 0x0d,
+	0x38, 0x86, 0xb8, 0x56, 0x58, 0x41, 0x0e, 0xef, 0xb4, 0xb9, 0x20, 0x3b, 0x5f, 0x03, 0xdc, 0x1b,
+	0x95, 0xa6, 0xe8, 0x83, 0x5d, 0x8a, 0x1a, 0xae, 0x61, 0xcd, 0x65, 0x0f, 0x61, 0xbf, 0x5a, 0xe0,
+	0x60, 0x77, 0x92, 0x11, 0xf4, 0x4c, 0x07, 0xf5, 0x58, 0x62, 0x86, 0xb1, 0x57, 0x1b, 0xc6, 0x1a,
+	0x6b, 0x76, 0x17, 0x6b, 0xce, 0x0e, 0x6b, 0x67, 0xe0, 0xa2, 0x24, 0x4a, 0x5e, 0x50, 0xbf, 0x2f,
+	0xaf, 0x37, 0x0a, 0xcc, 0x25, 0xd8, 0x03, 0x95, 0xa3, 0x6a, 0x85, 0xf2, 0x1c, 0x4c, 0x65, 0x97,
+	0x23, 0xb4, 0x35, 0x65, 0x5b, 0x08, 0x75, 0x87, 0x2b, 0x0b, 0xdd, 0xe1, 0x38, 0x36, 0xfb, 0x3b,
+	0x5c, 0x9a, 0x4a, 0x9b, 0x3d, 0x64, 0xfc, 0xd5, 0x03, 0xf7, 0xb9, 0x41, 0xf5, 0xb8, 0x8c, 0x10,
+	0x70, 0xca, 0x55, 0xbe, 0x26, 0x43, 0x9e, 0xf1, 0xb1, 0x8b, 0xa3, 0x3c, 0x8a, 0x59, 0xb9, 0xd2,
+	0x5c, 0x18, 0x99, 0xbc, 0x0f, 0x20, 0x5f, 0x1b, 0x91, 0x47, 0x31, 0xf5, 0x87, 0xf2, 0xd6, 0x45,
+	0xcd, 0x05, 0x2a, 0xb0, 0x20, 0x6c, 0x45, 0x7c, 0x82, 0x64, 0x0b, 0x4a, 0x01, 0xe1, 0xc5, 0x69,
+	0x25, 0x10, 0x81, 0xab, 0xe0, 0x69, 0x11, 0xd3, 0x3f, 0xf0, 0x8c, 0xfa, 0xa0, 0xd2, 0xe3, 0x99,
+	0x9c, 0xc2, 0xa0, 0xa0, 0x37, 0x8c, 0x67, 0xfe, 0xa1, 0xd4, 0x6a, 0x89, 0x3c, 0x05, 0x0f, 0x19,
+	0x89, 0x6e, 0xe8, 0x55, 0xce, 0x53, 0x16, 0x33, 0x2a, 0xfc, 0x23, 0x99, 0xe6, 0x44, 0xeb, 0x97,
+	0x5a, 0x1d, 0xfc, 0x6d, 0x81, 0x67, 0x18, 0x3c, 0xd7, 0xb9, 0x1e, 0x97, 0xc8, 0x3a, 0x69, 0xfd,
+	0x4e, 0xd2, 0x06, 0xdb, 0xa4, 0xe9, 0xd0, 0x12, 0xac, 0x5a, 0x04, 0x6e, 0x58, 0xd3, 0x04, 0xbf,
+	0x5b, 0xf0, 0x36, 0xce, 0xa3, 0xa9, 0xe8, 0xf5, 0xdf, 0x3d, 0x43, 0xbc, 0xdd, 0x4a, 0xbc, 0xd3,
+	0x20, 0xfe, 0x09, 0x8c, 0x1a, 0xc4, 0xaf, 0x74, 0xb7, 0x1c, 0xd7, 0x69, 0x5f, 0x05, 0x7f, 0x58,
+	0xf0, 0xe6, 0x36, 0x4c, 0x1c, 0x88, 0x2f, 0x1b, 0xe5, 0xa9, 0x27, 0xe3, 0xbd, 0x96, 0xb1, 0x58,
+	0xdb, 0xd4, 0x6b, 0x27, 0x5f, 0xc1, 0x81, 0xee, 0x15, 0x5c, 0x4a, 0xe8, 0x1a, 0x74, 0xb8, 0xea,
+	0x4f, 0x1d, 0x1a, 0x9f, 0xcd, 0x84, 0xd9, 0xf5, 0x09, 0xf3, 0x60, 0x74, 0x49, 0x0b, 0x81, 0x6f,
+	0x93, 0x62, 0x32, 0x98, 0xc1, 0x91, 0xd1, 0x20, 0x68, 0x64, 0x56, 0xc9, 0x86, 0x59, 0x25, 0x2e,
+	0xfe, 0x74, 0xc0, 0x3b, 0x47, 0x04, 0x4b, 0x8d, 0xe0, 0xdb, 0x58, 0x90, 0xef, 0x61, 0xa8, 0xd7,
+	0x1c, 0x69, 0x59, 0xe1, 0xcd, 0x3d, 0x39, 0x9e, 0x74, 0x58, 0xe4, 0xe9, 0x2a, 0x78, 0x83, 0x5c,
+	0x80, 0x6b, 0xb6, 0x12, 0x69, 0x29, 0x78, 0x7b, 0x01, 0x8e, 0xa7, 0x9d, 0x36, 0x2a, 0xe8, 0x12,
+	0xe0, 0x05, 0x2d, 0x75, 0x95, 0x6d, 0x30, 0x9b, 0x94, 0x8c, 0x27, 0x1d, 0x16, 0x2a, 0x62, 0x06,
+	0x64, 0x77, 0x4f, 0x90, 0x4f, 0xdb, 0xb1, 0xb4, 0x2e, 0xa0, 0xf1, 0xd3, 0xff, 0x66, 0xac, 0xf2,
+	0x29, 0x96, 0xe5, 0x9e, 0x68, 0x67, 0xb9, 0xf6, 0x4e, 0x8f, 0x27, 0x1d, 0x16, 0x2a, 0xdc, 0xb5,
+	0xda, 0xfd, 0x9b, 0x7e, 0x25, 0x1f, 0xb7, 0xa3, 0xd9, 0x19, 0xbc, 0xf1, 0x93, 0x7f, 0x37, 0x94,
+	0x39, 0x9e, 0x2d, 0xe0, 0x2c, 0xe6, 0x77, 0x73, 0x96, 0x89, 0xbc, 0x2a, 0x9a, 0x4e, 0x73, 0x16,
+	0x8b, 0x67, 0x3b, 0xad, 0xb4, 0xb4, 0xae, 0x07, 0xf2, 0xe7, 0xf6, 0x8b, 0x7f, 0x06, 0x00, 0xb6,
+	0xfc, 0xcf, 0x12, 0xf6, 0x0a, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -408,6 +1185,9 @@ type CloudProviderIcsClient interface {
 	GetNode(ctx context.Context, in *GetNodeRequest, opts ...grpc.CallOption) (*GetNodeReply, error)
 	ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesReply, error)
 	GetVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionReply, error)
+	ListHostViolations(ctx context.Context, in *ListHostViolationsRequest, opts ...grpc.CallOption) (*ListHostViolationsReply, error)
+	GetDisk(ctx context.Context, in *GetDiskRequest, opts ...grpc.CallOption) (*GetDiskReply, error)
+	ListDatastores(ctx context.Context, in *ListDatastoresRequest, opts ...grpc.CallOption) (*ListDatastoresReply, error)
 }
 
 type cloudProviderIcsClient struct {
@@ -445,11 +1225,41 @@ func (c *cloudProviderIcsClient) GetVersion(ctx context.Context, in *VersionRequ
 	return out, nil
 }
 
+func (c *cloudProviderIcsClient) ListHostViolations(ctx context.Context, in *ListHostViolationsRequest, opts ...grpc.CallOption) (*ListHostViolationsReply, error) {
+	out := new(ListHostViolationsReply)
+	err := c.cc.Invoke(ctx, "/cloudproviderics.CloudProviderIcs/ListHostViolations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderIcsClient) GetDisk(ctx context.Context, in *GetDiskRequest, opts ...grpc.CallOption) (*GetDiskReply, error) {
+	out := new(GetDiskReply)
+	err := c.cc.Invoke(ctx, "/cloudproviderics.CloudProviderIcs/GetDisk", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderIcsClient) ListDatastores(ctx context.Context, in *ListDatastoresRequest, opts ...grpc.CallOption) (*ListDatastoresReply, error) {
+	out := new(ListDatastoresReply)
+	err := c.cc.Invoke(ctx, "/cloudproviderics.CloudProviderIcs/ListDatastores", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CloudProviderIcsServer is the server API for CloudProviderIcs service.
 type CloudProviderIcsServer interface {
 	GetNode(context.Context, *GetNodeRequest) (*GetNodeReply, error)
 	ListNodes(context.Context, *ListNodesRequest) (*ListNodesReply, error)
 	GetVersion(context.Context, *VersionRequest) (*VersionReply, error)
+	ListHostViolations(context.Context, *ListHostViolationsRequest) (*ListHostViolationsReply, error)
+	GetDisk(context.Context, *GetDiskRequest) (*GetDiskReply, error)
+	ListDatastores(context.Context, *ListDatastoresRequest) (*ListDatastoresReply, error)
 }
 
 // UnimplementedCloudProviderIcsServer can be embedded to have forward compatible implementations.
@@ -465,6 +1275,15 @@ func (*UnimplementedCloudProviderIcsServer) ListNodes(ctx context.Context, req *
 func (*UnimplementedCloudProviderIcsServer) GetVersion(ctx context.Context, req *VersionRequest) (*VersionReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
 }
+func (*UnimplementedCloudProviderIcsServer) ListHostViolations(ctx context.Context, req *ListHostViolationsRequest) (*ListHostViolationsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHostViolations not implemented")
+}
+func (*UnimplementedCloudProviderIcsServer) GetDisk(ctx context.Context, req *GetDiskRequest) (*GetDiskReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDisk not implemented")
+}
+func (*UnimplementedCloudProviderIcsServer) ListDatastores(ctx context.Context, req *ListDatastoresRequest) (*ListDatastoresReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDatastores not implemented")
+}
 
 func RegisterCloudProviderIcsServer(s *grpc.Server, srv CloudProviderIcsServer) {
 	s.RegisterService(&_CloudProviderIcs_serviceDesc, srv)
@@ -524,6 +1343,60 @@ func _CloudProviderIcs_GetVersion_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CloudProviderIcs_ListHostViolations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHostViolationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderIcsServer).ListHostViolations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloudproviderics.CloudProviderIcs/ListHostViolations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderIcsServer).ListHostViolations(ctx, req.(*ListHostViolationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProviderIcs_GetDisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderIcsServer).GetDisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloudproviderics.CloudProviderIcs/GetDisk",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderIcsServer).GetDisk(ctx, req.(*GetDiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProviderIcs_ListDatastores_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDatastoresRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderIcsServer).ListDatastores(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cloudproviderics.CloudProviderIcs/ListDatastores",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderIcsServer).ListDatastores(ctx, req.(*ListDatastoresRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _CloudProviderIcs_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "cloudproviderics.CloudProviderIcs",
 	HandlerType: (*CloudProviderIcsServer)(nil),
@@ -540,6 +1413,18 @@ var _CloudProviderIcs_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetVersion",
 			Handler:    _CloudProviderIcs_GetVersion_Handler,
 		},
+		{
+			MethodName: "ListHostViolations",
+			Handler:    _CloudProviderIcs_ListHostViolations_Handler,
+		},
+		{
+			MethodName: "GetDisk",
+			Handler:    _CloudProviderIcs_GetDisk_Handler,
+		},
+		{
+			MethodName: "ListDatastores",
+			Handler:    _CloudProviderIcs_ListDatastores_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "cloudproviderics.proto",
@@ -0,0 +1,248 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog"
+
+	cm "github.com/inspur-ics/cloud-provider-ics/pkg/common/connectionmanager"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+const (
+	// ConfigReloadDelay is how long the config watcher waits for the config
+	// file to settle before reloading it. Updating a mounted ConfigMap
+	// produces a burst of events for the "..data" symlink swap.
+	ConfigReloadDelay = 2 * time.Second
+)
+
+// cloudConfigFile is the path of the cloud config, set from the
+// --cloud-config flag. The config is only reloaded if it is set.
+var cloudConfigFile string
+
+// SetCloudConfigFile sets the path of the cloud config file to watch for
+// changes.
+func SetCloudConfigFile(path string) {
+	cloudConfigFile = path
+}
+
+// watchConfig reloads the cloud config whenever the config file, or the
+// ConfigMap it is mounted from, changes until stop is closed.
+func (vs *ICS) watchConfig(stop <-chan struct{}) {
+	if cloudConfigFile == "" {
+		klog.V(2).Info("No cloud config file set, config reload is disabled")
+		return
+	}
+
+	data, err := ioutil.ReadFile(cloudConfigFile)
+	if err != nil {
+		klog.Warningf("Config will not be reloaded, failed to read %s. err=%v", cloudConfigFile, err)
+		return
+	}
+	vs.configData = data
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		klog.Warningf("Config will not be reloaded, failed to create watcher. err=%v", err)
+		return
+	}
+	// Watch the directory so the atomic symlink swap of ConfigMap volumes
+	// is seen.
+	if err := watcher.Add(filepath.Dir(cloudConfigFile)); err != nil {
+		klog.Warningf("Config will not be reloaded, failed to watch %s. err=%v", cloudConfigFile, err)
+		watcher.Close()
+		return
+	}
+
+	klog.V(2).Infof("Watching cloud config %s", cloudConfigFile)
+	go func() {
+		defer watcher.Close()
+
+		reload := time.NewTimer(ConfigReloadDelay)
+		reload.Stop()
+
+		for {
+			select {
+			case <-stop:
+				reload.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				klog.V(4).Infof("Cloud config event: %s", event)
+				// Drain a fire not received yet so Reset restarts the delay
+				if !reload.Stop() {
+					select {
+					case <-reload.C:
+					default:
+					}
+				}
+				reload.Reset(ConfigReloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				klog.Warningf("Cloud config watcher error: %q", err)
+			case <-reload.C:
+				vs.reloadConfig()
+			}
+		}
+	}()
+}
+
+// reloadConfig reads and validates the config file and applies it if it
+// changed. An invalid config is logged and the current one kept.
+func (vs *ICS) reloadConfig() {
+	data, err := ioutil.ReadFile(cloudConfigFile)
+	if err != nil {
+		klog.Errorf("Failed to read cloud config %s. err=%v", cloudConfigFile, err)
+		return
+	}
+	if bytes.Equal(data, vs.configData) {
+		return
+	}
+
+	cfg, err := ReadCPIConfig(bytes.NewReader(data))
+	if err != nil {
+		klog.Errorf("Keeping the current config, %s is invalid: %v", cloudConfigFile, err)
+		return
+	}
+	vs.configData = data
+
+	klog.V(1).Infof("Reloading cloud config %s", cloudConfigFile)
+	vs.applyConfig(cfg)
+}
+
+// applyConfig replaces the running config with cfg and re-discovers the nodes
+// of the iCenters that changed, or of all iCenters if the node address
+// settings changed.
+func (vs *ICS) applyConfig(cfg *CPIConfig) {
+	oldCfg := vs.nodeManager.config()
+	if oldCfg.Global.APIDisable != cfg.Global.APIDisable || oldCfg.Global.APIBinding != cfg.Global.APIBinding {
+		klog.Warning("Changes to api-disable and api-binding require a restart")
+	}
+	if oldCfg.Global.SecretName != cfg.Global.SecretName ||
+		oldCfg.Global.SecretNamespace != cfg.Global.SecretNamespace ||
+		oldCfg.Global.SecretsDirectory != cfg.Global.SecretsDirectory {
+		klog.Warning("Changes to the global secret-name, secret-namespace and secrets-directory require a restart")
+	}
+
+	vs.nodeManager.setConfig(cfg)
+
+	if !reflect.DeepEqual(oldCfg.VMTag, cfg.VMTag) {
+		klog.V(2).Info("VM tag mappings changed")
+		vs.nodeManager.vmTagSyncer.enqueueAll()
+	}
+	if !reflect.DeepEqual(oldCfg.AntiAffinity, cfg.AntiAffinity) {
+		klog.V(2).Info("Anti-affinity groups changed")
+		vs.nodeManager.hostAntiAffinityController.enqueue()
+		vs.nodeManager.antiAffinityRuleController.enqueue()
+	}
+
+	var changed []string
+	if vs.connectionManager != nil {
+		changed = vs.connectionManager.UpdateConfig(&cfg.Config)
+	}
+	allNodes := oldCfg.Nodes != cfg.Nodes || !reflect.DeepEqual(oldCfg.InstanceType, cfg.InstanceType)
+	if len(changed) == 0 && !allNodes {
+		klog.V(2).Info("No iCenter or node settings changed")
+		return
+	}
+
+	tenantRefs := make(map[string]bool)
+	for _, tenantRef := range changed {
+		tenantRefs[tenantRef] = true
+	}
+	vs.nodeManager.rediscoverNodes(tenantRefs, allNodes)
+}
+
+// config returns the current CPI configuration.
+func (nm *NodeManager) config() *CPIConfig {
+	nm.cpiCfgLock.RLock()
+	defer nm.cpiCfgLock.RUnlock()
+	return nm.cpiCfg
+}
+
+// setConfig replaces the CPI configuration.
+func (nm *NodeManager) setConfig(cpiCfg *CPIConfig) {
+	nm.cpiCfgLock.Lock()
+	defer nm.cpiCfgLock.Unlock()
+	nm.cpiCfg = cpiCfg
+}
+
+// rediscoverNodes discovers again the registered nodes running on the iCenters
+// in tenantRefs, or all registered nodes if all is set. Nodes that were never
+// discovered are always retried since they may run on an added iCenter. The
+// cached NodeInfo of every other node is left untouched.
+func (nm *NodeManager) rediscoverNodes(tenantRefs map[string]bool, all bool) {
+	nm.nodeRegInfoLock.RLock()
+	uuids := make([]string, 0, len(nm.nodeRegUUIDMap))
+	for uuid := range nm.nodeRegUUIDMap {
+		uuids = append(uuids, uuid)
+	}
+	nm.nodeRegInfoLock.RUnlock()
+
+	for _, uuid := range uuids {
+		nm.nodeInfoLock.RLock()
+		nodeInfo := nm.nodeUUIDMap[uuid]
+		nm.nodeInfoLock.RUnlock()
+
+		if nodeInfo != nil && !all && !tenantRefs[nodeInfo.tenantRef] {
+			continue
+		}
+
+		klog.V(2).Infof("Re-discovering node with UUID=%s after config reload", uuid)
+		nm.rediscoverNode(uuid)
+	}
+}
+
+// rediscoverNode discovers again the registered node with the UUID, replacing
+// its cached NodeInfo. The cached NodeInfo is kept if discovery fails, unless
+// the VM was not found or its iCenter was removed, so that an iCenter that is
+// unreachable during the reload does not drop its nodes.
+func (nm *NodeManager) rediscoverNode(uuid string) {
+	nm.nodeInfoLock.RLock()
+	nodeInfo := nm.nodeUUIDMap[uuid]
+	nm.nodeInfoLock.RUnlock()
+
+	err := nm.DiscoverNode(uuid, cm.FindVMByUUID)
+	if nodeInfo == nil {
+		if err != nil {
+			klog.Warningf("Failed to re-discover node with UUID=%s. err=%v", uuid, err)
+		}
+		return
+	}
+	if err != nil {
+		removed := nm.connectionManager == nil || nm.connectionManager.Instance(nodeInfo.tenantRef) == nil
+		if err != icslib.ErrNoVMFound && !removed {
+			klog.Warningf("Keeping node with UUID=%s, failed to re-discover it. err=%v", uuid, err)
+			return
+		}
+		klog.Warningf("Dropping node with UUID=%s, failed to re-discover it. err=%v", uuid, err)
+	}
+	// Drops what DiscoverNode did not replace, like the old name of the
+	// node
+	nm.removeNodeInfo(nodeInfo)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cm "github.com/inspur-ics/cloud-provider-ics/pkg/common/connectionmanager"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// closedPort returns a local port nothing listens on.
+func closedPort(t *testing.T) string {
+	server := httptest.NewServer(nil)
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	server.Close()
+	if err != nil {
+		t.Fatalf("Failed to get a port: %v", err)
+	}
+	return port
+}
+
+func TestApplyConfigRediscoversNodes(t *testing.T) {
+	port := closedPort(t)
+	otherPort := closedPort(t)
+	config := func(sections ...string) string {
+		return "[Global]\nuser = \"admin\"\npassword = \"secret\"\nport = \"" + port + "\"\n" + strings.Join(sections, "\n")
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		// nodes still cached after the reload
+		kept []string
+	}{
+		{
+			name:   "unchanged",
+			config: config(`[VirtualCenter "127.0.0.1"]`, `[VirtualCenter "127.0.0.2"]`),
+			kept:   []string{"node1", "node2"},
+		},
+		{
+			name:   "added iCenter",
+			config: config(`[VirtualCenter "127.0.0.1"]`, `[VirtualCenter "127.0.0.2"]`, `[VirtualCenter "127.0.0.3"]`),
+			kept:   []string{"node1", "node2"},
+		},
+		{
+			name:   "changed unreachable iCenter keeps its nodes",
+			config: config(`[VirtualCenter "127.0.0.1"]`+"\nport = \""+otherPort+"\"", `[VirtualCenter "127.0.0.2"]`),
+			kept:   []string{"node1", "node2"},
+		},
+		{
+			name:   "removed iCenter drops its nodes",
+			config: config(`[VirtualCenter "127.0.0.2"]`),
+			kept:   []string{"node2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := ReadCPIConfig(strings.NewReader(config(`[VirtualCenter "127.0.0.1"]`, `[VirtualCenter "127.0.0.2"]`)))
+			if err != nil {
+				t.Fatalf("Reading the config failed: %v", err)
+			}
+			connMgr := cm.NewConnectionManager(&cfg.Config, nil, nil)
+			vs := &ICS{
+				cfg:               cfg,
+				connectionManager: connMgr,
+				nodeManager:       newNodeManager(cfg, connMgr),
+			}
+			nm := vs.nodeManager
+			for i, vcServer := range []string{"127.0.0.1", "127.0.0.2"} {
+				nodeName := fmt.Sprintf("node%d", i+1)
+				uuid := fmt.Sprintf("uuid-%d", i+1)
+				nm.addNodeInfo(&NodeInfo{
+					tenantRef:  vcServer,
+					vcServer:   vcServer,
+					dataCenter: &icslib.Datacenter{Datacenter: &tp.Datacenter{Name: "dc1"}},
+					UUID:       uuid,
+					NodeName:   nodeName,
+				})
+				nm.addNode(uuid, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+			}
+
+			newCfg, err := ReadCPIConfig(strings.NewReader(test.config))
+			if err != nil {
+				t.Fatalf("Reading the new config failed: %v", err)
+			}
+			vs.applyConfig(newCfg)
+
+			if nm.config() != newCfg {
+				t.Error("The node manager did not get the new config")
+			}
+			if len(nm.nodeNameMap) != len(test.kept) {
+				t.Errorf("%d nodes are cached, expected %v", len(nm.nodeNameMap), test.kept)
+			}
+			for _, nodeName := range test.kept {
+				if nm.nodeNameMap[nodeName] == nil {
+					t.Errorf("Node %s was dropped", nodeName)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// Errors
+var (
+	// ErrNotVMAddress is returned when an annotation pins an address that is
+	// not an IP address of the node's VM.
+	ErrNotVMAddress = errors.New("Not an IP address of the VM")
+)
+
+// AddressResolver returns the addresses of a node. sel is the address
+// selection policy of the node, computed once per discovery from the config
+// and the node annotations. node is nil if the node is not registered yet.
+//
+// A resolver returns no addresses of a type to leave it to the next resolver
+// of a ChainResolver.
+type AddressResolver interface {
+	ResolveAddresses(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error)
+}
+
+// AddressResolverFunc is a function used as an AddressResolver.
+type AddressResolverFunc func(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error)
+
+// ResolveAddresses calls f.
+func (f AddressResolverFunc) ResolveAddresses(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error) {
+	return f(vm, sel, node)
+}
+
+// ChainResolver takes the addresses of each type from the first of its
+// resolvers returning addresses of that type. Resolvers failing with an
+// error are skipped, their errors are only returned if the chain resolves
+// no address at all.
+type ChainResolver struct {
+	Resolvers []AddressResolver
+}
+
+// NewChainResolver returns a ChainResolver trying the resolvers in order.
+func NewChainResolver(resolvers ...AddressResolver) *ChainResolver {
+	return &ChainResolver{Resolvers: resolvers}
+}
+
+// ResolveAddresses returns the addresses resolved by the chain.
+func (chain *ChainResolver) ResolveAddresses(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error) {
+	var addrs []v1.NodeAddress
+	var errs []error
+	resolved := make(map[v1.NodeAddressType]bool)
+
+	for _, resolver := range chain.Resolvers {
+		resolverAddrs, err := resolver.ResolveAddresses(vm, sel, node)
+		if err != nil {
+			klog.Warningf("Address resolver %T failed, trying the next one. err=%v", resolver, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		types := make(map[v1.NodeAddressType]bool)
+		for _, addr := range resolverAddrs {
+			if resolved[addr.Type] {
+				continue
+			}
+			types[addr.Type] = true
+			addrs = append(addrs, addr)
+		}
+		for addressType := range types {
+			resolved[addressType] = true
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return addrs, nil
+}
+
+// DefaultAddressResolver returns the chain of built-in resolvers: annotation,
+// subnet, network name and then first IP.
+func DefaultAddressResolver() AddressResolver {
+	return NewChainResolver(
+		&AnnotationResolver{},
+		&SubnetResolver{},
+		&NetworkNameResolver{},
+		&FirstIPResolver{},
+	)
+}
+
+// Node annotations pinning the addresses of a node, resolved by the
+// AnnotationResolver.
+const (
+	// AnnotationInternalIP pins the InternalIPs of the node to a
+	// comma-separated list of IP addresses of its VM.
+	AnnotationInternalIP = "ics.inspur.com/internal-ip"
+	// AnnotationExternalIP pins the ExternalIPs of the node to a
+	// comma-separated list of IP addresses of its VM.
+	AnnotationExternalIP = "ics.inspur.com/external-ip"
+)
+
+// AnnotationResolver returns the addresses pinned by the internal-ip and
+// external-ip annotations of the node. Pinned addresses that are not an IP
+// address of the VM are added to the invalid annotations of the selection.
+type AnnotationResolver struct{}
+
+// ResolveAddresses returns the pinned addresses that belong to the VM.
+func (r *AnnotationResolver) ResolveAddresses(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	vmIPs := make(map[string]bool)
+	for _, nic := range vm.NICs() {
+		if !nic.IsVirtual() {
+			continue
+		}
+		for _, ipNet := range nic.IPs {
+			vmIPs[ipNet.IP.String()] = true
+		}
+	}
+
+	var addrs []v1.NodeAddress
+	for _, pin := range []struct {
+		annotation  string
+		addressType v1.NodeAddressType
+	}{
+		{AnnotationInternalIP, v1.NodeInternalIP},
+		{AnnotationExternalIP, v1.NodeExternalIP},
+	} {
+		value, ok := node.Annotations[pin.annotation]
+		if !ok {
+			continue
+		}
+
+		var pinned []v1.NodeAddress
+		for _, ip := range strings.Split(value, ",") {
+			parsedIP := net.ParseIP(strings.TrimSpace(ip))
+			if parsedIP == nil || !vmIPs[parsedIP.String()] {
+				sel.invalidAnnotations[pin.annotation] = ErrNotVMAddress
+				pinned = nil
+				break
+			}
+			pinned = append(pinned, v1.NodeAddress{Type: pin.addressType, Address: parsedIP.String()})
+		}
+		for _, addr := range pinned {
+			klog.V(2).Infof("Adding %s by annotation: %s", addr.Type, addr.Address)
+		}
+		addrs = append(addrs, pinned...)
+	}
+
+	return addrs, nil
+}
+
+// SubnetResolver returns, for the roles with subnets, the IP addresses in
+// one of them.
+type SubnetResolver struct{}
+
+// ResolveAddresses returns the addresses matching the subnets.
+func (r *SubnetResolver) ResolveAddresses(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error) {
+	nics := sel.orderNICs(vm.NICs())
+
+	var addrs []v1.NodeAddress
+	if len(sel.externalNetworkSubnets) > 0 {
+		addrs = append(addrs, sel.roleAddresses(v1.NodeExternalIP, sel.externalNetworkSubnets, "", nics)...)
+	}
+	if len(sel.internalNetworkSubnets) > 0 {
+		addrs = append(addrs, sel.roleAddresses(v1.NodeInternalIP, sel.internalNetworkSubnets, "", nics)...)
+	}
+	return addrs, nil
+}
+
+// NetworkNameResolver returns, for the roles with a VM network name, the
+// first IP address on that network.
+type NetworkNameResolver struct{}
+
+// ResolveAddresses returns the addresses on the VM networks.
+func (r *NetworkNameResolver) ResolveAddresses(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error) {
+	nics := sel.orderNICs(vm.NICs())
+
+	var addrs []v1.NodeAddress
+	if sel.externalVMNetworkName != "" {
+		addrs = append(addrs, sel.roleAddresses(v1.NodeExternalIP, nil, sel.externalVMNetworkName, nics)...)
+	}
+	if sel.internalVMNetworkName != "" {
+		addrs = append(addrs, sel.roleAddresses(v1.NodeInternalIP, nil, sel.internalVMNetworkName, nics)...)
+	}
+	return addrs, nil
+}
+
+// FirstIPResolver returns the first IP address of the VM as both its
+// InternalIP and ExternalIP, unless subnets or VM network names are set for
+// one of the roles.
+type FirstIPResolver struct{}
+
+// ResolveAddresses returns the first IP address.
+func (r *FirstIPResolver) ResolveAddresses(vm *icslib.VirtualMachine, sel *AddressSelection, node *v1.Node) ([]v1.NodeAddress, error) {
+	if sel.configured() {
+		return nil, nil
+	}
+	nics := sel.orderNICs(vm.NICs())
+
+	var addrs []v1.NodeAddress
+	addrs = append(addrs, sel.roleAddresses(v1.NodeExternalIP, nil, "", nics)...)
+	addrs = append(addrs, sel.roleAddresses(v1.NodeInternalIP, nil, "", nics)...)
+	return addrs, nil
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vcfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// fakeVM returns a VM with the given NICs.
+func fakeVM(nics ...tp.Nic) *icslib.VirtualMachine {
+	return &icslib.VirtualMachine{VirtualMachine: &tp.VirtualMachine{Nics: nics}}
+}
+
+// vnic returns the number-th vNIC of a VM on network with the addresses ips.
+func vnic(number int, network string, ips string) tp.Nic {
+	return tp.Nic{
+		Name:        fmt.Sprintf("nic%d", number),
+		Mac:         fmt.Sprintf("fa:16:3e:00:00:%02x", number),
+		DeviceID:    fmt.Sprintf("device-%d", number),
+		NetworkName: network,
+		IP:          ips,
+	}
+}
+
+// testSelection returns the address selection of a node with cfg.
+func testSelection(t *testing.T, cfg *CPIConfig, vcConfig *vcfg.VirtualCenterConfig, node *v1.Node) *AddressSelection {
+	nm := &NodeManager{cpiCfg: cfg}
+	sel, err := nm.addressSelection(vcConfig, node)
+	if err != nil {
+		t.Fatalf("addressSelection() failed: %v", err)
+	}
+	return sel
+}
+
+func internalIP(ip string) v1.NodeAddress {
+	return v1.NodeAddress{Type: v1.NodeInternalIP, Address: ip}
+}
+
+func externalIP(ip string) v1.NodeAddress {
+	return v1.NodeAddress{Type: v1.NodeExternalIP, Address: ip}
+}
+
+func TestResolvers(t *testing.T) {
+	subnets := &CPIConfig{}
+	subnets.Nodes.InternalNetworkSubnetCIDR = "10.0.0.0/8"
+	subnets.Nodes.ExternalNetworkSubnetCIDR = "192.168.0.0/16"
+	networks := &CPIConfig{}
+	networks.Nodes.InternalVMNetworkName = "k8s"
+
+	vm := fakeVM(
+		vnic(0, "public", "192.168.1.5"),
+		vnic(1, "k8s", "10.1.1.5,10.1.1.6"),
+	)
+	annotated := func(annotations map[string]string) *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: annotations}}
+	}
+
+	tests := []struct {
+		name        string
+		resolver    AddressResolver
+		cfg         *CPIConfig
+		node        *v1.Node
+		want        []v1.NodeAddress
+		wantInvalid []string
+	}{
+		{
+			name:     "annotation without node",
+			resolver: &AnnotationResolver{},
+		},
+		{
+			name:     "annotation pins an IP of the VM",
+			resolver: &AnnotationResolver{},
+			node:     annotated(map[string]string{AnnotationInternalIP: "10.1.1.6"}),
+			want:     []v1.NodeAddress{internalIP("10.1.1.6")},
+		},
+		{
+			name:        "annotation pins a foreign IP",
+			resolver:    &AnnotationResolver{},
+			node:        annotated(map[string]string{AnnotationInternalIP: "10.1.1.6", AnnotationExternalIP: "172.16.0.1"}),
+			want:        []v1.NodeAddress{internalIP("10.1.1.6")},
+			wantInvalid: []string{AnnotationExternalIP},
+		},
+		{
+			name:     "subnets",
+			resolver: &SubnetResolver{},
+			cfg:      subnets,
+			want:     []v1.NodeAddress{externalIP("192.168.1.5"), internalIP("10.1.1.5"), internalIP("10.1.1.6")},
+		},
+		{
+			name:     "subnets from annotations",
+			resolver: &SubnetResolver{},
+			node:     annotated(map[string]string{AnnotationInternalCIDR: "10.1.1.6/32"}),
+			want:     []v1.NodeAddress{internalIP("10.1.1.6")},
+		},
+		{
+			name:     "no subnets",
+			resolver: &SubnetResolver{},
+		},
+		{
+			name:     "network name",
+			resolver: &NetworkNameResolver{},
+			cfg:      networks,
+			want:     []v1.NodeAddress{internalIP("10.1.1.5")},
+		},
+		{
+			name:     "unknown network name",
+			resolver: &NetworkNameResolver{},
+			node:     annotated(map[string]string{AnnotationExternalNetwork: "storage"}),
+		},
+		{
+			name:     "first IP",
+			resolver: &FirstIPResolver{},
+			want:     []v1.NodeAddress{externalIP("192.168.1.5"), internalIP("192.168.1.5")},
+		},
+		{
+			name:     "first IP with subnets",
+			resolver: &FirstIPResolver{},
+			cfg:      subnets,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sel := testSelection(t, test.cfg, nil, test.node)
+			got, err := test.resolver.ResolveAddresses(vm, sel, test.node)
+			if err != nil {
+				t.Fatalf("ResolveAddresses() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ResolveAddresses() = %v, want %v", got, test.want)
+			}
+			for _, annotation := range test.wantInvalid {
+				if sel.invalidAnnotations[annotation] == nil {
+					t.Errorf("annotation %s not reported as invalid", annotation)
+				}
+			}
+			if len(sel.invalidAnnotations) != len(test.wantInvalid) {
+				t.Errorf("invalid annotations = %v, want %v", sel.invalidAnnotations, test.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestChainResolver(t *testing.T) {
+	errFailed := errors.New("failed")
+	failing := AddressResolverFunc(func(*icslib.VirtualMachine, *AddressSelection, *v1.Node) ([]v1.NodeAddress, error) {
+		return nil, errFailed
+	})
+	resolving := func(addrs ...v1.NodeAddress) AddressResolver {
+		return AddressResolverFunc(func(*icslib.VirtualMachine, *AddressSelection, *v1.Node) ([]v1.NodeAddress, error) {
+			return addrs, nil
+		})
+	}
+
+	tests := []struct {
+		name      string
+		resolvers []AddressResolver
+		want      []v1.NodeAddress
+		wantErr   bool
+	}{
+		{
+			name:      "first resolver of each type wins",
+			resolvers: []AddressResolver{resolving(internalIP("10.0.0.1")), resolving(internalIP("10.0.0.2"), externalIP("192.168.0.1"))},
+			want:      []v1.NodeAddress{internalIP("10.0.0.1"), externalIP("192.168.0.1")},
+		},
+		{
+			name:      "failing resolver is skipped",
+			resolvers: []AddressResolver{failing, resolving(internalIP("10.0.0.1"))},
+			want:      []v1.NodeAddress{internalIP("10.0.0.1")},
+		},
+		{
+			name:      "every resolver fails",
+			resolvers: []AddressResolver{failing, failing},
+			wantErr:   true,
+		},
+		{
+			name:      "nothing resolved",
+			resolvers: []AddressResolver{resolving()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewChainResolver(test.resolvers...).ResolveAddresses(fakeVM(), &AddressSelection{}, nil)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ResolveAddresses() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ResolveAddresses() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultAddressResolver(t *testing.T) {
+	vm := fakeVM(
+		vnic(0, "docker", "172.17.0.1"),
+		vnic(1, "k8s", "10.1.1.5,192.168.1.5"),
+		vnic(2, "public", "192.168.2.7"),
+	)
+	nodes := func(configure func(cfg *CPIConfig)) *CPIConfig {
+		cfg := &CPIConfig{}
+		configure(cfg)
+		return cfg
+	}
+
+	tests := []struct {
+		name string
+		cfg  *CPIConfig
+		want []v1.NodeAddress
+	}{
+		{
+			name: "first IP",
+			cfg:  &CPIConfig{},
+			want: []v1.NodeAddress{externalIP("172.17.0.1"), internalIP("172.17.0.1")},
+		},
+		{
+			name: "first IP outside of the excluded subnets",
+			cfg: nodes(func(cfg *CPIConfig) {
+				cfg.Nodes.ExcludeSubnetCIDR = "172.17.0.0/16"
+			}),
+			want: []v1.NodeAddress{externalIP("10.1.1.5"), internalIP("10.1.1.5")},
+		},
+		{
+			name: "only an internal CIDR",
+			cfg: nodes(func(cfg *CPIConfig) {
+				cfg.Nodes.InternalNetworkSubnetCIDR = "10.0.0.0/8"
+			}),
+			want: []v1.NodeAddress{internalIP("10.1.1.5")},
+		},
+		{
+			name: "only an external CIDR list",
+			cfg: nodes(func(cfg *CPIConfig) {
+				cfg.Nodes.ExternalNetworkSubnetCIDR = "192.168.2.0/24, 10.0.0.0/8"
+			}),
+			want: []v1.NodeAddress{externalIP("10.1.1.5"), externalIP("192.168.2.7")},
+		},
+		{
+			name: "internal CIDR with excluded subnets",
+			cfg: nodes(func(cfg *CPIConfig) {
+				cfg.Nodes.InternalNetworkSubnetCIDR = "0.0.0.0/0"
+				cfg.Nodes.ExcludeSubnetCIDR = "172.17.0.0/16,192.168.1.0/24"
+			}),
+			want: []v1.NodeAddress{internalIP("10.1.1.5"), internalIP("192.168.2.7")},
+		},
+		{
+			name: "internal network name with excluded subnets",
+			cfg: nodes(func(cfg *CPIConfig) {
+				cfg.Nodes.InternalVMNetworkName = "k8s"
+				cfg.Nodes.ExcludeSubnetCIDR = "10.0.0.0/8"
+			}),
+			want: []v1.NodeAddress{internalIP("192.168.1.5")},
+		},
+		{
+			name: "every address excluded",
+			cfg: nodes(func(cfg *CPIConfig) {
+				cfg.Nodes.InternalNetworkSubnetCIDR = "10.0.0.0/8"
+				cfg.Nodes.ExcludeSubnetCIDR = "10.1.0.0/16"
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sel := testSelection(t, test.cfg, nil, nil)
+			got, err := DefaultAddressResolver().ResolveAddresses(vm, sel, nil)
+			if err != nil {
+				t.Fatalf("ResolveAddresses() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ResolveAddresses() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -45,6 +45,9 @@ const (
 type NodeManagerInterface interface {
 	GetNode(UUID string, node *pb.Node) error
 	ExportNodes(vcenter string, datacenter string, nodeList *[]*pb.Node) error
+	ExportHostViolations(group string, violations *[]*pb.HostViolation) error
+	GetDisk(id string, disk *pb.Disk) error
+	ExportDatastores(request *pb.ListDatastoresRequest, datastores *[]*pb.Datastore, clusters *[]*pb.DatastoreCluster) error
 }
 
 // GRPCServer describes an object that can start a gRPC server.
@@ -99,6 +102,47 @@ func (s *server) ListNodes(ctx context.Context, request *pb.ListNodesRequest) (*
 	return reply, nil
 }
 
+// ListHostViolations implements CloudProviderIcs interface
+func (s *server) ListHostViolations(ctx context.Context, request *pb.ListHostViolationsRequest) (*pb.ListHostViolationsReply, error) {
+	reply := &pb.ListHostViolationsReply{
+		Violations: make([]*pb.HostViolation, 0),
+	}
+	err := s.nodeMgr.ExportHostViolations(request.Group, &reply.Violations)
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	return reply, nil
+}
+
+// GetDisk implements CloudProviderIcs interface
+func (s *server) GetDisk(ctx context.Context, request *pb.GetDiskRequest) (*pb.GetDiskReply, error) {
+	reply := &pb.GetDiskReply{
+		Disk: &pb.Disk{},
+	}
+	err := s.nodeMgr.GetDisk(request.Id, reply.Disk)
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	return reply, nil
+}
+
+// ListDatastores implements CloudProviderIcs interface
+func (s *server) ListDatastores(ctx context.Context, request *pb.ListDatastoresRequest) (*pb.ListDatastoresReply, error) {
+	reply := &pb.ListDatastoresReply{
+		Datastores: make([]*pb.Datastore, 0),
+		Clusters:   make([]*pb.DatastoreCluster, 0),
+	}
+	//Do not allow specifying the Datacenter without specifying the iCenter
+	if request.Vcenter == "" && request.Datacenter != "" {
+		request.Datacenter = ""
+	}
+	err := s.nodeMgr.ExportDatastores(request, &reply.Datastores, &reply.Clusters)
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	return reply, nil
+}
+
 // GetVersion implements obtaining the version of the API server
 func (s *server) GetVersion(ctx context.Context, request *pb.VersionRequest) (*pb.VersionReply, error) {
 	return &pb.VersionReply{
@@ -20,6 +20,7 @@ import (
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
 
 	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
@@ -39,20 +40,97 @@ type CPIConfig struct {
 
 	Nodes struct {
 		// IP address on VirtualMachine's network interfaces included in the fields' CIDRs
-		// that will be used in respective status.addresses fields.
-		InternalNetworkSubnetCIDR string `gcfg:"internal-network-subnet-cidr"`
-		ExternalNetworkSubnetCIDR string `gcfg:"external-network-subnet-cidr"`
+		// that will be used in respective status.addresses fields. Each field is a
+		// comma-separated list of CIDRs and may be set without the other.
+		InternalNetworkSubnetCIDR string `gcfg:"internal-network-subnet-cidr" json:"internalNetworkSubnetCIDR,omitempty"`
+		ExternalNetworkSubnetCIDR string `gcfg:"external-network-subnet-cidr" json:"externalNetworkSubnetCIDR,omitempty"`
+		// Comma-separated list of CIDRs whose IP addresses are never used, like the
+		// docker0 bridge or the service range reported by the guest tools.
+		ExcludeSubnetCIDR string `gcfg:"exclude-subnet-cidr" json:"excludeSubnetCIDR,omitempty"`
 		// IP address on VirtualMachine's VM Network names that will be used to when searching
 		// for status.addresses fields. Note that if InternalNetworkSubnetCIDR and
 		// ExternalNetworkSubnetCIDR are not set, then the vNIC associated to this network must
 		// only have a single IP address assigned to it.
-		InternalVMNetworkName string `gcfg:"internal-vm-network-name"`
-		ExternalVMNetworkName string `gcfg:"external-vm-network-name"`
-	}
+		InternalVMNetworkName string `gcfg:"internal-vm-network-name" json:"internalVMNetworkName,omitempty"`
+		ExternalVMNetworkName string `gcfg:"external-vm-network-name" json:"externalVMNetworkName,omitempty"`
+		// Policy selecting the primary interface, whose addresses are preferred
+		// over the ones of the other interfaces. Supported values are:
+		// device-index - the first interface in device order (Default)
+		// mac-prefix - the first interface whose MAC address starts with PrimaryInterfaceMACPrefix
+		// network-regex - the first interface whose network matches PrimaryInterfaceNetworkRegex
+		// default-gateway - the first interface with a default gateway
+		PrimaryInterfacePolicy       string `gcfg:"primary-interface-policy" json:"primaryInterfacePolicy,omitempty"`
+		PrimaryInterfaceMACPrefix    string `gcfg:"primary-interface-mac-prefix" json:"primaryInterfaceMACPrefix,omitempty"`
+		PrimaryInterfaceNetworkRegex string `gcfg:"primary-interface-network-regex" json:"primaryInterfaceNetworkRegex,omitempty"`
+		// Comma-separated list of the ics.inspur.com/* labels published on
+		// nodes, out of host, cluster, datacenter, icenter, guest-os-family
+		// and hardware-version. Default: none
+		PublishLabels string `gcfg:"publish-labels" json:"publishLabels,omitempty"`
+		// Period of the sync of the VM tags into node labels and taints, like
+		// 30s or 5m. Default: 5m
+		VMTagSyncPeriod string `gcfg:"vm-tag-sync-period" json:"vmTagSyncPeriod,omitempty"`
+		// Period of the sync of the VM and host power states into the shutdown
+		// and host-maintenance taints, like 30s or 5m. Default: 1m
+		PowerStateSyncPeriod string `gcfg:"power-state-sync-period" json:"powerStateSyncPeriod,omitempty"`
+		// Period of the evaluation of the host placement of the anti-affinity
+		// groups, like 30s or 5m. Default: 1m
+		AntiAffinitySyncPeriod string `gcfg:"anti-affinity-sync-period" json:"antiAffinitySyncPeriod,omitempty"`
+		// Period of the sync of the disk controllers of the VMs into the
+		// disk-controllers and free-disk-slots node annotations, like 30s
+		// or 5m. Default: 1m
+		DiskSyncPeriod string `gcfg:"disk-sync-period" json:"diskSyncPeriod,omitempty"`
+	} `json:"nodes"`
+
+	// Named instance types, as [InstanceType "name"] sections. Nodes report
+	// the closest one as their instance type.
+	InstanceType map[string]*InstanceTypeConfig `json:"instanceTypes,omitempty"`
+
+	// VM tags mapped to node labels and taints, as [VMTag "name"] sections
+	// named after the tag, or after the category of the tag.
+	VMTag map[string]*VMTagConfig `json:"vmTags,omitempty"`
+
+	// Groups of nodes that should run on separate hosts, as
+	// [AntiAffinity "name"] sections.
+	AntiAffinity map[string]*AntiAffinityConfig `json:"antiAffinity,omitempty"`
+}
+
+// InstanceTypeConfig is a named VM flavor.
+type InstanceTypeConfig struct {
+	// Number of vCPUs.
+	CPU int `gcfg:"cpu" json:"cpu"`
+	// Memory size, like 512mb or 4gb. Plain numbers are MB.
+	Memory string `gcfg:"memory" json:"memory"`
+}
+
+// VMTagConfig maps a VM tag to node labels and taints.
+type VMTagConfig struct {
+	// Labels of the nodes whose VM has the tag, as key or key=value. Without
+	// value the label takes the value of the tag, or true for plain tags.
+	Label []string `gcfg:"label" json:"labels,omitempty"`
+	// Taints of the nodes whose VM has the tag, as key[=value]:effect.
+	// Without value the taint takes the value of the tag.
+	Taint []string `gcfg:"taint" json:"taints,omitempty"`
+}
+
+// AntiAffinityConfig is a group of nodes that should run on separate hosts.
+type AntiAffinityConfig struct {
+	// Label selector of the nodes of the group, like
+	// node-role.kubernetes.io/master or role in (etcd).
+	Selector string `gcfg:"selector" json:"selector"`
+	// Keep a DRS anti-affinity rule for the VMs of the group in each
+	// iCenter cluster running two of them or more. Default: false
+	ManageRule bool `gcfg:"manage-rule" json:"manageRule,omitempty"`
+	// Name of the DRS rules of the group. Default: kubernetes-<group>
+	RuleName string `gcfg:"rule-name" json:"ruleName,omitempty"`
+	// Log the changes to the DRS rules of the group instead of making them.
+	// Default: false
+	DryRun bool `gcfg:"dry-run" json:"dryRun,omitempty"`
 }
 
 // VSphere is an implementation of cloud provider Interface for ics.
 type ICS struct {
+	// config loaded at startup, reloads replace the one of the node
+	// manager
 	cfg               *CPIConfig
 	connectionManager *cm.ConnectionManager
 	nodeManager       *NodeManager
@@ -60,6 +138,8 @@ type ICS struct {
 	instances         cloudprovider.Instances
 	zones             cloudprovider.Zones
 	server            GRPCServer
+	// contents of the config file last loaded, to ignore no-op reloads
+	configData []byte
 }
 
 // NodeInfo is information about a Kubernetes node.
@@ -72,6 +152,9 @@ type NodeInfo struct {
 	NodeName      string
 	NodeType      string
 	NodeAddresses []v1.NodeAddress
+	// Disk controllers of the VM, updated by the disk controller syncer
+	// under nodeInfoLock
+	diskControllers []icslib.DiskController
 }
 
 // DatacenterInfo is information about a iCenter datascenter.
@@ -98,9 +181,38 @@ type NodeManager struct {
 	nodeRegUUIDMap map[string]*v1.Node
 	// ConnectionManager
 	connectionManager *cm.ConnectionManager
+	// Records Events on nodes, nil until the cloud provider is initialized
+	eventRecorder record.EventRecorder
+	// Resolves the addresses of discovered nodes
+	addressResolver AddressResolver
+	// Publishes the VM metadata as node labels, nil until the cloud provider
+	// is initialized
+	nodeLabeler *nodeLabeler
+	// Syncs the VM tags into node labels and taints, nil until the cloud
+	// provider is initialized
+	vmTagSyncer *vmTagSyncer
+	// Taints the nodes from the power state of their VM and host, nil until
+	// the cloud provider is initialized
+	powerStateController *powerStateController
+	// Reports the nodes of anti-affinity groups sharing a host, nil until
+	// the cloud provider is initialized
+	hostAntiAffinityController *hostAntiAffinityController
+	// Keeps the DRS anti-affinity rules of the anti-affinity groups, nil
+	// until the cloud provider is initialized
+	antiAffinityRuleController *antiAffinityRuleController
+	// Publishes the disk controllers and free disk slots of the VMs, nil
+	// until the cloud provider is initialized
+	diskControllerSyncer *diskControllerSyncer
+
+	// Reference to CPI-specific configuration, replaced on config reload
+	cpiCfg     *CPIConfig
+	cpiCfgLock sync.RWMutex
 
-	// Reference to CPI-specific configuration
-	cpiCfg *CPIConfig
+	// Value of the Events last recorded for a condition that lasts, like a
+	// conflict, by node name and key, so that the Event is only recorded
+	// again when the value changes
+	reportedEvents     map[string]map[string]string
+	reportedEventsLock sync.Mutex
 
 	// Mutexes
 	nodeInfoLock    sync.RWMutex
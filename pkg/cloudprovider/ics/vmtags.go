@@ -0,0 +1,413 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// Errors
+var (
+	// ErrInvalidVMTagLabel is returned when a VM tag is mapped to an invalid
+	// label, or to one of the labels published by publish-labels.
+	ErrInvalidVMTagLabel = errors.New("Invalid VM tag label")
+
+	// ErrInvalidVMTagTaint is returned when a VM tag is mapped to an invalid
+	// taint.
+	ErrInvalidVMTagTaint = errors.New("Invalid VM tag taint")
+
+	// ErrInvalidVMTagSyncPeriod is returned when the VM tag sync period is
+	// not a positive duration.
+	ErrInvalidVMTagSyncPeriod = errors.New("Invalid VM tag sync period")
+)
+
+const (
+	// defaultVMTagSyncPeriod is the default period of the VM tag sync.
+	defaultVMTagSyncPeriod = 5 * time.Minute
+
+	// plainTagLabelValue is the value of the labels of plain tags mapped
+	// without value.
+	plainTagLabelValue = "true"
+)
+
+// parseTagLabel parses a label mapping written key or key=value.
+func parseTagLabel(spec string) (key string, value string, hasValue bool, err error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), "=", 2)
+	key = parts[0]
+	if len(validation.IsQualifiedName(key)) > 0 {
+		return "", "", false, ErrInvalidVMTagLabel
+	}
+	for _, nodeLabel := range nodeLabels {
+		if key == nodeLabel {
+			return "", "", false, ErrInvalidVMTagLabel
+		}
+	}
+	if len(parts) == 2 {
+		value, hasValue = parts[1], true
+		if len(validation.IsValidLabelValue(value)) > 0 {
+			return "", "", false, ErrInvalidVMTagLabel
+		}
+	}
+	return key, value, hasValue, nil
+}
+
+// parseTagTaint parses a taint mapping written key[=value]:effect.
+func parseTagTaint(spec string) (taint v1.Taint, hasValue bool, err error) {
+	spec = strings.TrimSpace(spec)
+	sep := strings.LastIndex(spec, ":")
+	if sep < 0 {
+		return taint, false, ErrInvalidVMTagTaint
+	}
+
+	taint.Effect = v1.TaintEffect(spec[sep+1:])
+	switch taint.Effect {
+	case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+	default:
+		return taint, false, ErrInvalidVMTagTaint
+	}
+
+	parts := strings.SplitN(spec[:sep], "=", 2)
+	taint.Key = parts[0]
+	if len(validation.IsQualifiedName(taint.Key)) > 0 {
+		return taint, false, ErrInvalidVMTagTaint
+	}
+	if len(parts) == 2 {
+		taint.Value, hasValue = parts[1], true
+		if len(validation.IsValidLabelValue(taint.Value)) > 0 {
+			return taint, false, ErrInvalidVMTagTaint
+		}
+	}
+	return taint, hasValue, nil
+}
+
+// validateVMTags checks the VM tag mappings and their sync period.
+func (cfg *CPIConfig) validateVMTags() []error {
+	var errs []error
+
+	if cfg.Nodes.VMTagSyncPeriod == "" {
+		cfg.Nodes.VMTagSyncPeriod = defaultVMTagSyncPeriod.String()
+	}
+	if period, err := time.ParseDuration(cfg.Nodes.VMTagSyncPeriod); err != nil || period <= 0 {
+		errs = append(errs, icscfg.NewFieldError(icscfg.SectionPath("Nodes", "vm-tag-sync-period"),
+			cfg.Nodes.VMTagSyncPeriod, ErrInvalidVMTagSyncPeriod))
+	}
+
+	names := make([]string, 0, len(cfg.VMTag))
+	for name := range cfg.VMTag {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mapping := cfg.VMTag[name]
+		if mapping == nil {
+			mapping = &VMTagConfig{}
+			cfg.VMTag[name] = mapping
+		}
+		for _, spec := range mapping.Label {
+			if _, _, _, err := parseTagLabel(spec); err != nil {
+				errs = append(errs, icscfg.NewFieldError(icscfg.SubsectionPath("VMTag", name, "label"), spec, err))
+			}
+		}
+		for _, spec := range mapping.Taint {
+			if _, _, err := parseTagTaint(spec); err != nil {
+				errs = append(errs, icscfg.NewFieldError(icscfg.SubsectionPath("VMTag", name, "taint"), spec, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// vmTagSyncPeriod returns the period of the VM tag sync.
+func (nm *NodeManager) vmTagSyncPeriod() time.Duration {
+	if cpiCfg := nm.config(); cpiCfg != nil {
+		if period, err := time.ParseDuration(cpiCfg.Nodes.VMTagSyncPeriod); err == nil && period > 0 {
+			return period
+		}
+	}
+	return defaultVMTagSyncPeriod
+}
+
+// taintID identifies a taint of a node, which cannot have two taints with the
+// same key and effect.
+func taintID(taint v1.Taint) string {
+	return taint.Key + ":" + string(taint.Effect)
+}
+
+// vmTagMappings returns the labels and taints the tags are mapped to. When
+// tags are mapped to the same label or taint, the first tag wins.
+func (nm *NodeManager) vmTagMappings(tags []icslib.Tag) (map[string]string, map[string]v1.Taint) {
+	labels := make(map[string]string)
+	taints := make(map[string]v1.Taint)
+
+	cpiCfg := nm.config()
+	if cpiCfg == nil {
+		return labels, taints
+	}
+
+	for _, tag := range tags {
+		mapping := cpiCfg.VMTag[tag.Name]
+		if mapping == nil {
+			continue
+		}
+
+		for _, spec := range mapping.Label {
+			key, value, hasValue, err := parseTagLabel(spec)
+			if err != nil {
+				continue
+			}
+			if !hasValue {
+				value = toLabelValue(tag.Value)
+				if tag.Value == "" {
+					value = plainTagLabelValue
+				}
+			}
+			if current, ok := labels[key]; ok {
+				if current != value {
+					klog.Warningf("Ignoring label %s=%s of tag %s, already set to %s by another tag", key, value, tag.Name, current)
+				}
+				continue
+			}
+			labels[key] = value
+		}
+
+		for _, spec := range mapping.Taint {
+			taint, hasValue, err := parseTagTaint(spec)
+			if err != nil {
+				continue
+			}
+			if !hasValue {
+				taint.Value = toLabelValue(tag.Value)
+			}
+			if current, ok := taints[taintID(taint)]; ok {
+				if current.Value != taint.Value {
+					klog.Warningf("Ignoring taint %s=%s of tag %s, already set to %s by another tag", taintID(taint), taint.Value, tag.Name, current.Value)
+				}
+				continue
+			}
+			taints[taintID(taint)] = taint
+		}
+	}
+
+	return labels, taints
+}
+
+// vmTagConflictPrefix prefixes the keys of the conflicts reported by the VM
+// tag syncer.
+const vmTagConflictPrefix = "vmtags/"
+
+// vmTagSyncer keeps the labels and taints the VM tags are mapped to in sync
+// with the tags of the VM in iCenter. The registered nodes are synced
+// periodically as tags change without notice, and when they are discovered
+// or updated.
+//
+// Labels and taints already set on a node, by hand or by another controller,
+// are never overwritten: the mapped value is skipped and an Event reported
+// when the conflict appears or changes.
+// The ones set by the syncer are recorded in the managed-labels and
+// managed-taints annotations, so that they are removed along with the tag.
+type vmTagSyncer struct {
+	*nodeQueue
+	nodeManager *NodeManager
+	client      clientset.Interface
+}
+
+// newVMTagSyncer returns a VM tag syncer patching nodes with client.
+func newVMTagSyncer(nm *NodeManager, client clientset.Interface) *vmTagSyncer {
+	s := &vmTagSyncer{
+		nodeManager: nm,
+		client:      client,
+	}
+	s.nodeQueue = newNodeQueue("vmtags", s.sync)
+	return s
+}
+
+// Run syncs the queued nodes, and all registered nodes every sync period,
+// until stop is closed.
+func (s *vmTagSyncer) Run(stop <-chan struct{}) {
+	s.nodeQueue.RunWithResync(stop, s.nodeManager.vmTagSyncPeriod, s.enqueueAll)
+}
+
+// enqueue queues the node with the UUID. It is a no-op if s is nil.
+func (s *vmTagSyncer) enqueue(uuid string) {
+	if s == nil {
+		return
+	}
+	s.nodeQueue.enqueue(uuid)
+}
+
+// enqueueAll queues the registered nodes. It is a no-op if s is nil.
+func (s *vmTagSyncer) enqueueAll() {
+	if s == nil {
+		return
+	}
+	for _, uuid := range s.nodeManager.registeredUUIDs() {
+		s.nodeQueue.enqueue(uuid)
+	}
+}
+
+// sync reads the tags of the VM of the node with the UUID from iCenter and
+// patches the labels and taints of the node that differ.
+func (s *vmTagSyncer) sync(uuid string) error {
+	nm := s.nodeManager
+	nodeInfo, node := nm.discoveredNode(uuid)
+	if node == nil || nodeInfo.vm == nil || nodeInfo.vm.VirtualMachine == nil {
+		return nil
+	}
+
+	vm, err := nm.currentVM(context.Background(), nodeInfo)
+	if err != nil {
+		return err
+	}
+	desiredLabels, desiredTaints := nm.vmTagMappings(vm.VMTags())
+
+	managedLabels := splitManaged(node.Annotations[AnnotationManagedLabels])
+	managedTaints := splitManaged(node.Annotations[AnnotationManagedTaints])
+
+	// Conflicts are reported once, when they appear or change
+	conflicts := make(map[string]bool)
+	labelsPatch := make(map[string]interface{})
+	ownedLabels := make(map[string]bool)
+	for key, value := range desiredLabels {
+		current, ok := node.Labels[key]
+		if ok && !managedLabels[key] {
+			if current != value {
+				conflict := vmTagConflictPrefix + "label/" + key
+				conflicts[conflict] = true
+				nm.recordEventOnChange(node, conflict, value+"/"+current, v1.EventTypeWarning, EventReasonLabelConflict,
+					"Not setting label %s=%s of a VM tag, the label is already set to %s", key, value, current)
+			}
+			continue
+		}
+		ownedLabels[key] = true
+		if !ok || current != value {
+			labelsPatch[key] = value
+		}
+	}
+	for key := range managedLabels {
+		if _, ok := node.Labels[key]; ok && !ownedLabels[key] {
+			labelsPatch[key] = nil
+		}
+	}
+
+	var taints []v1.Taint
+	ownedTaints := make(map[string]bool)
+	for _, taint := range node.Spec.Taints {
+		id := taintID(taint)
+		desired, ok := desiredTaints[id]
+		switch {
+		case !ok && managedTaints[id]:
+			continue
+		case ok && managedTaints[id]:
+			ownedTaints[id] = true
+			taint.Value = desired.Value
+		case ok && taint.Value != desired.Value:
+			conflict := vmTagConflictPrefix + "taint/" + id
+			conflicts[conflict] = true
+			nm.recordEventOnChange(node, conflict, desired.Value+"/"+taint.Value, v1.EventTypeWarning, EventReasonTaintConflict,
+				"Not setting taint %s=%s of a VM tag, the taint is already set to %s", id, desired.Value, taint.Value)
+		}
+		delete(desiredTaints, id)
+		taints = append(taints, taint)
+	}
+	nm.resolveEvents(node, vmTagConflictPrefix, conflicts)
+
+	ids := make([]string, 0, len(desiredTaints))
+	for id := range desiredTaints {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		ownedTaints[id] = true
+		taints = append(taints, desiredTaints[id])
+	}
+
+	annotationsPatch := make(map[string]interface{})
+	for annotation, owned := range map[string]map[string]bool{
+		AnnotationManagedLabels: ownedLabels,
+		AnnotationManagedTaints: ownedTaints,
+	} {
+		value := joinManaged(owned)
+		if current, ok := node.Annotations[annotation]; value == "" && ok {
+			annotationsPatch[annotation] = nil
+		} else if value != "" && current != value {
+			annotationsPatch[annotation] = value
+		}
+	}
+
+	metadata := map[string]interface{}{"resourceVersion": node.ResourceVersion}
+	patch := map[string]interface{}{"metadata": metadata}
+	if len(labelsPatch) > 0 {
+		metadata["labels"] = labelsPatch
+	}
+	if len(annotationsPatch) > 0 {
+		metadata["annotations"] = annotationsPatch
+	}
+	if !reflect.DeepEqual(taints, node.Spec.Taints) {
+		patch["spec"] = map[string]interface{}{"taints": taints}
+	}
+	if len(metadata) == 1 && patch["spec"] == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Patching VM tag labels and taints of node %s: %s", node.Name, data)
+	_, err = s.client.CoreV1().Nodes().Patch(node.Name, types.MergePatchType, data)
+	return err
+}
+
+// splitManaged parses the comma-separated list of a managed-labels or
+// managed-taints annotation.
+func splitManaged(value string) map[string]bool {
+	managed := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			managed[item] = true
+		}
+	}
+	return managed
+}
+
+// joinManaged returns the sorted comma-separated list of a managed-labels or
+// managed-taints annotation.
+func joinManaged(managed map[string]bool) string {
+	items := make([]string, 0, len(managed))
+	for item := range managed {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
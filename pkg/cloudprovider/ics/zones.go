@@ -38,6 +38,15 @@ func newZones(nodeManager *NodeManager, zone string, region string) cloudprovide
 	}
 }
 
+// labels returns the tag categories of the zone and region, read from the
+// current config so reloads apply.
+func (z *zones) labels() (string, string) {
+	if cpiCfg := z.nodeManager.config(); cpiCfg != nil {
+		return cpiCfg.Labels.Zone, cpiCfg.Labels.Region
+	}
+	return z.zone, z.region
+}
+
 // GetZone implements Zones.GetZone for In-Tree providers
 func (z *zones) GetZone(ctx context.Context) (cloudprovider.Zone, error) {
 	klog.V(4).Info("zones.GetZone() called")
@@ -58,7 +67,7 @@ func (z *zones) GetZone(ctx context.Context) (cloudprovider.Zone, error) {
 
 //ics 
 //vm's host summary
-	vmHost, err := node.vm.HostSystem(ctx)
+	vmHost, err := z.nodeManager.vmHost(ctx, node)
 	if err != nil {
 		klog.Errorf("Failed to get host system for VM: %q. err: %+v", node.vm.Name, err)
 		return zone, err
@@ -67,8 +76,9 @@ func (z *zones) GetZone(ctx context.Context) (cloudprovider.Zone, error) {
 	// host ip address, like "192.168.10.1"
 	klog.V(4).Infof("Host owning VM is %s", vmHost.Name)
 
+	zoneLabel, regionLabel := z.labels()
 	zoneResult, err := z.nodeManager.connectionManager.LookupZoneByMoref(
-		ctx, node.tenantRef, vmHost, z.zone, z.region)
+		ctx, node.tenantRef, vmHost, zoneLabel, regionLabel)
 	if err != nil {
 		klog.Errorf("Failed to get host system properties. err: %+v", err)
 		return zone, err
@@ -97,7 +107,7 @@ func (z *zones) GetZoneByNodeName(ctx context.Context, nodeName k8stypes.NodeNam
 
 //ics
 //vm's host summary
-	vmHost, err := node.vm.HostSystem(ctx)
+	vmHost, err := z.nodeManager.vmHost(ctx, node)
 	if err != nil {
 		klog.Errorf("Failed to get host system for VM: %q. err: %+v", node.vm.Name, err)
 		return zone, err
@@ -106,8 +116,9 @@ func (z *zones) GetZoneByNodeName(ctx context.Context, nodeName k8stypes.NodeNam
 	// host ip address, like "192.168.10.1"
 	klog.V(4).Infof("Host owning VM is %s", vmHost.Name)
 
+	zoneLabel, regionLabel := z.labels()
 	zoneResult, err := z.nodeManager.connectionManager.LookupZoneByMoref(
-		ctx, node.tenantRef, vmHost, z.zone, z.region)
+		ctx, node.tenantRef, vmHost, zoneLabel, regionLabel)
 	if err != nil {
 		klog.Errorf("Failed to get host system properties. err: %+v", err)
 		return zone, err
@@ -136,7 +147,7 @@ func (z *zones) GetZoneByProviderID(ctx context.Context, providerID string) (clo
 
 //ics
 //vm's host summary
-	vmHost, err := node.vm.HostSystem(ctx)
+	vmHost, err := z.nodeManager.vmHost(ctx, node)
 	if err != nil {
 		klog.Errorf("Failed to get host system for VM: %q. err: %+v", node.vm.UUID, err)
 		return zone, err
@@ -145,8 +156,9 @@ func (z *zones) GetZoneByProviderID(ctx context.Context, providerID string) (clo
 	// host ip address, like "192.168.10.1"
 	klog.V(4).Infof("Host owning VM is %s", vmHost.Name)
 
+	zoneLabel, regionLabel := z.labels()
 	zoneResult, err := z.nodeManager.connectionManager.LookupZoneByMoref(
-		ctx, node.tenantRef, vmHost, z.zone, z.region)
+		ctx, node.tenantRef, vmHost, zoneLabel, regionLabel)
 	if err != nil {
 		klog.Errorf("Failed to get host system properties. err: %+v", err)
 		return zone, err
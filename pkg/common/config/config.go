@@ -19,276 +19,47 @@ package config
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 
 	"k8s.io/klog"
 
 	"gopkg.in/gcfg.v1"
-)
-
-func getEnvKeyValue(match string, partial bool) (string, string, error) {
-	for _, e := range os.Environ() {
-		pair := strings.Split(e, "=")
-		if len(pair) != 2 {
-			continue
-		}
-
-		key := pair[0]
-		value := pair[1]
-
-		if partial && strings.Contains(key, match) {
-			return key, value, nil
-		}
-
-		if strings.Compare(key, match) == 0 {
-			return key, value, nil
-		}
-	}
 
-	matchType := "match"
-	if partial {
-		matchType = "partial match"
-	}
-
-	return "", "", fmt.Errorf("Failed to find %s with %s", matchType, match)
-}
+	"github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
 
 // FromEnv initializes the provided configuratoin object with values
 // obtained from environment variables. If an environment variable is set
 // for a property that's already initialized, the environment variable's value
-// takes precedence.
+// takes precedence. See GlobalEnvVars and VirtualCenterEnvVars for the
+// supported variables. The resulting config is validated.
 func (cfg *Config) FromEnv() error {
 
 	//Init
 	if cfg.VirtualCenter == nil {
 		cfg.VirtualCenter = make(map[string]*VirtualCenterConfig)
 	}
+	cfg.fixupVirtualCenters()
 
-	//Globals
-	if v := os.Getenv("ICS_VCENTER"); v != "" {
-		cfg.Global.VCenterIP = v
-	}
-	if v := os.Getenv("ICS_VCENTER_PORT"); v != "" {
-		cfg.Global.VCenterPort = v
-	}
-	if v := os.Getenv("ICS_USER"); v != "" {
-		cfg.Global.User = v
-	}
-	if v := os.Getenv("ICS_PASSWORD"); v != "" {
-		cfg.Global.Password = v
-	}
-	if v := os.Getenv("ICS_DATACENTER"); v != "" {
-		cfg.Global.Datacenters = v
-	}
-	if v := os.Getenv("ICS_SECRET_NAME"); v != "" {
-		cfg.Global.SecretName = v
-	}
-	if v := os.Getenv("ICS_SECRET_NAMESPACE"); v != "" {
-		cfg.Global.SecretNamespace = v
-	}
-
-	if v := os.Getenv("ICS_ROUNDTRIP_COUNT"); v != "" {
-		tmp, err := strconv.ParseUint(v, 10, 32)
-		if err != nil {
-			klog.Errorf("Failed to parse ICS_ROUNDTRIP_COUNT: %s", err)
-		} else {
-			cfg.Global.RoundTripperCount = uint(tmp)
-		}
-	}
-/*
-	if v := os.Getenv("ICS_INSECURE"); v != "" {
-		InsecureFlag, err := strconv.ParseBool(v)
-		if err != nil {
-			klog.Errorf("Failed to parse ICS_INSECURE: %s", err)
-		} else {
-			cfg.Global.InsecureFlag = InsecureFlag
-		}
-	}
-*/
-	if v := os.Getenv("ICS_API_DISABLE"); v != "" {
-		APIDisable, err := strconv.ParseBool(v)
-		if err != nil {
-			klog.Errorf("Failed to parse ICS_API_DISABLE: %s", err)
-		} else {
-			cfg.Global.APIDisable = APIDisable
-		}
-	}
-
-	if v := os.Getenv("ICS_API_BINDING"); v != "" {
-		cfg.Global.APIBinding = v
-	}
+	errs := cfg.applyEnv()
 
-	if v := os.Getenv("ICS_SECRETS_DIRECTORY"); v != "" {
-		cfg.Global.SecretsDirectory = v
-	}
 	if cfg.Global.SecretsDirectory == "" {
 		cfg.Global.SecretsDirectory = DefaultSecretDirectory
 	}
 	if _, err := os.Stat(cfg.Global.SecretsDirectory); os.IsNotExist(err) {
 		cfg.Global.SecretsDirectory = "" //Dir does not exist, set to empty string
 	}
-/*
-	if v := os.Getenv("ICS_CAFILE"); v != "" {
-		cfg.Global.CAFile = v
-	}
-*/
-/*
-	if v := os.Getenv("ICS_THUMBPRINT"); v != "" {
-		cfg.Global.Thumbprint = v
-	}
-*/	
-	if v := os.Getenv("ICS_LABEL_REGION"); v != "" {
-		cfg.Labels.Region = v
-	}
-	if v := os.Getenv("ICS_LABEL_ZONE"); v != "" {
-		cfg.Labels.Zone = v
-	}
 
-	if v := os.Getenv("ICS_IP_FAMILY"); v != "" {
-		cfg.Global.IPFamily = v
-	}
 	if cfg.Global.IPFamily == "" {
 		cfg.Global.IPFamily = DefaultIPFamily
 	}
 
-	//Build VirtualCenter from ENVs
-	for _, e := range os.Environ() {
-		pair := strings.Split(e, "=")
-
-		if len(pair) != 2 {
-			continue
-		}
-
-		key := pair[0]
-		value := pair[1]
-
-		if strings.HasPrefix(key, "ICS_VCENTER_") && len(value) > 0 {
-			id := strings.TrimPrefix(key, "ICS_VCENTER_")
-			vcenter := value
-
-			_, username, errUsername := getEnvKeyValue("VCENTER_"+id+"_USERNAME", false)
-			if errUsername != nil {
-				username = cfg.Global.User
-			}
-			_, password, errPassword := getEnvKeyValue("VCENTER_"+id+"_PASSWORD", false)
-			if errPassword != nil {
-				password = cfg.Global.Password
-			}
-			_, server, errServer := getEnvKeyValue("VCENTER_"+id+"_SERVER", false)
-			if errServer != nil {
-				server = ""
-			}
-			_, port, errPort := getEnvKeyValue("VCENTER_"+id+"_PORT", false)
-			if errPort != nil {
-				port = cfg.Global.VCenterPort
-			}
-/*			
-			insecureFlag := false
-			_, insecureTmp, errInsecure := getEnvKeyValue("VCENTER_"+id+"_INSECURE", false)
-			if errInsecure != nil {
-				insecureFlagTmp, errTmp := strconv.ParseBool(insecureTmp)
-				if errTmp == nil {
-					insecureFlag = insecureFlagTmp
-				}
-			}
-*/
-			_, datacenters, errDatacenters := getEnvKeyValue("VCENTER_"+id+"_DATACENTERS", false)
-			if errDatacenters != nil {
-				datacenters = cfg.Global.Datacenters
-			}
-			roundtrip := DefaultRoundTripperCount
-			_, roundtripTmp, errRoundtrip := getEnvKeyValue("VCENTER_"+id+"_ROUNDTRIP", false)
-			if errRoundtrip != nil {
-				roundtripFlagTmp, errTmp := strconv.ParseUint(roundtripTmp, 10, 32)
-				if errTmp == nil {
-					roundtrip = uint(roundtripFlagTmp)
-				}
-			}
-/*
-			_, caFile, errCaFile := getEnvKeyValue("VCENTER_"+id+"_CAFILE", false)
-			if errCaFile != nil {
-				caFile = cfg.Global.CAFile
-			}
-*/
-/*
-			_, thumbprint, errThumbprint := getEnvKeyValue("VCENTER_"+id+"_THUMBPRINT", false)
-			if errThumbprint != nil {
-				thumbprint = cfg.Global.Thumbprint
-			}
-*/
-			_, secretName, secretNameErr := getEnvKeyValue("VCENTER_"+id+"_SECRET_NAME", false)
-			_, secretNamespace, secretNamespaceErr := getEnvKeyValue("VCENTER_"+id+"_SECRET_NAMESPACE", false)
-
-			if secretNameErr != nil || secretNamespaceErr != nil {
-				secretName = ""
-				secretNamespace = ""
-			}
-			secretRef := DefaultCredentialManager
-			if secretName != "" && secretNamespace != "" {
-				secretRef = vcenter
-			}
-
-			_, ipFamily, errIPFamily := getEnvKeyValue("VCENTER_"+id+"_IP_FAMILY", false)
-			if errIPFamily != nil {
-				ipFamily = cfg.Global.IPFamily
-			}
-
-			// If server is explicitly set, that means the vcenter value above is the TenantRef
-			vcenterIP := vcenter
-			tenantRef := vcenter
-			if server != "" {
-				vcenterIP = server
-				tenantRef = vcenter
-			}
-
-			cfg.VirtualCenter[tenantRef] = &VirtualCenterConfig{
-				User:              username,
-				Password:          password,
-				TenantRef:         tenantRef,
-				VCenterIP:         vcenterIP,
-				VCenterPort:       port,
-//				InsecureFlag:      insecureFlag,
-				Datacenters:       datacenters,
-				RoundTripperCount: roundtrip,
-//				CAFile:            caFile,
-//				Thumbprint:        thumbprint,
-				SecretRef:         secretRef,
-				SecretName:        secretName,
-				SecretNamespace:   secretNamespace,
-				IPFamily:          ipFamily,
-			}
-		}
-	}
-
-        // only global icenter exits, use it to initialize virtualcenter.
-	// it must have at least one virtualcenter 
-	if cfg.Global.VCenterIP != "" && cfg.VirtualCenter[cfg.Global.VCenterIP] == nil {
-		cfg.VirtualCenter[cfg.Global.VCenterIP] = &VirtualCenterConfig{
-			User:              cfg.Global.User,
-			Password:          cfg.Global.Password,
-			TenantRef:         cfg.Global.VCenterIP,
-			VCenterIP:         cfg.Global.VCenterIP,
-			VCenterPort:       cfg.Global.VCenterPort,
-//			InsecureFlag:      cfg.Global.InsecureFlag,
-			Datacenters:       cfg.Global.Datacenters,
-			RoundTripperCount: cfg.Global.RoundTripperCount,
-//			CAFile:            cfg.Global.CAFile,
-//			Thumbprint:        cfg.Global.Thumbprint,
-			SecretRef:         DefaultCredentialManager,
-			SecretName:        cfg.Global.SecretName,
-			SecretNamespace:   cfg.Global.SecretNamespace,
-			IPFamily:          cfg.Global.IPFamily,
-		}
-	}
-
-	err := cfg.validateConfig()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	errs = append(errs, cfg.validateConfig())
+	return NewAggregate(errs)
 }
 
 // IsSecretInfoProvided returns true if k8s secret is set or using generic CO secret method.
@@ -298,29 +69,59 @@ func (cfg *Config) IsSecretInfoProvided() bool {
 		(cfg.Global.SecretName == "" && cfg.Global.SecretNamespace == "" && cfg.Global.SecretsDirectory != "")
 }
 
-func validateIPFamily(value string) ([]string, error) {
-	if len(value) == 0 {
-		return []string{DefaultIPFamily}, nil
-	}
-
-	ipFamilies := strings.Split(value, ",")
-	for i, ipFamily := range ipFamilies {
-		ipFamily = strings.TrimSpace(ipFamily)
+// ParseIPFamily returns the IP families, in order of priority, of a
+// comma-separated ip-family setting.
+func ParseIPFamily(value string) ([]string, error) {
+	var ipFamilies []string
+	for _, ipFamily := range strings.Split(value, ",") {
+		ipFamily = strings.ToLower(strings.TrimSpace(ipFamily))
 		if len(ipFamily) == 0 {
-			copy(ipFamilies[i:], ipFamilies[i+1:])      // Shift a[i+1:] left one index.
-			ipFamilies[len(ipFamilies)-1] = ""          // Erase last element (write zero value).
-			ipFamilies = ipFamilies[:len(ipFamilies)-1] // Truncate slice.
 			continue
 		}
-		if !strings.EqualFold(ipFamily, IPv4Family) && !strings.EqualFold(ipFamily, IPv6Family) {
+		if ipFamily != IPv4Family && ipFamily != IPv6Family {
 			return nil, ErrInvalidIPFamilyType
 		}
+		ipFamilies = append(ipFamilies, ipFamily)
+	}
+	if len(ipFamilies) == 0 {
+		return []string{DefaultIPFamily}, nil
 	}
 
 	return ipFamilies, nil
 }
 
+// validateDualStack checks that both IP families are listed when dual stack
+// is enabled.
+func validateDualStack(dualStack bool, ipFamilyPriority []string) error {
+	if !dualStack {
+		return nil
+	}
+	families := make(map[string]bool)
+	for _, ipFamily := range ipFamilyPriority {
+		families[ipFamily] = true
+	}
+	if !families[IPv4Family] || !families[IPv6Family] {
+		return ErrDualStackIPFamily
+	}
+	return nil
+}
+
+// validateThumbprint checks that the value is a thumbprint ICSConnection
+// accepts, if set.
+func validateThumbprint(value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+	_, err := icslib.ParseThumbprint(value)
+	return err
+}
+
+// validateConfig fills in the defaults of every iCenter and validates the
+// whole configuration. All problems are reported at once as an aggregate of
+// FieldErrors.
 func (cfg *Config) validateConfig() error {
+	var errs []error
+
 	//Fix default global values
 	if cfg.Global.RoundTripperCount == 0 {
 		cfg.Global.RoundTripperCount = DefaultRoundTripperCount
@@ -335,10 +136,24 @@ func (cfg *Config) validateConfig() error {
 		cfg.Global.IPFamily = DefaultIPFamily
 	}
 
-	ipFamilyPriority, err := validateIPFamily(cfg.Global.IPFamily)
+	if err := validatePort(cfg.Global.VCenterPort); err != nil {
+		errs = append(errs, NewFieldError(GlobalPath("port"), cfg.Global.VCenterPort, err))
+	}
+	if err := validateAPIBinding(cfg.Global.APIBinding); err != nil {
+		errs = append(errs, NewFieldError(GlobalPath("api-binding"), cfg.Global.APIBinding, err))
+	}
+	if err := validateThumbprint(cfg.Global.Thumbprint); err != nil {
+		errs = append(errs, NewFieldError(GlobalPath("thumbprint"), cfg.Global.Thumbprint, err))
+	}
+	if (cfg.Global.SecretName == "") != (cfg.Global.SecretNamespace == "") {
+		errs = append(errs, NewFieldError(GlobalPath("secret-name"), cfg.Global.SecretName, ErrIncompleteSecretRef))
+	}
+
+	ipFamilyPriority, err := ParseIPFamily(cfg.Global.IPFamily)
 	if err != nil {
-		klog.Errorf("Invalid Global IPFamily: %s, err=%s", cfg.Global.IPFamily, err)
-		return err
+		errs = append(errs, NewFieldError(GlobalPath("ip-family"), cfg.Global.IPFamily, err))
+	} else if err := validateDualStack(cfg.Global.DualStack, ipFamilyPriority); err != nil {
+		errs = append(errs, NewFieldError(GlobalPath("ip-family"), cfg.Global.IPFamily, err))
 	}
 
 	// Create a single instance of ICSInstance for the Global VCenterIP if the
@@ -350,15 +165,16 @@ func (cfg *Config) validateConfig() error {
 			TenantRef:         cfg.Global.VCenterIP,
 			VCenterIP:         cfg.Global.VCenterIP,
 			VCenterPort:       cfg.Global.VCenterPort,
-//			InsecureFlag:      cfg.Global.InsecureFlag,
+			InsecureFlag:      cfg.Global.InsecureFlag,
 			Datacenters:       cfg.Global.Datacenters,
 			RoundTripperCount: cfg.Global.RoundTripperCount,
-//			CAFile:            cfg.Global.CAFile,
-//			Thumbprint:        cfg.Global.Thumbprint,
+			CAFile:            cfg.Global.CAFile,
+			Thumbprint:        cfg.Global.Thumbprint,
 			SecretRef:         DefaultCredentialManager,
 			SecretName:        cfg.Global.SecretName,
 			SecretNamespace:   cfg.Global.SecretNamespace,
 			IPFamily:          cfg.Global.IPFamily,
+			DualStack:         cfg.Global.DualStack,
 			IPFamilyPriority:  ipFamilyPriority,
 		}
 		cfg.VirtualCenter[cfg.Global.VCenterIP] = vcConfig
@@ -366,16 +182,24 @@ func (cfg *Config) validateConfig() error {
 
 	// Must have at least one vCenter defined
 	if len(cfg.VirtualCenter) == 0 {
-		klog.Error(ErrMissingVCenter)
-		return ErrMissingVCenter
+		errs = append(errs, NewFieldError(GlobalPath("server"), "", ErrMissingVCenter))
+	}
+
+	// Visit the iCenters in a stable order so that errors are reproducible
+	vcServers := make([]string, 0, len(cfg.VirtualCenter))
+	for vcServer := range cfg.VirtualCenter {
+		vcServers = append(vcServers, vcServer)
 	}
+	sort.Strings(vcServers)
+	endpoints := make(map[string]string)
 
 	// ics.conf is no longer supported in the old format.
-	for vcServer, vcConfig := range cfg.VirtualCenter {
+	for _, vcServer := range vcServers {
+		vcConfig := cfg.VirtualCenter[vcServer]
 		klog.V(4).Infof("Initializing vc server %s", vcServer)
 		if vcServer == "" {
-			klog.Error(ErrInvalidVCenterIP)
-			return ErrInvalidVCenterIP
+			errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "server"), "", ErrInvalidVCenterIP))
+			continue
 		}
 
 		// If vcConfig.VCenterIP is explicitly set, that means the vcServer
@@ -388,30 +212,60 @@ func (cfg *Config) validateConfig() error {
 			vcConfig.TenantRef = vcServer
 		}
 
-		if !cfg.IsSecretInfoProvided() && !vcConfig.IsSecretInfoProvided() {
-			if vcConfig.User == "" {
-				vcConfig.User = cfg.Global.User
+		vcConfig.CredentialProvider = strings.ToLower(vcConfig.CredentialProvider)
+		switch vcConfig.CredentialProvider {
+		case "", CredentialProviderSecret:
+			vcConfig.CredentialProvider = CredentialProviderSecret
+			if (vcConfig.SecretName == "") != (vcConfig.SecretNamespace == "") {
+				errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "secret-name"), vcConfig.SecretName, ErrIncompleteSecretRef))
+			}
+			if !cfg.IsSecretInfoProvided() && !vcConfig.IsSecretInfoProvided() {
 				if vcConfig.User == "" {
-					klog.Errorf("vcConfig.User is empty for vc %s!", vcServer)
-					return ErrUsernameMissing
+					vcConfig.User = cfg.Global.User
+					if vcConfig.User == "" {
+						errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "user"), "", ErrUsernameMissing))
+					}
 				}
-			}
-			if vcConfig.Password == "" {
-				vcConfig.Password = cfg.Global.Password
 				if vcConfig.Password == "" {
-					klog.Errorf("vcConfig.Password is empty for vc %s!", vcServer)
-					return ErrPasswordMissing
+					vcConfig.Password = cfg.Global.Password
+					if vcConfig.Password == "" {
+						errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "password"), "", ErrPasswordMissing))
+					}
 				}
+			} else if cfg.IsSecretInfoProvided() && !vcConfig.IsSecretInfoProvided() {
+				vcConfig.SecretRef = DefaultCredentialManager
+			} else if vcConfig.IsSecretInfoProvided() {
+				vcConfig.SecretRef = vcConfig.SecretNamespace + "/" + vcConfig.SecretName
+			}
+		case CredentialProviderExec:
+			if vcConfig.CredentialExecCommand == "" {
+				errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "credential-exec-command"), "", ErrCredentialExecCommandMissing))
 			}
-		} else if cfg.IsSecretInfoProvided() && !vcConfig.IsSecretInfoProvided() {
-			vcConfig.SecretRef = DefaultCredentialManager
-		} else if vcConfig.IsSecretInfoProvided() {
-			vcConfig.SecretRef = vcConfig.SecretNamespace + "/" + vcConfig.SecretName
+			vcConfig.SecretRef = CredentialProviderExec + "/" + vcConfig.TenantRef
+		case CredentialProviderHTTP:
+			if vcConfig.CredentialURL == "" {
+				errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "credential-url"), "", ErrCredentialURLMissing))
+			}
+			vcConfig.SecretRef = CredentialProviderHTTP + "/" + vcConfig.TenantRef
+		default:
+			errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "credential-provider"), vcConfig.CredentialProvider, ErrInvalidCredentialProvider))
 		}
 
 		if vcConfig.VCenterPort == "" {
 			vcConfig.VCenterPort = cfg.Global.VCenterPort
 		}
+		if err := validatePort(vcConfig.VCenterPort); err != nil {
+			errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "port"), vcConfig.VCenterPort, err))
+		}
+
+		// Two tenants must not point at the same iCenter endpoint
+		endpoint := strings.ToLower(net.JoinHostPort(vcConfig.VCenterIP, vcConfig.VCenterPort))
+		if other, ok := endpoints[endpoint]; ok {
+			klog.Errorf("iCenter %s is configured by both %s and %s", endpoint, other, vcServer)
+			errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "server"), vcConfig.VCenterIP, ErrDuplicateVCenter))
+		} else {
+			endpoints[endpoint] = vcServer
+		}
 
 		if vcConfig.Datacenters == "" {
 			if cfg.Global.Datacenters != "" {
@@ -421,47 +275,59 @@ func (cfg *Config) validateConfig() error {
 		if vcConfig.RoundTripperCount == 0 {
 			vcConfig.RoundTripperCount = cfg.Global.RoundTripperCount
 		}
-/*
 		if vcConfig.CAFile == "" {
 			vcConfig.CAFile = cfg.Global.CAFile
 		}
-*/
-/*
 		if vcConfig.Thumbprint == "" {
 			vcConfig.Thumbprint = cfg.Global.Thumbprint
+		} else if err := validateThumbprint(vcConfig.Thumbprint); err != nil {
+			errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "thumbprint"), vcConfig.Thumbprint, err))
 		}
-*/
 		if vcConfig.IPFamily == "" {
 			vcConfig.IPFamily = cfg.Global.IPFamily
 		}
 
-		ipFamilyPriority, err := validateIPFamily(vcConfig.IPFamily)
+		if !vcConfig.DualStack {
+			vcConfig.DualStack = cfg.Global.DualStack
+		}
+
+		ipFamilyPriority, err := ParseIPFamily(vcConfig.IPFamily)
 		if err != nil {
-			klog.Errorf("Invalid vcConfig IPFamily: %s, err=%s", vcConfig.IPFamily, err)
-			return err
+			errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "ip-family"), vcConfig.IPFamily, err))
+		} else if err := validateDualStack(vcConfig.DualStack, ipFamilyPriority); err != nil {
+			errs = append(errs, NewFieldError(VirtualCenterPath(vcServer, "ip-family"), vcConfig.IPFamily, err))
 		}
 		vcConfig.IPFamilyPriority = ipFamilyPriority
-/*
+
 		insecure := vcConfig.InsecureFlag
 		if !insecure {
 			vcConfig.InsecureFlag = cfg.Global.InsecureFlag
 		}
-*/
 	}
 
-	return nil
+	return NewAggregate(errs)
 }
 
 // ReadConfig parses ics cloud config file and stores it into VSphereConfig.
+// Both the gcfg INI and the versioned YAML format are accepted.
 // Environment variables are also checked
 func ReadConfig(config io.Reader) (*Config, error) {
 	if config == nil {
 		return nil, fmt.Errorf("no vSphere cloud provider config file given")
 	}
 
+	data, err := ioutil.ReadAll(config)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{}
 
-	if err := gcfg.FatalOnly(gcfg.ReadInto(cfg, config)); err != nil {
+	if IsYAMLConfig(data) {
+		if err := UnmarshalYAMLConfig(data, cfg); err != nil {
+			return nil, err
+		}
+	} else if err := gcfg.FatalOnly(gcfg.ReadStringInto(cfg, string(data))); err != nil {
 		return nil, err
 	}
 
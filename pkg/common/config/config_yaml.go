@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+)
+
+// The YAML format carries the same settings as the gcfg INI format, with
+// sections as camelCase maps, for example:
+//
+//   apiVersion: ics.cloudprovider/v1alpha1
+//   global:
+//     port: "443"
+//     secretName: icsccm
+//     secretNamespace: kube-system
+//   virtualCenter:
+//     10.0.0.1:
+//       datacenters: dc1,dc2
+//   labels:
+//     region: k8s-region
+//     zone: k8s-zone
+
+var yamlAPIVersionRegexp = regexp.MustCompile(`(?m)^apiVersion\s*:`)
+
+// IsYAMLConfig returns true if the config file uses the versioned YAML format
+// rather than gcfg INI, that is if it sets apiVersion at the top level.
+func IsYAMLConfig(data []byte) bool {
+	return yamlAPIVersionRegexp.Match(data)
+}
+
+// UnmarshalYAMLConfig decodes a versioned YAML config into out. Unknown fields
+// are rejected so that typos do not go unnoticed.
+func UnmarshalYAMLConfig(data []byte, out interface{}) error {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &fields); err != nil {
+		return err
+	}
+	var apiVersion string
+	if raw, ok := fields["apiVersion"]; ok {
+		if err := json.Unmarshal(raw, &apiVersion); err != nil {
+			return err
+		}
+	}
+	if apiVersion != APIVersion {
+		klog.Errorf("Unsupported config apiVersion %q, expected %q", apiVersion, APIVersion)
+		return ErrUnsupportedAPIVersion
+	}
+	delete(fields, "apiVersion")
+
+	jsonData, err = json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("invalid %s config: %v", APIVersion, err)
+	}
+	return nil
+}
+
+// redactedValue replaces secrets in printed configs.
+const redactedValue = "<redacted>"
+
+// redactedKeys are the settings holding secrets.
+var redactedKeys = map[string]bool{
+	"password": true,
+	"token":    true,
+}
+
+// MarshalYAMLConfig encodes in as a versioned YAML config.
+func MarshalYAMLConfig(in interface{}) ([]byte, error) {
+	return marshalYAMLConfig(in, false)
+}
+
+// MarshalRedactedYAMLConfig encodes in as a versioned YAML config with the
+// values of passwords and tokens redacted. It is used to print the effective
+// config.
+func MarshalRedactedYAMLConfig(in interface{}) ([]byte, error) {
+	return marshalYAMLConfig(in, true)
+}
+
+func marshalYAMLConfig(in interface{}, redactSecrets bool) ([]byte, error) {
+	jsonData, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(jsonData, &fields); err != nil {
+		return nil, err
+	}
+	if redactSecrets {
+		redact(fields)
+	}
+	fields["apiVersion"] = APIVersion
+
+	return yaml.Marshal(fields)
+}
+
+// redact replaces the values of redactedKeys in nested maps.
+func redact(fields map[string]interface{}) {
+	for key, value := range fields {
+		if nested, ok := value.(map[string]interface{}); ok {
+			redact(nested)
+		} else if redactedKeys[key] && value != "" {
+			fields[key] = redactedValue
+		}
+	}
+}
+
+// fixupVirtualCenters replaces iCenters declared without any settings, which
+// the YAML format decodes as nil, with empty configs.
+func (cfg *Config) fixupVirtualCenters() {
+	for vcServer, vcConfig := range cfg.VirtualCenter {
+		if vcConfig == nil {
+			cfg.VirtualCenter[vcServer] = &VirtualCenterConfig{}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsYAMLConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected bool
+	}{
+		{
+			name:     "gcfg",
+			config:   "[Global]\nport = \"443\"\n",
+			expected: false,
+		},
+		{
+			name:     "YAML",
+			config:   "apiVersion: ics.cloudprovider/v1alpha1\nglobal:\n  port: \"443\"\n",
+			expected: true,
+		},
+		{
+			name:     "YAML with apiVersion last",
+			config:   "# comment\nglobal:\n  port: \"443\"\napiVersion : ics.cloudprovider/v1alpha1\n",
+			expected: true,
+		},
+		{
+			name:     "nested apiVersion",
+			config:   "global:\n  apiVersion: ics.cloudprovider/v1alpha1\n",
+			expected: false,
+		},
+		{
+			name:     "gcfg comment",
+			config:   "# apiVersion: ics.cloudprovider/v1alpha1\n[Global]\n",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := IsYAMLConfig([]byte(test.config)); actual != test.expected {
+				t.Errorf("IsYAMLConfig returned %t, expected %t", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestReadConfigFormats(t *testing.T) {
+	gcfgConfig := `
+[Global]
+user = "admin"
+password = "secret"
+port = "8443"
+insecure-flag = "true"
+datacenters = "dc1"
+ip-family = "ipv4,ipv6"
+
+[VirtualCenter "10.0.0.1"]
+datacenters = "dc1,dc2"
+
+[VirtualCenter "tenant"]
+server = "10.0.0.2"
+port = "443"
+user = "other"
+password = "other-secret"
+
+[Labels]
+region = "k8s-region"
+zone = "k8s-zone"
+`
+	yamlConfig := `
+apiVersion: ics.cloudprovider/v1alpha1
+global:
+  user: admin
+  password: secret
+  port: "8443"
+  insecureFlag: true
+  datacenters: dc1
+  ipFamily: ipv4,ipv6
+virtualCenter:
+  10.0.0.1:
+    datacenters: dc1,dc2
+  tenant:
+    server: 10.0.0.2
+    port: "443"
+    user: other
+    password: other-secret
+labels:
+  region: k8s-region
+  zone: k8s-zone
+`
+
+	fromGcfg, err := ReadConfig(strings.NewReader(gcfgConfig))
+	if err != nil {
+		t.Fatalf("Reading the gcfg config failed: %v", err)
+	}
+	fromYAML, err := ReadConfig(strings.NewReader(yamlConfig))
+	if err != nil {
+		t.Fatalf("Reading the YAML config failed: %v", err)
+	}
+	if !reflect.DeepEqual(fromGcfg, fromYAML) {
+		t.Errorf("The configs differ:\ngcfg: %+v\nYAML: %+v", fromGcfg, fromYAML)
+	}
+
+	vcConfig := fromYAML.VirtualCenter["tenant"]
+	if vcConfig == nil || vcConfig.VCenterIP != "10.0.0.2" || vcConfig.TenantRef != "tenant" || vcConfig.VCenterPort != "443" {
+		t.Errorf("Unexpected iCenter config %+v", vcConfig)
+	}
+	if vcConfig := fromYAML.VirtualCenter["10.0.0.1"]; vcConfig == nil || vcConfig.VCenterPort != "8443" || vcConfig.User != "admin" {
+		t.Errorf("Global settings were not applied to %+v", vcConfig)
+	}
+}
+
+func TestReadYAMLConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "unsupported apiVersion",
+			config: "apiVersion: ics.cloudprovider/v2\nglobal:\n  port: \"443\"\n",
+			err:    ErrUnsupportedAPIVersion.Error(),
+		},
+		{
+			name:   "unknown field",
+			config: "apiVersion: ics.cloudprovider/v1alpha1\nglobal:\n  prot: \"443\"\n",
+			err:    "unknown field",
+		},
+		{
+			name:   "gcfg key",
+			config: "apiVersion: ics.cloudprovider/v1alpha1\nglobal:\n  insecure-flag: true\n",
+			err:    "unknown field",
+		},
+		{
+			name:   "invalid YAML",
+			config: "apiVersion: ics.cloudprovider/v1alpha1\nglobal: [\n",
+			err:    "yaml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(test.config))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ReadConfig returned %v, expected an error containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestMarshalYAMLConfig(t *testing.T) {
+	cfg := &Config{}
+	cfg.Global.User = "admin"
+	cfg.Global.Password = "secret"
+	cfg.VirtualCenter = map[string]*VirtualCenterConfig{
+		"10.0.0.1": {Password: "other-secret", Datacenters: "dc1"},
+	}
+
+	data, err := MarshalYAMLConfig(cfg)
+	if err != nil {
+		t.Fatalf("MarshalYAMLConfig failed: %v", err)
+	}
+	if !IsYAMLConfig(data) {
+		t.Errorf("The marshaled config is not detected as YAML:\n%s", data)
+	}
+
+	decoded := &Config{}
+	if err := UnmarshalYAMLConfig(data, decoded); err != nil {
+		t.Fatalf("UnmarshalYAMLConfig failed: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(cfg, decoded) {
+		t.Errorf("The config changed in the round trip:\nbefore: %+v\nafter:  %+v", cfg, decoded)
+	}
+}
@@ -43,8 +43,19 @@ const (
 	// DefaultIPFamily is the default IP addressing to use for networking
 	DefaultIPFamily = IPv4Family
 
+	// APIVersion is the version of the YAML config format.
+	APIVersion = "ics.cloudprovider/v1alpha1"
+
 	// DefaultCredentialManager used for the Global CredMgr/Lister
 	DefaultCredentialManager string = "Global"
+
+	// CredentialProviderSecret reads credentials from the config file, a
+	// Kubernetes secret or the secrets directory.
+	CredentialProviderSecret = "secret"
+	// CredentialProviderExec runs a credential exec plugin.
+	CredentialProviderExec = "exec"
+	// CredentialProviderHTTP queries a vault-style credential service.
+	CredentialProviderHTTP = "http"
 )
 
 var (
@@ -64,4 +75,44 @@ var (
 
 	// ErrInvalidIPFamilyType is returned when an invalid IPFamily type is encountered
 	ErrInvalidIPFamilyType = errors.New("Invalid IP Family type")
+
+	// ErrDualStackIPFamily is returned when dual stack is enabled but the IP
+	// family does not list both ipv4 and ipv6.
+	ErrDualStackIPFamily = errors.New("Dual stack requires both ipv4 and ipv6 IP families")
+
+	// ErrInvalidPort is returned when a port is not a number between 1 and
+	// 65535.
+	ErrInvalidPort = errors.New("Invalid port")
+
+	// ErrInvalidAPIBinding is returned when the API binding is not an
+	// ADDRESS:PORT pair.
+	ErrInvalidAPIBinding = errors.New("Invalid API binding")
+
+	// ErrIncompleteSecretRef is returned when only one of the secret name
+	// and namespace is set.
+	ErrIncompleteSecretRef = errors.New("Secret name and namespace must be set together")
+
+	// ErrDuplicateVCenter is returned when two VirtualCenter sections point
+	// at the same iCenter server and port.
+	ErrDuplicateVCenter = errors.New("iCenter is configured more than once")
+
+	// ErrInvalidEnvValue is returned when an environment variable cannot be
+	// parsed as the type of the setting it overrides.
+	ErrInvalidEnvValue = errors.New("Invalid environment variable value")
+
+	// ErrUnsupportedAPIVersion is returned when a YAML config does not use
+	// a supported apiVersion.
+	ErrUnsupportedAPIVersion = errors.New("Unsupported config apiVersion")
+
+	// ErrInvalidCredentialProvider is returned when an unknown credential
+	// provider is configured.
+	ErrInvalidCredentialProvider = errors.New("Invalid credential provider")
+
+	// ErrCredentialExecCommandMissing is returned when the exec credential
+	// provider is configured without a command.
+	ErrCredentialExecCommandMissing = errors.New("Credential exec command is missing")
+
+	// ErrCredentialURLMissing is returned when the http credential provider
+	// is configured without a URL.
+	ErrCredentialURLMissing = errors.New("Credential service URL is missing")
 )
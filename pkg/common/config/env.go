@@ -0,0 +1,212 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// Environment variables override the settings read from the config file,
+// which in turn override the defaults applied during validation.
+//
+// iCenters are declared with ICS_VCENTER_<ID>=<tenant ref> and their settings
+// overridden with VCENTER_<ID>_<SETTING>. Variables of the global schema, such
+// as ICS_VCENTER_PORT, never declare an iCenter.
+
+// EnvVar is an environment variable overriding a config setting.
+type EnvVar struct {
+	// Name of the environment variable
+	Name string
+	// Path of the overridden setting, as reported in FieldErrors
+	Path string
+	// Secret values are never logged
+	Secret bool
+	// field returns a pointer to the overridden setting
+	field func(cfg *Config) interface{}
+}
+
+// VirtualCenterEnvVar is an environment variable overriding a setting of the
+// iCenters declared through the environment. Its name is
+// VCENTER_<ID>_<Suffix>.
+type VirtualCenterEnvVar struct {
+	Suffix string
+	// Key of the overridden setting in the VirtualCenter section
+	Key    string
+	Secret bool
+	field  func(vcConfig *VirtualCenterConfig) interface{}
+}
+
+const (
+	// EnvVirtualCenterPrefix prefixes the variables declaring iCenters.
+	EnvVirtualCenterPrefix = "ICS_VCENTER_"
+)
+
+// GlobalEnvVars is the schema of the variables overriding the Global and
+// Labels sections.
+var GlobalEnvVars = []EnvVar{
+	{Name: "ICS_VCENTER", Path: GlobalPath("server"), field: func(cfg *Config) interface{} { return &cfg.Global.VCenterIP }},
+	{Name: "ICS_VCENTER_PORT", Path: GlobalPath("port"), field: func(cfg *Config) interface{} { return &cfg.Global.VCenterPort }},
+	{Name: "ICS_USER", Path: GlobalPath("user"), field: func(cfg *Config) interface{} { return &cfg.Global.User }},
+	{Name: "ICS_PASSWORD", Path: GlobalPath("password"), Secret: true, field: func(cfg *Config) interface{} { return &cfg.Global.Password }},
+	{Name: "ICS_DATACENTER", Path: GlobalPath("datacenters"), field: func(cfg *Config) interface{} { return &cfg.Global.Datacenters }},
+	{Name: "ICS_SECRET_NAME", Path: GlobalPath("secret-name"), field: func(cfg *Config) interface{} { return &cfg.Global.SecretName }},
+	{Name: "ICS_SECRET_NAMESPACE", Path: GlobalPath("secret-namespace"), field: func(cfg *Config) interface{} { return &cfg.Global.SecretNamespace }},
+	{Name: "ICS_SECRET_FIELD_SELECTOR", Path: GlobalPath("secret-field-selector"), field: func(cfg *Config) interface{} { return &cfg.Global.SecretFieldSelector }},
+	{Name: "ICS_SECRETS_DIRECTORY", Path: GlobalPath("secrets-directory"), field: func(cfg *Config) interface{} { return &cfg.Global.SecretsDirectory }},
+	{Name: "ICS_ROUNDTRIP_COUNT", Path: GlobalPath("soap-roundtrip-count"), field: func(cfg *Config) interface{} { return &cfg.Global.RoundTripperCount }},
+	{Name: "ICS_INSECURE", Path: GlobalPath("insecure-flag"), field: func(cfg *Config) interface{} { return &cfg.Global.InsecureFlag }},
+	{Name: "ICS_CAFILE", Path: GlobalPath("ca-file"), field: func(cfg *Config) interface{} { return &cfg.Global.CAFile }},
+	{Name: "ICS_THUMBPRINT", Path: GlobalPath("thumbprint"), field: func(cfg *Config) interface{} { return &cfg.Global.Thumbprint }},
+	{Name: "ICS_API_DISABLE", Path: GlobalPath("api-disable"), field: func(cfg *Config) interface{} { return &cfg.Global.APIDisable }},
+	{Name: "ICS_API_BINDING", Path: GlobalPath("api-binding"), field: func(cfg *Config) interface{} { return &cfg.Global.APIBinding }},
+	{Name: "ICS_IP_FAMILY", Path: GlobalPath("ip-family"), field: func(cfg *Config) interface{} { return &cfg.Global.IPFamily }},
+	{Name: "ICS_DUAL_STACK", Path: GlobalPath("dual-stack"), field: func(cfg *Config) interface{} { return &cfg.Global.DualStack }},
+	{Name: "ICS_LABEL_REGION", Path: SectionPath("Labels", "region"), field: func(cfg *Config) interface{} { return &cfg.Labels.Region }},
+	{Name: "ICS_LABEL_ZONE", Path: SectionPath("Labels", "zone"), field: func(cfg *Config) interface{} { return &cfg.Labels.Zone }},
+}
+
+// VirtualCenterEnvVars is the schema of the variables overriding the settings
+// of an iCenter declared through the environment.
+var VirtualCenterEnvVars = []VirtualCenterEnvVar{
+	{Suffix: "USERNAME", Key: "user", field: func(vc *VirtualCenterConfig) interface{} { return &vc.User }},
+	{Suffix: "PASSWORD", Key: "password", Secret: true, field: func(vc *VirtualCenterConfig) interface{} { return &vc.Password }},
+	{Suffix: "SERVER", Key: "server", field: func(vc *VirtualCenterConfig) interface{} { return &vc.VCenterIP }},
+	{Suffix: "PORT", Key: "port", field: func(vc *VirtualCenterConfig) interface{} { return &vc.VCenterPort }},
+	{Suffix: "INSECURE", Key: "insecure-flag", field: func(vc *VirtualCenterConfig) interface{} { return &vc.InsecureFlag }},
+	{Suffix: "DATACENTERS", Key: "datacenters", field: func(vc *VirtualCenterConfig) interface{} { return &vc.Datacenters }},
+	{Suffix: "ROUNDTRIP", Key: "soap-roundtrip-count", field: func(vc *VirtualCenterConfig) interface{} { return &vc.RoundTripperCount }},
+	{Suffix: "CAFILE", Key: "ca-file", field: func(vc *VirtualCenterConfig) interface{} { return &vc.CAFile }},
+	{Suffix: "THUMBPRINT", Key: "thumbprint", field: func(vc *VirtualCenterConfig) interface{} { return &vc.Thumbprint }},
+	{Suffix: "SECRET_NAME", Key: "secret-name", field: func(vc *VirtualCenterConfig) interface{} { return &vc.SecretName }},
+	{Suffix: "SECRET_NAMESPACE", Key: "secret-namespace", field: func(vc *VirtualCenterConfig) interface{} { return &vc.SecretNamespace }},
+	{Suffix: "IP_FAMILY", Key: "ip-family", field: func(vc *VirtualCenterConfig) interface{} { return &vc.IPFamily }},
+	{Suffix: "DUAL_STACK", Key: "dual-stack", field: func(vc *VirtualCenterConfig) interface{} { return &vc.DualStack }},
+}
+
+// SetFromEnv sets the setting pointed to by field, a *string, *bool or *uint,
+// from the environment variable name. Unset and empty variables are ignored.
+// It returns true if the setting was overridden, and an error if the value
+// cannot be parsed or field has another type.
+func SetFromEnv(name string, path string, secret bool, field interface{}) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return false, nil
+	}
+
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, NewFieldError(path, value, ErrInvalidEnvValue)
+		}
+		*f = b
+	case *uint:
+		u, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return false, NewFieldError(path, value, ErrInvalidEnvValue)
+		}
+		*f = uint(u)
+	default:
+		return false, fmt.Errorf("unsupported type %T for environment variable %s", field, name)
+	}
+
+	if secret {
+		value = redactedValue
+	}
+	klog.V(4).Infof("%s set from %s=%s", path, name, value)
+	return true, nil
+}
+
+// isGlobalEnvVar returns true if name belongs to the global schema.
+func isGlobalEnvVar(name string) bool {
+	for _, envVar := range GlobalEnvVars {
+		if envVar.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// virtualCenterEnvIDs returns the IDs of the iCenters declared through
+// ICS_VCENTER_<ID> variables, mapped to their tenant refs.
+func virtualCenterEnvIDs() map[string]string {
+	ids := make(map[string]string)
+	for _, e := range os.Environ() {
+		pair := strings.SplitN(e, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+
+		key := pair[0]
+		value := pair[1]
+		if !strings.HasPrefix(key, EnvVirtualCenterPrefix) || value == "" || isGlobalEnvVar(key) {
+			continue
+		}
+		id := strings.TrimPrefix(key, EnvVirtualCenterPrefix)
+		if id == "" {
+			continue
+		}
+		ids[id] = value
+	}
+	return ids
+}
+
+// applyEnv overrides the config with the environment variables of the
+// schema. Variables that cannot be parsed are reported as FieldErrors.
+func (cfg *Config) applyEnv() []error {
+	var errs []error
+
+	for _, envVar := range GlobalEnvVars {
+		if _, err := SetFromEnv(envVar.Name, envVar.Path, envVar.Secret, envVar.field(cfg)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	ids := virtualCenterEnvIDs()
+	sortedIDs := make([]string, 0, len(ids))
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Strings(sortedIDs)
+
+	for _, id := range sortedIDs {
+		tenantRef := ids[id]
+		vcConfig, ok := cfg.VirtualCenter[tenantRef]
+		if !ok {
+			klog.V(3).Infof("Adding iCenter %s from %s%s", tenantRef, EnvVirtualCenterPrefix, id)
+			vcConfig = &VirtualCenterConfig{}
+			cfg.VirtualCenter[tenantRef] = vcConfig
+		}
+		for _, envVar := range VirtualCenterEnvVars {
+			name := "VCENTER_" + id + "_" + envVar.Suffix
+			path := VirtualCenterPath(tenantRef, envVar.Key)
+			if _, err := SetFromEnv(name, path, envVar.Secret, envVar.field(vcConfig)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setEnv sets the environment variables and returns a function restoring
+// the previous environment.
+func setEnv(t *testing.T, env map[string]string) func() {
+	previous := make(map[string]*string)
+	for name, value := range env {
+		if old, ok := os.LookupEnv(name); ok {
+			previous[name] = &old
+		} else {
+			previous[name] = nil
+		}
+		if err := os.Setenv(name, value); err != nil {
+			t.Fatalf("Setting %s failed: %v", name, err)
+		}
+	}
+	return func() {
+		for name, old := range previous {
+			if old == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *old)
+			}
+		}
+	}
+}
+
+func TestReadConfigEnv(t *testing.T) {
+	fileConfig := `
+[Global]
+user = "admin"
+password = "secret"
+port = "8443"
+[VirtualCenter "10.0.0.1"]
+`
+
+	tests := []struct {
+		name   string
+		config string
+		env    map[string]string
+		check  func(t *testing.T, cfg *Config)
+	}{
+		{
+			name:   "defaults",
+			config: "[VirtualCenter \"10.0.0.1\"]\nuser = \"admin\"\npassword = \"secret\"\n",
+			check: func(t *testing.T, cfg *Config) {
+				if port := cfg.VirtualCenter["10.0.0.1"].VCenterPort; port != DefaultVCenterPort {
+					t.Errorf("Port is %q, expected the default %q", port, DefaultVCenterPort)
+				}
+			},
+		},
+		{
+			name:   "file overrides defaults",
+			config: fileConfig,
+			check: func(t *testing.T, cfg *Config) {
+				if port := cfg.VirtualCenter["10.0.0.1"].VCenterPort; port != "8443" {
+					t.Errorf("Port is %q, expected 8443 from the file", port)
+				}
+			},
+		},
+		{
+			name:   "environment overrides file",
+			config: fileConfig,
+			env:    map[string]string{"ICS_VCENTER_PORT": "9443", "ICS_USER": "env-admin"},
+			check: func(t *testing.T, cfg *Config) {
+				vcConfig := cfg.VirtualCenter["10.0.0.1"]
+				if vcConfig.VCenterPort != "9443" || vcConfig.User != "env-admin" {
+					t.Errorf("iCenter has port %q and user %q, expected 9443 and env-admin from the environment", vcConfig.VCenterPort, vcConfig.User)
+				}
+			},
+		},
+		{
+			name:   "global variables do not declare iCenters",
+			config: fileConfig,
+			env:    map[string]string{"ICS_VCENTER_PORT": "9443"},
+			check: func(t *testing.T, cfg *Config) {
+				if len(cfg.VirtualCenter) != 1 || cfg.VirtualCenter["10.0.0.1"] == nil {
+					t.Errorf("Unexpected iCenters %v", cfg.VirtualCenter)
+				}
+			},
+		},
+		{
+			name:   "iCenter declared by the environment",
+			config: fileConfig,
+			env: map[string]string{
+				"ICS_VCENTER_PROD":       "prod",
+				"VCENTER_PROD_SERVER":    "10.0.0.2",
+				"VCENTER_PROD_PASSWORD":  "pa=ss==",
+				"VCENTER_PROD_ROUNDTRIP": "5",
+				"VCENTER_PROD_INSECURE":  "true",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				vcConfig := cfg.VirtualCenter["prod"]
+				if vcConfig == nil {
+					t.Fatalf("iCenter prod was not declared: %v", cfg.VirtualCenter)
+				}
+				if vcConfig.VCenterIP != "10.0.0.2" || vcConfig.TenantRef != "prod" {
+					t.Errorf("iCenter prod has server %q and tenant ref %q", vcConfig.VCenterIP, vcConfig.TenantRef)
+				}
+				if vcConfig.Password != "pa=ss==" {
+					t.Errorf("Password is %q, expected pa=ss==", vcConfig.Password)
+				}
+				if vcConfig.RoundTripperCount != 5 || !vcConfig.InsecureFlag {
+					t.Errorf("iCenter prod has round trip count %d and insecure flag %t", vcConfig.RoundTripperCount, vcConfig.InsecureFlag)
+				}
+				if vcConfig.User != "admin" || vcConfig.VCenterPort != "8443" {
+					t.Errorf("iCenter prod did not inherit the global user and port: %q, %q", vcConfig.User, vcConfig.VCenterPort)
+				}
+			},
+		},
+		{
+			name:   "tenant ref containing =",
+			config: fileConfig,
+			env:    map[string]string{"ICS_VCENTER_EQ": "a=b", "VCENTER_EQ_SERVER": "10.0.0.3"},
+			check: func(t *testing.T, cfg *Config) {
+				if vcConfig := cfg.VirtualCenter["a=b"]; vcConfig == nil || vcConfig.VCenterIP != "10.0.0.3" {
+					t.Errorf("Unexpected iCenters %v", cfg.VirtualCenter)
+				}
+			},
+		},
+		{
+			name:   "environment overrides an iCenter of the file",
+			config: fileConfig,
+			env:    map[string]string{"ICS_VCENTER_FILE": "10.0.0.1", "VCENTER_FILE_DATACENTERS": "dc1"},
+			check: func(t *testing.T, cfg *Config) {
+				if len(cfg.VirtualCenter) != 1 || cfg.VirtualCenter["10.0.0.1"].Datacenters != "dc1" {
+					t.Errorf("Unexpected iCenters %v", cfg.VirtualCenter)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer setEnv(t, test.env)()
+
+			cfg, err := ReadConfig(strings.NewReader(test.config))
+			if err != nil {
+				t.Fatalf("ReadConfig failed: %v", err)
+			}
+			test.check(t, cfg)
+		})
+	}
+}
+
+func TestReadConfigEnvErrors(t *testing.T) {
+	defer setEnv(t, map[string]string{
+		"ICS_INSECURE":           "maybe",
+		"ICS_VCENTER_PROD":       "prod",
+		"VCENTER_PROD_ROUNDTRIP": "-1",
+	})()
+
+	_, err := ReadConfig(strings.NewReader("[Global]\nuser = \"admin\"\npassword = \"secret\"\n"))
+	if err == nil {
+		t.Fatal("ReadConfig accepted invalid environment variables")
+	}
+	for _, expected := range []string{
+		`Global.insecure-flag: Invalid environment variable value: "maybe"`,
+		`VirtualCenter["prod"].soap-roundtrip-count: Invalid environment variable value: "-1"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Error %q does not contain %q", err, expected)
+		}
+	}
+}
+
+func TestSetFromEnvUnsupportedType(t *testing.T) {
+	defer setEnv(t, map[string]string{"ICS_TEST_INT": "1"})()
+
+	var value int
+	if _, err := SetFromEnv("ICS_TEST_INT", GlobalPath("test"), false, &value); err == nil {
+		t.Error("SetFromEnv accepted an *int")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	fields := map[string]interface{}{
+		"password": "secret",
+		"user":     "admin",
+		"global": map[string]interface{}{
+			"password": "secret",
+			"token":    "token",
+		},
+		"virtualCenter": map[string]interface{}{
+			"10.0.0.1": map[string]interface{}{
+				"password": "",
+				"port":     "443",
+			},
+		},
+	}
+	expected := map[string]interface{}{
+		"password": redactedValue,
+		"user":     "admin",
+		"global": map[string]interface{}{
+			"password": redactedValue,
+			"token":    redactedValue,
+		},
+		"virtualCenter": map[string]interface{}{
+			"10.0.0.1": map[string]interface{}{
+				"password": "",
+				"port":     "443",
+			},
+		},
+	}
+
+	redact(fields)
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("redact returned %v, expected %v", fields, expected)
+	}
+}
+
+func TestMarshalRedactedYAMLConfig(t *testing.T) {
+	cfg := &Config{}
+	cfg.Global.User = "admin"
+	cfg.Global.Password = "global-secret"
+	cfg.VirtualCenter = map[string]*VirtualCenterConfig{
+		"10.0.0.1": {User: "other", Password: "vc-secret"},
+	}
+
+	data, err := MarshalRedactedYAMLConfig(cfg)
+	if err != nil {
+		t.Fatalf("MarshalRedactedYAMLConfig failed: %v", err)
+	}
+	for _, secret := range []string{"global-secret", "vc-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("The config shows %q:\n%s", secret, data)
+		}
+	}
+	if strings.Count(string(data), redactedValue) != 2 || !strings.Contains(string(data), "user: other") {
+		t.Errorf("Unexpected redacted config:\n%s", data)
+	}
+	if cfg.Global.Password != "global-secret" || cfg.VirtualCenter["10.0.0.1"].Password != "vc-secret" {
+		t.Error("MarshalRedactedYAMLConfig changed the config")
+	}
+}
@@ -20,88 +20,132 @@ package config
 type Config struct {
 	Global struct {
 		//iCenter username.
-		User string `gcfg:"user"`
+		User string `gcfg:"user" json:"user,omitempty"`
 		//iCenter password in clear text.
-		Password string `gcfg:"password"`
+		Password string `gcfg:"password" json:"password,omitempty"`
 		// Deprecated. Use VirtualCenter to specify multiple iCenter Servers.
 		// iCenter IP.
-		VCenterIP string `gcfg:"server"`
+		VCenterIP string `gcfg:"server" json:"server,omitempty"`
 		// iCenter port.
-		VCenterPort string `gcfg:"port"`
-
+		VCenterPort string `gcfg:"port" json:"port,omitempty"`
+		// True if iCenter uses self-signed cert.
+		InsecureFlag bool `gcfg:"insecure-flag" json:"insecureFlag,omitempty"`
 		// Datacenter in which VMs are located.
-		Datacenters string `gcfg:"datacenters"`
+		Datacenters string `gcfg:"datacenters" json:"datacenters,omitempty"`
 		// Soap round tripper count (retries = RoundTripper - 1)
-		RoundTripperCount uint `gcfg:"soap-roundtrip-count"`
+		RoundTripperCount uint `gcfg:"soap-roundtrip-count" json:"roundTripperCount,omitempty"`
+		// Specifies the path to a CA certificate in PEM format. This has no effect if
+		// InsecureFlag is enabled. Optional; if not configured, the system's CA
+		// certificates will be used.
+		CAFile string `gcfg:"ca-file" json:"caFile,omitempty"`
+		// Thumbprint of the iCenter's certificate, as colon-separated
+		// hex pairs. Both SHA-1 and SHA-256 fingerprints are accepted.
+		Thumbprint string `gcfg:"thumbprint" json:"thumbprint,omitempty"`
 
 		// Name of the secret were iCenter credentials are present.
-		SecretName string `gcfg:"secret-name"`
+		SecretName string `gcfg:"secret-name" json:"secretName,omitempty"`
 		// Secret Namespace where secret will be present that has iCenter credentials.
-		SecretNamespace string `gcfg:"secret-namespace"`
+		SecretNamespace string `gcfg:"secret-namespace" json:"secretNamespace,omitempty"`
+		// Watch only the configured secrets by name, using a field selector,
+		// instead of every secret in their namespace. This allows the RBAC
+		// rules to be restricted to those secrets.
+		SecretFieldSelector bool `gcfg:"secret-field-selector" json:"secretFieldSelector,omitempty"`
 		// Secret directory in the event that:
 		// 1) we don't want to use the k8s API to listen for changes to secrets
 		// 2) we are not in a k8s env, namely DC/OS, since CSI is CO agnostic
 		// Default: /etc/cloud/credentials
-		SecretsDirectory string `gcfg:"secrets-directory"`
+		SecretsDirectory string `gcfg:"secrets-directory" json:"secretsDirectory,omitempty"`
 		// Disable the ICS CCM API
 		// Default: true
-		APIDisable bool `gcfg:"api-disable"`
+		APIDisable bool `gcfg:"api-disable" json:"apiDisable,omitempty"`
 		// Configurable ICS CCM API port
 		// Default: 43001
-		APIBinding string `gcfg:"api-binding"`
+		APIBinding string `gcfg:"api-binding" json:"apiBinding,omitempty"`
 		// IP Family enables the ability to support IPv4 or IPv6
 		// Supported values are:
 		// ipv4 - IPv4 addresses only (Default)
 		// ipv6 - IPv6 addresses only
-		IPFamily string `gcfg:"ip-family"`
-	}
+		IPFamily string `gcfg:"ip-family" json:"ipFamily,omitempty"`
+		// Dual stack nodes get one address of each IP family, in the order of
+		// IPFamily, which must then list both ipv4 and ipv6.
+		// Default: false
+		DualStack bool `gcfg:"dual-stack" json:"dualStack,omitempty"`
+	} `json:"global"`
 
 	// Virtual Center configurations
-	VirtualCenter map[string]*VirtualCenterConfig
+	VirtualCenter map[string]*VirtualCenterConfig `json:"virtualCenter,omitempty"`
 
 	// Tag categories and tags which correspond to "built-in node labels: zones and region"
 	Labels struct {
-		Zone   string `gcfg:"zone"`
-		Region string `gcfg:"region"`
-	}
+		Zone   string `gcfg:"zone" json:"zone,omitempty"`
+		Region string `gcfg:"region" json:"region,omitempty"`
+	} `json:"labels"`
 }
 
 // VirtualCenterConfig contains information used to access a remote iCenter
 // endpoint.
 type VirtualCenterConfig struct {
 	// iCenter username.
-	User string `gcfg:"user"`
+	User string `gcfg:"user" json:"user,omitempty"`
 	// iCenter password in clear text.
-	Password string `gcfg:"password"`
+	Password string `gcfg:"password" json:"password,omitempty"`
 	// TenantRef (intentionally not exposed via the config) is a unique tenant ref to
 	// be used in place of the vcServer as the primary connection key. If one label is set,
 	// all virtual center configs must have a unique label.
-	TenantRef string
+	TenantRef string `json:"-"`
 	// iCenterIP - If this field in the config is set, it is assumed then that value in [VirtualCenter "<value>"]
 	// is now the TenantRef above and this field is the actual iCenterIP. Otherwise for backward
 	// compatibility, the value by default is the IP or FQDN of the iCenter Server.
-	VCenterIP string `gcfg:"server"`
+	VCenterIP string `gcfg:"server" json:"server,omitempty"`
 	// iCenter port.
-	VCenterPort string `gcfg:"port"`
-
+	VCenterPort string `gcfg:"port" json:"port,omitempty"`
+	// True if iCenter uses self-signed cert.
+	InsecureFlag bool `gcfg:"insecure-flag" json:"insecureFlag,omitempty"`
 	// Datacenter in which VMs are located.
 	//like,"dc1,dc2,dc3,..."
-	Datacenters string `gcfg:"datacenters"`
+	Datacenters string `gcfg:"datacenters" json:"datacenters,omitempty"`
 	// Soap round tripper count (retries = RoundTripper - 1)
-	RoundTripperCount uint `gcfg:"soap-roundtrip-count"`
+	RoundTripperCount uint `gcfg:"soap-roundtrip-count" json:"roundTripperCount,omitempty"`
+	// Specifies the path to a CA certificate in PEM format. This has no effect if
+	// InsecureFlag is enabled. Optional; if not configured, the system's CA
+	// certificates will be used.
+	CAFile string `gcfg:"ca-file" json:"caFile,omitempty"`
+	// Thumbprint of the iCenter's certificate, as colon-separated
+	// hex pairs. Both SHA-1 and SHA-256 fingerprints are accepted.
+	Thumbprint string `gcfg:"thumbprint" json:"thumbprint,omitempty"`
 
 	// SecretRef (intentionally not exposed via the config) is a key to identify which
 	// InformerManager holds the secret
-	SecretRef string
+	SecretRef string `json:"-"`
 	// Name of the secret where iCenter credentials are present.
-	SecretName string `gcfg:"secret-name"`
+	SecretName string `gcfg:"secret-name" json:"secretName,omitempty"`
 	// Namespace where the secret will be present containing iCenter credentials.
-	SecretNamespace string `gcfg:"secret-namespace"`
+	SecretNamespace string `gcfg:"secret-namespace" json:"secretNamespace,omitempty"`
+	// CredentialProvider selects where the iCenter credentials come from.
+	// Supported values are:
+	// secret - config file, Kubernetes secret or secrets directory (Default)
+	// exec - the output of CredentialExecCommand
+	// http - a vault-style service at CredentialURL
+	CredentialProvider string `gcfg:"credential-provider" json:"credentialProvider,omitempty"`
+	// Command run by the exec credential provider.
+	CredentialExecCommand string `gcfg:"credential-exec-command" json:"credentialExecCommand,omitempty"`
+	// Arguments passed to CredentialExecCommand, one per entry.
+	CredentialExecArgs []string `gcfg:"credential-exec-arg" json:"credentialExecArgs,omitempty"`
+	// URL of the service used by the http credential provider.
+	CredentialURL string `gcfg:"credential-url" json:"credentialURL,omitempty"`
+	// File holding the bearer token sent to CredentialURL.
+	CredentialTokenFile string `gcfg:"credential-token-file" json:"credentialTokenFile,omitempty"`
+	// Seconds credentials returned without a lease duration or an expiration
+	// are cached. Default: 300
+	CredentialCacheTTL int64 `gcfg:"credential-cache-ttl" json:"credentialCacheTTL,omitempty"`
 	// IP Family enables the ability to support IPv4 or IPv6
 	// Supported values are:
 	// ipv4 - IPv4 addresses only (Default)
 	// ipv6 - IPv6 addresses only
-	IPFamily string `gcfg:"ip-family"`
+	IPFamily string `gcfg:"ip-family" json:"ipFamily,omitempty"`
+	// Dual stack nodes get one address of each IP family, in the order of
+	// IPFamily, which must then list both ipv4 and ipv6.
+	DualStack bool `gcfg:"dual-stack" json:"dualStack,omitempty"`
 	// IPFamilyPriority (intentionally not exposed via the config) the list/priority of IP versions
-	IPFamilyPriority []string
+	IPFamilyPriority []string `json:"-"`
 }
@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+)
+
+// FieldError is a problem with the value of a single configuration field.
+type FieldError struct {
+	// Path of the field, e.g. VirtualCenter["10.0.0.1"].port
+	Path string
+	// Value is the offending value, if any.
+	Value string
+	Err   error
+}
+
+// NewFieldError returns a FieldError for the field at path.
+func NewFieldError(path string, value string, err error) *FieldError {
+	return &FieldError{
+		Path:  path,
+		Value: value,
+		Err:   err,
+	}
+}
+
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %q", e.Path, e.Err, e.Value)
+}
+
+// SectionPath returns the path of key in a configuration section.
+func SectionPath(section string, key string) string {
+	return section + "." + key
+}
+
+// GlobalPath returns the path of key in the Global section.
+func GlobalPath(key string) string {
+	return SectionPath("Global", key)
+}
+
+// SubsectionPath returns the path of key in the named subsection of section,
+// like [VirtualCenter "name"].
+func SubsectionPath(section string, name string, key string) string {
+	return fmt.Sprintf("%s[%q].%s", section, name, key)
+}
+
+// VirtualCenterPath returns the path of key in the VirtualCenter section
+// named vcServer.
+func VirtualCenterPath(vcServer string, key string) string {
+	return SubsectionPath("VirtualCenter", vcServer, key)
+}
+
+// NewAggregate logs every error and combines them into a single one. It
+// returns nil if there are no errors. Aggregates are flattened.
+func NewAggregate(errs []error) error {
+	var flat []error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			flat = append(flat, agg.Errors()...)
+			continue
+		}
+		klog.Errorf("Invalid configuration: %v", err)
+		flat = append(flat, err)
+	}
+	return utilerrors.NewAggregate(flat)
+}
+
+// validatePort checks that the value is a TCP port number.
+func validatePort(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return ErrInvalidPort
+	}
+	return nil
+}
+
+// validateAPIBinding checks that the value is an ADDRESS:PORT binding. The
+// address may be empty to listen on all interfaces.
+func validateAPIBinding(value string) error {
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return ErrInvalidAPIBinding
+	}
+	if validatePort(port) != nil {
+		return ErrInvalidAPIBinding
+	}
+	return nil
+}
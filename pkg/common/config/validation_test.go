@@ -0,0 +1,197 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []FieldError
+	}{
+		{
+			name: "valid",
+			config: `
+[Global]
+user = "admin"
+password = "secret"
+[VirtualCenter "10.0.0.1"]
+[VirtualCenter "10.0.0.2"]
+port = "8443"
+`,
+		},
+		{
+			name: "invalid iCenter port",
+			config: `
+[Global]
+user = "admin"
+password = "secret"
+[VirtualCenter "10.0.0.1"]
+port = "70000"
+`,
+			expected: []FieldError{
+				{Path: `VirtualCenter["10.0.0.1"].port`, Value: "70000", Err: ErrInvalidPort},
+			},
+		},
+		{
+			name: "all errors reported together",
+			config: `
+[Global]
+port = "https"
+api-binding = "43001"
+ip-family = "ipv5"
+[VirtualCenter "10.0.0.1"]
+port = "443"
+[VirtualCenter "10.0.0.2"]
+user = "admin"
+password = "secret"
+thumbprint = "not a thumbprint"
+`,
+			expected: []FieldError{
+				{Path: `Global.port`, Value: "https", Err: ErrInvalidPort},
+				{Path: `Global.api-binding`, Value: "43001", Err: ErrInvalidAPIBinding},
+				{Path: `Global.ip-family`, Value: "ipv5", Err: ErrInvalidIPFamilyType},
+				{Path: `VirtualCenter["10.0.0.1"].user`, Err: ErrUsernameMissing},
+				{Path: `VirtualCenter["10.0.0.1"].password`, Err: ErrPasswordMissing},
+				{Path: `VirtualCenter["10.0.0.1"].ip-family`, Value: "ipv5", Err: ErrInvalidIPFamilyType},
+				{Path: `VirtualCenter["10.0.0.2"].port`, Value: "https", Err: ErrInvalidPort},
+				{Path: `VirtualCenter["10.0.0.2"].thumbprint`, Value: "not a thumbprint"},
+				{Path: `VirtualCenter["10.0.0.2"].ip-family`, Value: "ipv5", Err: ErrInvalidIPFamilyType},
+			},
+		},
+		{
+			name: "duplicate server",
+			config: `
+[Global]
+user = "admin"
+password = "secret"
+[VirtualCenter "ICENTER.example.com"]
+[VirtualCenter "tenant"]
+server = "icenter.example.com"
+port = "443"
+`,
+			expected: []FieldError{
+				{Path: `VirtualCenter["tenant"].server`, Value: "icenter.example.com", Err: ErrDuplicateVCenter},
+			},
+		},
+		{
+			name: "same server on another port",
+			config: `
+[Global]
+user = "admin"
+password = "secret"
+[VirtualCenter "10.0.0.1"]
+[VirtualCenter "tenant"]
+server = "10.0.0.1"
+port = "8443"
+`,
+		},
+		{
+			name: "missing iCenter",
+			config: `
+[Global]
+user = "admin"
+password = "secret"
+`,
+			expected: []FieldError{
+				{Path: `Global.server`, Err: ErrMissingVCenter},
+			},
+		},
+		{
+			name: "incomplete secret reference",
+			config: `
+[Global]
+secret-name = "icsccm"
+[VirtualCenter "10.0.0.1"]
+secret-namespace = "kube-system"
+`,
+			expected: []FieldError{
+				{Path: `Global.secret-name`, Value: "icsccm", Err: ErrIncompleteSecretRef},
+				{Path: `VirtualCenter["10.0.0.1"].secret-name`, Err: ErrIncompleteSecretRef},
+				{Path: `VirtualCenter["10.0.0.1"].user`, Err: ErrUsernameMissing},
+				{Path: `VirtualCenter["10.0.0.1"].password`, Err: ErrPasswordMissing},
+			},
+		},
+		{
+			name: "credential providers",
+			config: `
+[VirtualCenter "10.0.0.1"]
+credential-provider = "exec"
+[VirtualCenter "10.0.0.2"]
+credential-provider = "HTTP"
+[VirtualCenter "10.0.0.3"]
+credential-provider = "ldap"
+`,
+			expected: []FieldError{
+				{Path: `VirtualCenter["10.0.0.1"].credential-exec-command`, Err: ErrCredentialExecCommandMissing},
+				{Path: `VirtualCenter["10.0.0.2"].credential-url`, Err: ErrCredentialURLMissing},
+				{Path: `VirtualCenter["10.0.0.3"].credential-provider`, Value: "ldap", Err: ErrInvalidCredentialProvider},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(test.config))
+			if len(test.expected) == 0 {
+				if err != nil {
+					t.Fatalf("ReadConfig failed: %v", err)
+				}
+				return
+			}
+
+			agg, ok := err.(utilerrors.Aggregate)
+			if !ok {
+				t.Fatalf("ReadConfig returned %v, expected an aggregate of %d errors", err, len(test.expected))
+			}
+			errs := agg.Errors()
+			if len(errs) != len(test.expected) {
+				t.Fatalf("ReadConfig returned %d errors, expected %d: %v", len(errs), len(test.expected), err)
+			}
+			for i, expected := range test.expected {
+				actual, ok := errs[i].(*FieldError)
+				if !ok {
+					t.Errorf("Error %d is %T, expected a FieldError: %v", i, errs[i], errs[i])
+					continue
+				}
+				if actual.Path != expected.Path || actual.Value != expected.Value {
+					t.Errorf("Error %d is for %s=%q, expected %s=%q", i, actual.Path, actual.Value, expected.Path, expected.Value)
+				}
+				if expected.Err != nil && actual.Err != expected.Err {
+					t.Errorf("Error %d of %s is %v, expected %v", i, actual.Path, actual.Err, expected.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldError(t *testing.T) {
+	err := NewFieldError(VirtualCenterPath("10.0.0.1", "port"), "0", ErrInvalidPort)
+	if expected := `VirtualCenter["10.0.0.1"].port: Invalid port: "0"`; err.Error() != expected {
+		t.Errorf("Error is %q, expected %q", err.Error(), expected)
+	}
+	err = NewFieldError(GlobalPath("server"), "", ErrMissingVCenter)
+	if expected := `Global.server: No Virtual Center hosts defined`; err.Error() != expected {
+		t.Errorf("Error is %q, expected %q", err.Error(), expected)
+	}
+}
@@ -19,7 +19,9 @@ package connectionmanager
 import (
 	"context"
 	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 	listerv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog"
@@ -34,21 +36,28 @@ import (
 
 // NewConnectionManager returns a new ConnectionManager object
 // This function also initializes the Default/Global lister for secrets. In other words,
-// If a single global secret is used for all VCs, the informMgr param will be used to
-// obtain those secrets
+// If a single global secret is used for all VCs and informMgr is set, a Secret
+// informer scoped to the namespace of that secret is used to obtain it
 func NewConnectionManager(cfg *icscfg.Config, informMgr *k8s.InformerManager, client clientset.Interface) *ConnectionManager {
 	connMgr := &ConnectionManager{
-		client:             client,
-		IcsInstanceMap:     generateInstanceMap(cfg),
-		credentialManagers: make(map[string]*cm.CredentialManager),
-		informerManagers:   make(map[string]*k8s.InformerManager),
+		client:              client,
+		IcsInstanceMap:      generateInstanceMap(cfg),
+		credentialManagers:  make(map[string]cm.CredentialProvider),
+		informerManagers:    make(map[string]*k8s.InformerManager),
+		secretFieldSelector: cfg.Global.SecretFieldSelector,
 	}
+	connMgr.createCredentialProviders()
 
-	if informMgr != nil {
+	// Without a global secret there is nothing to watch, the iCenters use
+	// their own secrets, the secrets directory or the config credentials
+	if informMgr != nil && cfg.Global.SecretName != "" && cfg.Global.SecretNamespace != "" {
 		klog.V(2).Info("Initializing with K8s SecretLister")
-		credMgr := cm.NewCredentialManager(cfg.Global.SecretName, cfg.Global.SecretNamespace, "", informMgr.GetSecretLister())
+		credMgr, secretInformMgr := connMgr.createManagersPerTenant(cfg.Global.SecretName,
+			cfg.Global.SecretNamespace, "", client)
 		connMgr.credentialManagers[icscfg.DefaultCredentialManager] = credMgr
-		connMgr.informerManagers[icscfg.DefaultCredentialManager] = informMgr
+		if secretInformMgr != nil {
+			connMgr.informerManagers[icscfg.DefaultCredentialManager] = secretInformMgr
+		}
 
 		return connMgr
 	}
@@ -57,6 +66,7 @@ func NewConnectionManager(cfg *icscfg.Config, informMgr *k8s.InformerManager, cl
 		klog.V(2).Info("Initializing for generic CO with secrets")
 		credMgr, _ := connMgr.createManagersPerTenant("", "", cfg.Global.SecretsDirectory, nil)
 		connMgr.credentialManagers[icscfg.DefaultCredentialManager] = credMgr
+		connMgr.watchSecretsDirectory(icscfg.DefaultCredentialManager, credMgr)
 
 		return connMgr
 	}
@@ -80,7 +90,10 @@ func generateInstanceMap(cfg *icscfg.Config) map[string]*ICSInstance {
 				Password: vcConfig.Password,
 				Hostname: vcConfig.VCenterIP,
 				Port:     vcConfig.VCenterPort,
+				Insecure: vcConfig.InsecureFlag,
 			},
+			CACert:            vcConfig.CAFile,
+			Thumbprint:        vcConfig.Thumbprint,
 			RoundTripperCount: vcConfig.RoundTripperCount,
 		}
 		icsIns := ICSInstance{
@@ -93,32 +106,108 @@ func generateInstanceMap(cfg *icscfg.Config) map[string]*ICSInstance {
 	return icsInstanceMap
 }
 
+// Instances returns a snapshot of the iCenter instances, keyed by tenant ref.
+func (connMgr *ConnectionManager) Instances() map[string]*ICSInstance {
+	connMgr.instancesLock.RLock()
+	defer connMgr.instancesLock.RUnlock()
+
+	instances := make(map[string]*ICSInstance, len(connMgr.IcsInstanceMap))
+	for tenantRef, vcInstance := range connMgr.IcsInstanceMap {
+		instances[tenantRef] = vcInstance
+	}
+	return instances
+}
+
+// Instance returns the iCenter instance with the tenant ref, or nil if there
+// is none.
+func (connMgr *ConnectionManager) Instance(tenantRef string) *ICSInstance {
+	connMgr.instancesLock.RLock()
+	defer connMgr.instancesLock.RUnlock()
+	return connMgr.IcsInstanceMap[tenantRef]
+}
+
 // InitializeSecretLister initializes the individual secret listers that are NOT
 // handled through the Default/Global lister tied to the default service account.
 func (connMgr *ConnectionManager) InitializeSecretLister() {
+	connMgr.Lock()
+	defer connMgr.Unlock()
+	connMgr.initializeSecretListers()
+}
+
+// initializeSecretListers is InitializeSecretLister. The caller must hold the
+// lock.
+func (connMgr *ConnectionManager) initializeSecretListers() {
 	// For each vsi that has a Secret set createManagersPerTenant
-	for _, vInstance := range connMgr.IcsInstanceMap {
+	for _, vInstance := range connMgr.Instances() {
 		klog.V(3).Infof("Checking vcServer=%s SecretRef=%s", vInstance.Cfg.VCenterIP, vInstance.Cfg.SecretRef)
+		if vInstance.Cfg.SecretRef == "" {
+			klog.V(3).Infof("Skipping. iCenter %s is configured with credentials in the config.", vInstance.Cfg.VCenterIP)
+			continue
+		}
 		if strings.EqualFold(vInstance.Cfg.SecretRef, icscfg.DefaultCredentialManager) {
 			klog.V(3).Infof("Skipping. iCenter %s is configured using global service account/secret.", vInstance.Cfg.VCenterIP)
 			continue
 		}
+		if vInstance.Cfg.CredentialProvider != icscfg.CredentialProviderSecret {
+			klog.V(3).Infof("Skipping. iCenter %s uses the %s credential provider.", vInstance.Cfg.VCenterIP, vInstance.Cfg.CredentialProvider)
+			continue
+		}
+		if _, ok := connMgr.credentialManagers[vInstance.Cfg.SecretRef]; ok {
+			klog.V(3).Infof("Skipping. credMgr for %s already exists.", vInstance.Cfg.SecretRef)
+			continue
+		}
 
 		klog.V(3).Infof("Adding credMgr/informMgr for vcServer=%s", vInstance.Cfg.VCenterIP)
 		credsMgr, informMgr := connMgr.createManagersPerTenant(vInstance.Cfg.SecretName,
 			vInstance.Cfg.SecretNamespace, "", connMgr.client)
 		connMgr.credentialManagers[vInstance.Cfg.SecretRef] = credsMgr
-		connMgr.informerManagers[vInstance.Cfg.SecretRef] = informMgr
+		if informMgr != nil {
+			connMgr.informerManagers[vInstance.Cfg.SecretRef] = informMgr
+		}
+	}
+}
+
+// createCredentialProviders creates the exec and http credential providers
+// selected by the iCenters. They are keyed by the SecretRef of the iCenter.
+// Existing providers are kept. The caller must hold the lock, unless the
+// connection manager is not shared yet.
+func (connMgr *ConnectionManager) createCredentialProviders() {
+	for _, vInstance := range connMgr.Instances() {
+		vcConfig := vInstance.Cfg
+		if _, ok := connMgr.credentialManagers[vcConfig.SecretRef]; ok {
+			continue
+		}
+		switch vcConfig.CredentialProvider {
+		case icscfg.CredentialProviderExec:
+			klog.V(3).Infof("Adding exec credential provider for vcServer=%s", vcConfig.VCenterIP)
+			connMgr.credentialManagers[vcConfig.SecretRef] = cm.NewExecCredentialProvider(
+				vcConfig.CredentialExecCommand, vcConfig.CredentialExecArgs,
+				time.Duration(vcConfig.CredentialCacheTTL)*time.Second)
+		case icscfg.CredentialProviderHTTP:
+			klog.V(3).Infof("Adding http credential provider for vcServer=%s", vcConfig.VCenterIP)
+			connMgr.credentialManagers[vcConfig.SecretRef] = cm.NewHTTPCredentialProvider(
+				vcConfig.CredentialURL, vcConfig.CredentialTokenFile,
+				time.Duration(vcConfig.CredentialCacheTTL)*time.Second)
+		}
 	}
 }
 
+// createManagersPerTenant creates the CredentialManager of a secret. Secrets
+// read through the API get their own informer, scoped to the namespace of the
+// secret and, with secret-field-selector, to the secret itself. There is no
+// informer, and a nil InformerManager is returned, without client or without
+// the name and namespace of the secret.
 func (connMgr *ConnectionManager) createManagersPerTenant(secretName string, secretNamespace string,
 	secretsDirectory string, client clientset.Interface) (*cm.CredentialManager, *k8s.InformerManager) {
 
 	var informMgr *k8s.InformerManager
 	var lister listerv1.SecretLister
-	if client != nil && secretsDirectory == "" {
-		informMgr = k8s.NewInformer(client, true)
+	if client != nil && secretsDirectory == "" && secretName != "" && secretNamespace != "" {
+		selectorName := ""
+		if connMgr.secretFieldSelector {
+			selectorName = secretName
+		}
+		informMgr = k8s.NewSecretInformer(client, secretNamespace, selectorName)
 		lister = informMgr.GetSecretLister()
 	}
 
@@ -136,10 +225,27 @@ func (connMgr *ConnectionManager) createManagersPerTenant(secretName string, sec
 // 		1. It will fetch credentials from credentialManager
 //      2. Update the credentials
 //		3. Connects again to iCenter with fetched credentials
+// iCenters using the exec or http credential provider always connect with the
+// credentials currently returned by their provider.
 func (connMgr *ConnectionManager) Connect(ctx context.Context, vcInstance *ICSInstance) error {
 	connMgr.Lock()
 	defer connMgr.Unlock()
 
+	if vcInstance.Cfg.CredentialProvider == icscfg.CredentialProviderExec ||
+		vcInstance.Cfg.CredentialProvider == icscfg.CredentialProviderHTTP {
+		credMgr := connMgr.credentialManagers[vcInstance.Cfg.SecretRef]
+		if credMgr == nil {
+			klog.Errorf("Unable to find credential provider for vcServer=%s credentialHolder=%s", vcInstance.Cfg.VCenterIP, vcInstance.Cfg.SecretRef)
+			return ErrUnableToFindCredentialManager
+		}
+		credentials, err := credMgr.GetCredential(vcInstance.Cfg.VCenterIP)
+		if err != nil {
+			klog.Errorf("Failed to get credentials from %s credential provider with err: %v", vcInstance.Cfg.CredentialProvider, err)
+			return err
+		}
+		updateConnection(vcInstance, credentials)
+	}
+
 	err := vcInstance.Conn.Connect(ctx)
 	if err == nil {
 		return nil
@@ -158,19 +264,93 @@ func (connMgr *ConnectionManager) Connect(ctx context.Context, vcInstance *ICSIn
 		klog.Errorf("Unable to find credential manager for vcServer=%s credentialHolder=%s", vcInstance.Cfg.VCenterIP, vcInstance.Cfg.SecretRef)
 		return ErrUnableToFindCredentialManager
 	}
-	credentials, err := credMgr.GetCredential(vcInstance.Cfg.VCenterIP)
+	credentials, err := getCredential(credMgr, vcInstance)
 	if err != nil {
 		klog.Error("Failed to get credentials from Secret Credential Manager with err:", err)
 		return err
 	}
-	vcInstance.Conn.UpdateCredentials(credentials.User, credentials.Password)
+	updateConnection(vcInstance, credentials)
 	return vcInstance.Conn.Connect(ctx)
 }
 
+// watchSecretsDirectory hot-reloads the credentials of the credential manager
+// referenced by secretRef and reconnects the iCenters whose credentials rotate.
+func (connMgr *ConnectionManager) watchSecretsDirectory(secretRef string, credMgr *cm.CredentialManager) {
+	credMgr.AddCredentialsChangedHandler(func(servers []string) {
+		connMgr.credentialsChanged(secretRef, servers)
+	})
+	if err := credMgr.WatchSecretsDirectory(wait.NeverStop); err != nil {
+		klog.Warningf("Credentials in %s will not be reloaded. err=%v", credMgr.SecretsDirectory, err)
+	}
+}
+
+// credentialsChanged reconnects every iCenter using the credential manager
+// referenced by secretRef whose credentials were rotated or removed.
+func (connMgr *ConnectionManager) credentialsChanged(secretRef string, servers []string) {
+	for _, vcInstance := range connMgr.Instances() {
+		if !strings.EqualFold(vcInstance.Cfg.SecretRef, secretRef) {
+			continue
+		}
+		for _, server := range servers {
+			if !strings.EqualFold(vcInstance.Cfg.VCenterIP, server) && vcInstance.Cfg.TenantRef != server {
+				continue
+			}
+			if err := connMgr.reconnect(context.Background(), vcInstance); err != nil {
+				klog.Errorf("Failed to reconnect to iCenter %s after credential change. err=%v", vcInstance.Cfg.VCenterIP, err)
+			}
+			break
+		}
+	}
+}
+
+// reconnect replaces the session of the iCenter with one using the latest
+// credentials held by its credential manager. Requests in flight complete on
+// the previous session. An iCenter that was never connected only gets its
+// credentials updated.
+func (connMgr *ConnectionManager) reconnect(ctx context.Context, vcInstance *ICSInstance) error {
+	connMgr.Lock()
+	defer connMgr.Unlock()
+
+	credMgr := connMgr.credentialManagers[vcInstance.Cfg.SecretRef]
+	if credMgr == nil {
+		klog.Errorf("Unable to find credential manager for vcServer=%s credentialHolder=%s", vcInstance.Cfg.VCenterIP, vcInstance.Cfg.SecretRef)
+		return ErrUnableToFindCredentialManager
+	}
+
+	credentials, err := getCredential(credMgr, vcInstance)
+	if err != nil {
+		klog.Warningf("Credentials for vcServer=%s are no longer available", vcInstance.Cfg.VCenterIP)
+		return err
+	}
+	updateConnection(vcInstance, credentials)
+
+	klog.V(2).Infof("Reconnecting to vcServer=%s with rotated credentials", vcInstance.Cfg.VCenterIP)
+	return vcInstance.Conn.Reconnect(ctx)
+}
+
+// getCredential returns the credentials of the iCenter. Secrets may hold them
+// under the tenant ref of the iCenter or under its address.
+func getCredential(credMgr cm.CredentialProvider, vcInstance *ICSInstance) (*cm.Credential, error) {
+	if _, ok := credMgr.(*cm.CredentialManager); ok && vcInstance.Cfg.TenantRef != vcInstance.Cfg.VCenterIP {
+		if credentials, err := credMgr.GetCredential(vcInstance.Cfg.TenantRef); err == nil {
+			return credentials, nil
+		}
+	}
+	return credMgr.GetCredential(vcInstance.Cfg.VCenterIP)
+}
+
+// updateConnection updates the credentials of the iCenter connection and
+// applies the server, port and CA overrides they carry.
+func updateConnection(vcInstance *ICSInstance, credentials *cm.Credential) {
+	vcInstance.Conn.UpdateCredentials(credentials.User, credentials.Password)
+	vcInstance.Conn.UpdateToken(credentials.Token)
+	vcInstance.Conn.UpdateEndpoint(credentials.Server, credentials.Port, credentials.CACert)
+}
+
 // Logout closes existing connections to remote iCenter endpoints.
 func (connMgr *ConnectionManager) Logout() {
 //ics block
-	for _, icsIns := range connMgr.IcsInstanceMap {
+	for _, icsIns := range connMgr.Instances() {
 		connMgr.Lock()
 		c := icsIns.Conn.Client
 		connMgr.Unlock()
@@ -184,7 +364,7 @@ func (connMgr *ConnectionManager) Logout() {
 // Verify validates the configuration by attempting to connect to the
 // configured, remote iCenter endpoints.
 func (connMgr *ConnectionManager) Verify() error {
-	for _, vcInstance := range connMgr.IcsInstanceMap {
+	for _, vcInstance := range connMgr.Instances() {
 		err := connMgr.Connect(context.Background(), vcInstance)
 		if err == nil {
 			klog.V(3).Infof("iCenter connect %s succeeded.", vcInstance.Cfg.VCenterIP)
@@ -199,7 +379,7 @@ func (connMgr *ConnectionManager) Verify() error {
 // VerifyWithContext is the same as Verify but allows a Go Context
 // to control the lifecycle of the connection event.
 func (connMgr *ConnectionManager) VerifyWithContext(ctx context.Context) error {
-	for _, vcInstance := range connMgr.IcsInstanceMap {
+	for _, vcInstance := range connMgr.Instances() {
 		err := connMgr.Connect(ctx, vcInstance)
 		if err == nil {
 			klog.V(3).Infof("iCenter connect %s succeeded.", vcInstance.Cfg.VCenterIP)
@@ -0,0 +1,203 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+	cm "github.com/inspur-ics/cloud-provider-ics/pkg/common/credentialmanager"
+	icslib "github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// fakeICenter accepts every login and returns the password as session token.
+type fakeICenter struct {
+	server *httptest.Server
+	host   string
+	port   string
+	logins chan tp.Login
+}
+
+func newFakeICenter(t *testing.T) *fakeICenter {
+	f := &fakeICenter{logins: make(chan tp.Login, 100)}
+	f.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/authentication") {
+			var login tp.Login
+			if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.logins <- login
+			json.NewEncoder(w).Encode(tp.LoginResponse{UserId: login.Username, SessonId: "session-" + login.Password})
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+
+	var err error
+	f.host, f.port, err = net.SplitHostPort(strings.TrimPrefix(f.server.URL, "https://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// instance returns an iCenter instance of the fake iCenter whose credentials
+// are held by the credential manager secretRef.
+func (f *fakeICenter) instance(secretRef string) *ICSInstance {
+	conn := &icslib.ICSConnection{}
+	conn.Hostname = f.host
+	conn.Port = f.port
+	conn.Insecure = true
+	return &ICSInstance{
+		Conn: conn,
+		Cfg: &icscfg.VirtualCenterConfig{
+			TenantRef:   f.host,
+			VCenterIP:   f.host,
+			VCenterPort: f.port,
+			SecretRef:   secretRef,
+		},
+	}
+}
+
+func TestCredentialsChangedReconnects(t *testing.T) {
+	icenter := newFakeICenter(t)
+	defer icenter.server.Close()
+
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeSecret := func(key string, value string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, key), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSecret(icenter.host+".username", "admin")
+	writeSecret(icenter.host+".password", "old")
+
+	credMgr := cm.NewCredentialManager("", "", dir, nil)
+	vcInstance := icenter.instance("secrets")
+	connMgr := &ConnectionManager{
+		IcsInstanceMap:     map[string]*ICSInstance{icenter.host: vcInstance},
+		credentialManagers: map[string]cm.CredentialProvider{"secrets": credMgr},
+	}
+
+	credentials, err := getCredential(credMgr, vcInstance)
+	if err != nil {
+		t.Fatalf("getCredential() failed: %v", err)
+	}
+	updateConnection(vcInstance, credentials)
+	ctx := context.Background()
+	if err := connMgr.Connect(ctx, vcInstance); err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	oldClient, err := vcInstance.Conn.GetClient(ctx)
+	if err != nil {
+		t.Fatalf("GetClient() failed: %v", err)
+	}
+	if login := <-icenter.logins; login.Password != "old" {
+		t.Fatalf("first login password = %q, want old", login.Password)
+	}
+
+	connMgr.watchSecretsDirectory("secrets", credMgr)
+	writeSecret(icenter.host+".password", "new")
+
+	select {
+	case login := <-icenter.logins:
+		if login.Username != "admin" || login.Password != "new" {
+			t.Fatalf("login after rotation = %+v, want admin/new", login)
+		}
+	case <-time.After(3 * cm.SecretsDirectoryResyncDelay):
+		t.Fatal("no login with the rotated credentials")
+	}
+
+	// The client is swapped once the new session is established
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client, err := vcInstance.Conn.GetClient(ctx)
+		if err != nil {
+			t.Fatalf("GetClient() failed: %v", err)
+		}
+		if client != oldClient {
+			if client.GetToken() != "session-new" {
+				t.Errorf("new client token = %q, want session-new", client.GetToken())
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client not replaced after the credentials rotated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Requests in flight keep a usable client
+	if oldClient.GetToken() != "session-old" {
+		t.Errorf("old client token = %q, want session-old", oldClient.GetToken())
+	}
+}
+
+func TestReconnectAppliesEndpointOverride(t *testing.T) {
+	icenter := newFakeICenter(t)
+	defer icenter.server.Close()
+
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Only the port is overridden, the address comes from the config
+	secret := "prod:\n  user: admin\n  password: secret\n  port: " + icenter.port + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "credentials.yaml"), []byte(secret), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	vcInstance := icenter.instance("secrets")
+	vcInstance.Cfg.TenantRef = "prod"
+	vcInstance.Conn.Port = "1"
+	connMgr := &ConnectionManager{
+		IcsInstanceMap:     map[string]*ICSInstance{"prod": vcInstance},
+		credentialManagers: map[string]cm.CredentialProvider{"secrets": cm.NewCredentialManager("", "", dir, nil)},
+	}
+
+	ctx := context.Background()
+	if err := connMgr.reconnect(ctx, vcInstance); err != nil {
+		t.Fatalf("reconnect() failed: %v", err)
+	}
+	if vcInstance.Conn.Hostname != icenter.host || vcInstance.Conn.Port != icenter.port {
+		t.Errorf("endpoint = %s:%s, want %s:%s", vcInstance.Conn.Hostname, vcInstance.Conn.Port, icenter.host, icenter.port)
+	}
+	if err := connMgr.Connect(ctx, vcInstance); err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	if login := <-icenter.logins; login.Username != "admin" || login.Password != "secret" {
+		t.Errorf("login = %+v, want admin/secret", login)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+
+	icslib "github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+const (
+	// DefaultEventInterval is the period between two reads of the event feed
+	// of an iCenter.
+	DefaultEventInterval = 5 * time.Second
+
+	// maxEventBackoff is the longest wait before reading again the event feed
+	// of an iCenter that failed.
+	maxEventBackoff = 5 * time.Minute
+)
+
+// EventSource is the task feed of an iCenter.
+type EventSource interface {
+	// Events returns the events at or after since, oldest first. A zero
+	// since returns the most recent events only.
+	Events(ctx context.Context, since time.Time) ([]icslib.Event, error)
+}
+
+// EventSourceFunc is a function used as an EventSource.
+type EventSourceFunc func(ctx context.Context, since time.Time) ([]icslib.Event, error)
+
+// Events calls f.
+func (f EventSourceFunc) Events(ctx context.Context, since time.Time) ([]icslib.Event, error) {
+	return f(ctx, since)
+}
+
+// EventHandler is called with each event of the iCenter with tenantRef.
+type EventHandler func(tenantRef string, event icslib.Event)
+
+// eventCursor is the position of an EventSubscriber in the feed of an
+// iCenter: the time of the last event handled and the keys of the events
+// handled at that time, which may be returned again. Times are the ones of
+// the iCenter, whose clock may differ from the local one. A zero time is a
+// feed not read yet.
+type eventCursor struct {
+	time    time.Time
+	handled map[string]bool
+	// backoff after failures, and when the next read is due
+	backoff time.Duration
+	next    time.Time
+}
+
+// EventSubscriber follows the event feeds of iCenters and calls its handler
+// with each new event, in order. Every event is handled once: after an error
+// or a reconnection, the feed of an iCenter is resumed from the last event
+// handled. Feeds are followed from their most recent event when they are
+// first read, which is not handled.
+type EventSubscriber struct {
+	sources  func() map[string]EventSource
+	handler  EventHandler
+	interval time.Duration
+	now      func() time.Time
+	cursors  map[string]*eventCursor
+}
+
+// NewEventSubscriber returns a subscriber reading the feeds returned by
+// sources, by tenant ref, every interval.
+func NewEventSubscriber(sources func() map[string]EventSource, handler EventHandler, interval time.Duration) *EventSubscriber {
+	return &EventSubscriber{
+		sources:  sources,
+		handler:  handler,
+		interval: interval,
+		now:      time.Now,
+		cursors:  make(map[string]*eventCursor),
+	}
+}
+
+// Run reads the feeds until stop is closed.
+func (s *EventSubscriber) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Poll(context.Background())
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads each feed once, except the ones backing off after a failure,
+// and handles their new events.
+func (s *EventSubscriber) Poll(ctx context.Context) {
+	sources := s.sources()
+	tenantRefs := make([]string, 0, len(sources))
+	for tenantRef := range sources {
+		tenantRefs = append(tenantRefs, tenantRef)
+	}
+	sort.Strings(tenantRefs)
+
+	now := s.now()
+	for tenantRef := range s.cursors {
+		if _, ok := sources[tenantRef]; !ok {
+			klog.V(2).Infof("No longer following the events of iCenter %s", tenantRef)
+			delete(s.cursors, tenantRef)
+		}
+	}
+
+	for _, tenantRef := range tenantRefs {
+		cursor := s.cursors[tenantRef]
+		if cursor == nil {
+			klog.V(2).Infof("Following the events of iCenter %s", tenantRef)
+			cursor = &eventCursor{handled: make(map[string]bool)}
+			s.cursors[tenantRef] = cursor
+		}
+		if now.Before(cursor.next) {
+			continue
+		}
+
+		events, err := sources[tenantRef].Events(ctx, cursor.time)
+		if err != nil {
+			cursor.backoff *= 2
+			if cursor.backoff == 0 {
+				cursor.backoff = s.interval
+			}
+			if cursor.backoff > maxEventBackoff {
+				cursor.backoff = maxEventBackoff
+			}
+			cursor.next = now.Add(cursor.backoff)
+			klog.Warningf("Failed to read the events of iCenter %s, resuming in %v from %v. err=%v",
+				tenantRef, cursor.backoff, cursor.time, err)
+			continue
+		}
+		cursor.backoff = 0
+		cursor.next = time.Time{}
+
+		if cursor.time.IsZero() {
+			// Start after the most recent event, in the time of the iCenter
+			for _, event := range events {
+				if event.Time.After(cursor.time) {
+					cursor.time = event.Time
+					cursor.handled = make(map[string]bool)
+				}
+				if event.Time.Equal(cursor.time) {
+					cursor.handled[eventKey(event)] = true
+				}
+			}
+			continue
+		}
+
+		for _, event := range events {
+			key := eventKey(event)
+			if event.Time.Before(cursor.time) || cursor.handled[key] {
+				continue
+			}
+			if event.Time.After(cursor.time) {
+				cursor.time = event.Time
+				cursor.handled = make(map[string]bool)
+			}
+			cursor.handled[key] = true
+
+			klog.V(4).Infof("Event %s of iCenter %s: %s (%s) on %s %s",
+				event.ID, tenantRef, event.Type, event.RawType, event.TargetType, event.TargetID)
+			s.handler(tenantRef, event)
+		}
+	}
+}
+
+// eventKey returns the key of an event among the events handled at its time:
+// its ID or, for an event without an ID, its type and target, so that such
+// events are not mistaken for one another.
+func eventKey(event icslib.Event) string {
+	if event.ID != "" {
+		return event.ID
+	}
+	return strings.Join([]string{"", event.RawType, event.TargetType, event.TargetID}, "\x00")
+}
+
+// connectionEventSource reads the event feed of an iCenter through its
+// connection, reconnecting after a failure.
+type connectionEventSource struct {
+	connMgr    *ConnectionManager
+	vcInstance *ICSInstance
+	failed     bool
+}
+
+// Events returns the events of the iCenter at or after since.
+func (source *connectionEventSource) Events(ctx context.Context, since time.Time) ([]icslib.Event, error) {
+	if source.failed || source.vcInstance.Conn.Client == nil {
+		if err := source.connMgr.Connect(ctx, source.vcInstance); err != nil {
+			return nil, err
+		}
+	}
+
+	events, err := icslib.GetEvents(ctx, source.vcInstance.Conn, since)
+	source.failed = err != nil
+	return events, err
+}
+
+// SubscribeEvents calls handler with the events of the iCenters, until stop
+// is closed. The iCenters added or removed by a config reload are followed
+// or dropped on the next read.
+func (connMgr *ConnectionManager) SubscribeEvents(handler EventHandler, stop <-chan struct{}) {
+	sources := make(map[*ICSInstance]*connectionEventSource)
+	subscriber := NewEventSubscriber(func() map[string]EventSource {
+		vcInstances := connMgr.Instances()
+		current := make(map[string]EventSource, len(vcInstances))
+		instances := make(map[*ICSInstance]bool, len(vcInstances))
+		for tenantRef, vcInstance := range vcInstances {
+			source := sources[vcInstance]
+			if source == nil {
+				source = &connectionEventSource{connMgr: connMgr, vcInstance: vcInstance}
+				sources[vcInstance] = source
+			}
+			current[tenantRef] = source
+			instances[vcInstance] = true
+		}
+		for vcInstance := range sources {
+			if !instances[vcInstance] {
+				delete(sources, vcInstance)
+			}
+		}
+		return current
+	}, handler, DefaultEventInterval)
+
+	subscriber.Run(stop)
+}
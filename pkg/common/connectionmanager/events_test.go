@@ -0,0 +1,197 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	icslib "github.com/inspur-ics/cloud-provider-ics/pkg/common/icslib"
+)
+
+// fakeFeed is the event feed of an iCenter, read through an EventSourceFunc.
+type fakeFeed struct {
+	events []icslib.Event
+	err    error
+	// since of each read
+	reads []time.Time
+}
+
+func (f *fakeFeed) source() EventSource {
+	return EventSourceFunc(func(ctx context.Context, since time.Time) ([]icslib.Event, error) {
+		f.reads = append(f.reads, since)
+		if f.err != nil {
+			return nil, f.err
+		}
+		var events []icslib.Event
+		for _, event := range f.events {
+			if !event.Time.Before(since) {
+				events = append(events, event)
+			}
+		}
+		return events, nil
+	})
+}
+
+func TestEventSubscriberPoll(t *testing.T) {
+	// The clock of the iCenter is an hour behind
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(id string, offset time.Duration) icslib.Event {
+		return icslib.Event{ID: id, Type: icslib.EventOther, Time: base.Add(offset)}
+	}
+	// Events without an ID are named by their target
+	vmEvent := func(vmID string, offset time.Duration) icslib.Event {
+		return icslib.Event{RawType: "VM_POWER_ON", Type: icslib.EventVMPoweredOn,
+			TargetType: icslib.EventTargetVM, TargetID: vmID, Time: base.Add(offset)}
+	}
+
+	tests := []struct {
+		name string
+		// Events of the feed before the first poll
+		initial []icslib.Event
+		// Events added after the first poll
+		added []icslib.Event
+		want  []string
+	}{
+		{
+			name:    "history is not handled",
+			initial: []icslib.Event{event("1", 0), event("2", time.Second)},
+			added:   []icslib.Event{event("3", 2*time.Second)},
+			want:    []string{"3"},
+		},
+		{
+			name:    "events at the time of the cursor are handled once",
+			initial: []icslib.Event{event("1", time.Second)},
+			added:   []icslib.Event{event("2", time.Second), event("3", 2*time.Second)},
+			want:    []string{"2", "3"},
+		},
+		{
+			name:    "older events are skipped",
+			initial: []icslib.Event{event("1", time.Second)},
+			added:   []icslib.Event{event("2", 0), event("3", 2*time.Second)},
+			want:    []string{"3"},
+		},
+		{
+			name:    "events without an ID at the same time are told apart",
+			initial: []icslib.Event{vmEvent("vm-1", time.Second)},
+			added:   []icslib.Event{vmEvent("vm-2", time.Second), vmEvent("vm-3", time.Second)},
+			want:    []string{"vm-2", "vm-3"},
+		},
+		{
+			name:  "empty feed starts at its first event",
+			added: []icslib.Event{event("1", time.Second)},
+			want:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed := &fakeFeed{events: test.initial}
+			var handled []string
+			s := NewEventSubscriber(func() map[string]EventSource {
+				return map[string]EventSource{"vc": feed.source()}
+			}, func(tenantRef string, event icslib.Event) {
+				handled = append(handled, event.ID+event.TargetID)
+			}, time.Second)
+			s.now = func() time.Time { return base.Add(time.Hour) }
+
+			s.Poll(context.Background())
+			feed.events = append(feed.events, test.added...)
+			s.Poll(context.Background())
+			// Nothing is handled twice
+			s.Poll(context.Background())
+
+			if !reflect.DeepEqual(handled, test.want) {
+				t.Errorf("handled %v, want %v", handled, test.want)
+			}
+			if !feed.reads[0].IsZero() {
+				t.Errorf("first read since %v, want the most recent events", feed.reads[0])
+			}
+		})
+	}
+}
+
+func TestEventSubscriberPollBackoff(t *testing.T) {
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := base
+	feed := &fakeFeed{events: []icslib.Event{{ID: "1", Time: base}}}
+	var handled []string
+	s := NewEventSubscriber(func() map[string]EventSource {
+		return map[string]EventSource{"vc": feed.source()}
+	}, func(tenantRef string, event icslib.Event) {
+		handled = append(handled, event.ID)
+	}, time.Second)
+	s.now = func() time.Time { return now }
+
+	s.Poll(context.Background())
+
+	feed.err = errors.New("connection refused")
+	feed.events = append(feed.events, icslib.Event{ID: "2", Time: base.Add(time.Second)})
+	s.Poll(context.Background())
+	if len(feed.reads) != 2 {
+		t.Fatalf("read %d times, want 2", len(feed.reads))
+	}
+
+	// Backing off
+	now = now.Add(500 * time.Millisecond)
+	s.Poll(context.Background())
+	if len(feed.reads) != 2 {
+		t.Fatalf("read %d times while backing off, want 2", len(feed.reads))
+	}
+
+	feed.err = nil
+	now = now.Add(time.Second)
+	s.Poll(context.Background())
+	if len(feed.reads) != 3 {
+		t.Fatalf("read %d times after the backoff, want 3", len(feed.reads))
+	}
+	if !feed.reads[2].Equal(base) {
+		t.Errorf("resumed since %v, want %v", feed.reads[2], base)
+	}
+	if !reflect.DeepEqual(handled, []string{"2"}) {
+		t.Errorf("handled %v, want [2]", handled)
+	}
+}
+
+func TestEventSubscriberPollRemovedSource(t *testing.T) {
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	feeds := map[string]*fakeFeed{
+		"vc1": {events: []icslib.Event{{ID: "1", Time: base}}},
+		"vc2": {events: []icslib.Event{{ID: "1", Time: base}}},
+	}
+	s := NewEventSubscriber(func() map[string]EventSource {
+		sources := make(map[string]EventSource)
+		for tenantRef, feed := range feeds {
+			sources[tenantRef] = feed.source()
+		}
+		return sources
+	}, func(string, icslib.Event) {}, time.Second)
+
+	s.Poll(context.Background())
+	delete(feeds, "vc2")
+	s.Poll(context.Background())
+
+	if _, ok := s.cursors["vc2"]; ok {
+		t.Error("cursor of a removed iCenter is kept")
+	}
+	if _, ok := s.cursors["vc1"]; !ok {
+		t.Error("cursor of a kept iCenter is dropped")
+	}
+}
@@ -34,7 +34,7 @@ func (cm *ConnectionManager) ListAllVCandDCPairs(ctx context.Context) ([]*ListDi
 	listOfVCAndDCPairs := make([]*ListDiscoveryInfo, 0)
 
 //ics
-	for _, vsi := range cm.IcsInstanceMap {
+	for _, vsi := range cm.Instances() {
 		var datacenterObjs []*icslib.Datacenter
 //ics
 		var err error
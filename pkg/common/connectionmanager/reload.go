@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/klog"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+)
+
+// UpdateConfig applies a new, validated, configuration. iCenters whose
+// settings did not change keep their ICSInstance and session. Added iCenters
+// get credential managers, removed ones are logged out and their credential
+// managers dropped. It returns the tenant refs of the iCenters that were
+// added, changed or removed.
+func (connMgr *ConnectionManager) UpdateConfig(cfg *icscfg.Config) []string {
+	newInstanceMap := generateInstanceMap(cfg)
+	var changed []string
+	var stale []*ICSInstance
+
+	connMgr.Lock()
+	for tenantRef, oldInstance := range connMgr.IcsInstanceMap {
+		newInstance, ok := newInstanceMap[tenantRef]
+		if !ok {
+			klog.V(2).Infof("iCenter %s was removed from the config", tenantRef)
+			stale = append(stale, oldInstance)
+			changed = append(changed, tenantRef)
+			continue
+		}
+		if reflect.DeepEqual(oldInstance.Cfg, newInstance.Cfg) {
+			newInstanceMap[tenantRef] = oldInstance
+			continue
+		}
+		klog.V(2).Infof("iCenter %s changed in the config", tenantRef)
+		stale = append(stale, oldInstance)
+		changed = append(changed, tenantRef)
+	}
+	for tenantRef := range newInstanceMap {
+		if _, ok := connMgr.IcsInstanceMap[tenantRef]; !ok {
+			klog.V(2).Infof("iCenter %s was added to the config", tenantRef)
+			changed = append(changed, tenantRef)
+		}
+	}
+	connMgr.instancesLock.Lock()
+	connMgr.IcsInstanceMap = newInstanceMap
+	connMgr.instancesLock.Unlock()
+	connMgr.removeStaleCredentialManagers(stale)
+	connMgr.createCredentialProviders()
+	connMgr.initializeSecretListers()
+	connMgr.Unlock()
+
+	for _, vcInstance := range stale {
+		if vcInstance.Conn.Client != nil {
+			vcInstance.Conn.Logout(context.Background())
+		}
+	}
+
+	return changed
+}
+
+// removeStaleCredentialManagers drops the credential managers that are no
+// longer referenced, and those of the exec and http providers of changed
+// iCenters so they are recreated with the new settings. The global credential
+// manager is always kept. The caller must hold the lock.
+func (connMgr *ConnectionManager) removeStaleCredentialManagers(stale []*ICSInstance) {
+	referenced := make(map[string]bool)
+	for _, vcInstance := range connMgr.IcsInstanceMap {
+		referenced[vcInstance.Cfg.SecretRef] = true
+	}
+	for _, vcInstance := range stale {
+		secretRef := vcInstance.Cfg.SecretRef
+		if secretRef == icscfg.DefaultCredentialManager {
+			continue
+		}
+		if referenced[secretRef] && vcInstance.Cfg.CredentialProvider == icscfg.CredentialProviderSecret {
+			continue
+		}
+		klog.V(3).Infof("Removing credMgr/informMgr for %s", secretRef)
+		delete(connMgr.credentialManagers, secretRef)
+		if informMgr := connMgr.informerManagers[secretRef]; informMgr != nil {
+			informMgr.Stop()
+		}
+		delete(connMgr.informerManagers, secretRef)
+	}
+}
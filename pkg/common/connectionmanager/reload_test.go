@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	icscfg "github.com/inspur-ics/cloud-provider-ics/pkg/common/config"
+)
+
+const testReloadConfig = `
+[Global]
+user = "admin"
+password = "secret"
+[VirtualCenter "10.0.0.1"]
+[VirtualCenter "10.0.0.2"]
+secret-name = "vc2"
+secret-namespace = "ics"
+`
+
+func readTestConfig(t *testing.T, config string) *icscfg.Config {
+	cfg, err := icscfg.ReadConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Reading the config failed: %v", err)
+	}
+	return cfg
+}
+
+func TestUpdateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		// config replacing testReloadConfig
+		config string
+		// tenant refs reported as changed
+		changed []string
+		// tenant refs that keep their ICSInstance
+		kept []string
+		// credential managers after the reload
+		credentialManagers []string
+	}{
+		{
+			name:               "unchanged",
+			config:             testReloadConfig,
+			kept:               []string{"10.0.0.1", "10.0.0.2"},
+			credentialManagers: []string{icscfg.DefaultCredentialManager, "ics/vc2"},
+		},
+		{
+			name:               "added iCenter",
+			config:             testReloadConfig + "[VirtualCenter \"10.0.0.3\"]\nsecret-name = \"vc3\"\nsecret-namespace = \"ics\"\n",
+			changed:            []string{"10.0.0.3"},
+			kept:               []string{"10.0.0.1", "10.0.0.2"},
+			credentialManagers: []string{icscfg.DefaultCredentialManager, "ics/vc2", "ics/vc3"},
+		},
+		{
+			name:               "removed iCenter",
+			config:             "[Global]\nuser = \"admin\"\npassword = \"secret\"\n[VirtualCenter \"10.0.0.1\"]\n",
+			changed:            []string{"10.0.0.2"},
+			kept:               []string{"10.0.0.1"},
+			credentialManagers: []string{icscfg.DefaultCredentialManager},
+		},
+		{
+			name:               "changed iCenter",
+			config:             strings.Replace(testReloadConfig, "[VirtualCenter \"10.0.0.1\"]", "[VirtualCenter \"10.0.0.1\"]\nport = \"8443\"", 1),
+			changed:            []string{"10.0.0.1"},
+			kept:               []string{"10.0.0.2"},
+			credentialManagers: []string{icscfg.DefaultCredentialManager, "ics/vc2"},
+		},
+		{
+			name:               "changed secret",
+			config:             strings.Replace(testReloadConfig, "\"vc2\"", "\"other\"", 1),
+			changed:            []string{"10.0.0.2"},
+			kept:               []string{"10.0.0.1"},
+			credentialManagers: []string{icscfg.DefaultCredentialManager, "ics/other"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connMgr := NewConnectionManager(readTestConfig(t, testReloadConfig), nil, nil)
+			connMgr.InitializeSecretLister()
+			before := connMgr.Instances()
+
+			cfg := readTestConfig(t, test.config)
+			changed := connMgr.UpdateConfig(cfg)
+			sort.Strings(changed)
+			if !reflect.DeepEqual(changed, test.changed) {
+				t.Errorf("Changed iCenters are %v, expected %v", changed, test.changed)
+			}
+
+			after := connMgr.Instances()
+			if len(after) != len(cfg.VirtualCenter) {
+				t.Errorf("There are %d iCenters, expected %d", len(after), len(cfg.VirtualCenter))
+			}
+			for tenantRef, vcConfig := range cfg.VirtualCenter {
+				vcInstance := connMgr.Instance(tenantRef)
+				if vcInstance == nil {
+					t.Errorf("iCenter %s is missing", tenantRef)
+					continue
+				}
+				if !reflect.DeepEqual(vcInstance.Cfg, vcConfig) {
+					t.Errorf("iCenter %s has config %+v, expected %+v", tenantRef, vcInstance.Cfg, vcConfig)
+				}
+				if vcInstance.Conn.Port != vcConfig.VCenterPort {
+					t.Errorf("iCenter %s connects to port %s, expected %s", tenantRef, vcInstance.Conn.Port, vcConfig.VCenterPort)
+				}
+			}
+			for _, tenantRef := range test.kept {
+				if after[tenantRef] != before[tenantRef] {
+					t.Errorf("iCenter %s did not keep its instance", tenantRef)
+				}
+			}
+			for _, tenantRef := range test.changed {
+				if before[tenantRef] != nil && after[tenantRef] == before[tenantRef] {
+					t.Errorf("iCenter %s kept its instance", tenantRef)
+				}
+			}
+
+			var credentialManagers []string
+			for secretRef := range connMgr.credentialManagers {
+				credentialManagers = append(credentialManagers, secretRef)
+			}
+			sort.Strings(credentialManagers)
+			if !reflect.DeepEqual(credentialManagers, test.credentialManagers) {
+				t.Errorf("Credential managers are %v, expected %v", credentialManagers, test.credentialManagers)
+			}
+		})
+	}
+}
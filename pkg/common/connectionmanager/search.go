@@ -100,7 +100,7 @@ func (cm *ConnectionManager) WhichVCandDCByNodeID(ctx context.Context, nodeID st
 
 	go func() {
 //ics	
-		for _, vsi := range cm.IcsInstanceMap {
+		for _, vsi := range cm.Instances() {
 //ics
 			var datacenterObjs []*icslib.Datacenter
 //ics
@@ -231,7 +231,6 @@ func (cm *ConnectionManager) WhichVCandDCByNodeID(ctx context.Context, nodeID st
 	return nil, icslib.ErrNoVMFound
 }
 
-/*
 // WhichVCandDCByFCDId searches for an FCD using the provided ID.
 func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID string) (*FcdDiscoveryInfo, error) {
 	if fcdID == "" {
@@ -244,6 +243,7 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 		tenantRef  string
 		vc         string
 		datacenter *icslib.Datacenter
+		fcd        *icslib.FirstClassDiskInfo
 	}
 
 	var mutex = &sync.Mutex{}
@@ -278,7 +278,7 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 
 //ics
 	go func() {
-		for _, vsi := range cm.IcsInstanceMap {
+		for _, vsi := range cm.Instances() {
 			var datacenterObjs []*icslib.Datacenter
 
 			if getFCDFound() {
@@ -300,6 +300,17 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 				continue
 			}
 
+			fcd, err := icslib.GetFirstClassDisk(ctx, vsi.Conn, fcdID)
+			if err != nil {
+				if err != icslib.ErrNoDiskIDFound {
+					klog.Errorf("Error while looking for FCD %s in vc=%s: %v", fcdID, vsi.Cfg.VCenterIP, err)
+					setGlobalErr(err)
+				} else {
+					klog.V(2).Infof("Did not find FCD %s in vc=%s", fcdID, vsi.Cfg.VCenterIP)
+				}
+				continue
+			}
+
 			if vsi.Cfg.Datacenters == "" {
 				datacenterObjs, err = icslib.GetAllDatacenter(ctx, vsi.Conn)
 				if err != nil {
@@ -334,6 +345,7 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 					tenantRef:  vsi.Cfg.TenantRef,
 					vc:         vsi.Cfg.VCenterIP,
 					datacenter: datacenterObj,
+					fcd:        fcd,
 				}
 			}
 		}
@@ -346,23 +358,16 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 		go func() {
 			for res := range queueChannel {
 
-				fcd, err := res.datacenter.DoesFirstClassDiskExist(ctx, fcdID)
-				if err != nil {
-					klog.Errorf("Error while looking for FCD=%+v in vc=%s and datacenter=%s: %v",
-						fcd, res.vc, res.datacenter.Name(), err)
-					if err != icslib.ErrNoDiskIDFound {
-						setGlobalErr(err)
-					} else {
-						klog.V(2).Infof("Did not find FCD %s in vc=%s and datacenter=%s",
-							fcdID, res.vc, res.datacenter.Name())
-					}
+				if !res.datacenter.HoldsFirstClassDisk(res.fcd) {
+					klog.V(2).Infof("Did not find FCD %s in vc=%s and datacenter=%s",
+						fcdID, res.vc, res.datacenter.Name())
 					continue
 				}
 
 				klog.V(2).Infof("Found FCD %s as vm=%+v in vc=%s and datacenter=%s",
-					fcdID, fcd, res.vc, res.datacenter.Name())
+					fcdID, res.fcd, res.vc, res.datacenter.Name())
 
-				fcdInfo = &FcdDiscoveryInfo{TenantRef: res.tenantRef, DataCenter: res.datacenter, FCDInfo: fcd, VcServer: res.vc}
+				fcdInfo = &FcdDiscoveryInfo{TenantRef: res.tenantRef, DataCenter: res.datacenter, FCDInfo: res.fcd, VcServer: res.vc}
 				setFCDFound(true)
 				break
 			}
@@ -381,4 +386,4 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 	klog.V(4).Infof("WhichVCandDCByFCDId: %q FCD not found", fcdID)
 	return nil, icslib.ErrNoDiskIDFound
 }
-*/
+
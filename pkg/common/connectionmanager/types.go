@@ -33,14 +33,18 @@ type ConnectionManager struct {
 	// The k8s client init from the cloud provider service account
 	client clientset.Interface
 
-	// Maps the VC server to ICSInstance
+	// Maps the VC server to ICSInstance. The map is replaced on config
+	// reload under instancesLock, read it through Instances or Instance.
 	IcsInstanceMap map[string]*ICSInstance
+	instancesLock  sync.RWMutex
 	// CredentialManager per VC
 	// The global CredentialManager will have an entry in this map with the key of "Global"
-	credentialManagers map[string]*cm.CredentialManager
+	credentialManagers map[string]cm.CredentialProvider
 	// InformerManagers per VC
 	// The global InformerManager will have an entry in this map with the key of "Global"
 	informerManagers map[string]*k8s.InformerManager
+	// Restrict secret informers to the configured secret names
+	secretFieldSelector bool
 }
 
 // ICSInstance represents a ics instance where one or more kubernetes nodes are running.
@@ -59,7 +63,6 @@ type VMDiscoveryInfo struct {
 	NodeName   string
 }
 
-/*
 // FcdDiscoveryInfo contains FCD info about a discovered FCD
 type FcdDiscoveryInfo struct {
 	TenantRef  string
@@ -67,7 +70,6 @@ type FcdDiscoveryInfo struct {
 	FCDInfo    *icslib.FirstClassDiskInfo
 	VcServer   string
 }
-*/
 
 // ListDiscoveryInfo represents a VC/DC pair
 type ListDiscoveryInfo struct {
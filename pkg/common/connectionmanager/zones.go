@@ -54,7 +54,7 @@ func (cm *ConnectionManager) WhichVCandDCByZone(ctx context.Context,
 	klog.V(4).Infof("WhichVCandDCByZone called with zone: %s and region: %s", zoneLooking, regionLooking)
 
 	// Need at least one VC
-	numOfVCs := len(cm.IcsInstanceMap)
+	numOfVCs := len(cm.Instances())
 	if numOfVCs == 0 {
 		err := ErrMustHaveAtLeastOneVCDC
 		klog.Errorf("%v", err)
@@ -74,7 +74,8 @@ func (cm *ConnectionManager) getDIFromSingleVC(ctx context.Context,
 	zoneLabel string, regionLabel string, zoneLooking string, regionLooking string) (*ZoneDiscoveryInfo, error) {
 	klog.V(4).Infof("getDIFromSingleVC called with zone: %s and region: %s", zoneLooking, regionLooking)
 
-	if len(cm.IcsInstanceMap) != 1 {
+	instances := cm.Instances()
+	if len(instances) != 1 {
 		err := ErrUnsupportedConfiguration
 		klog.Errorf("%v", err)
 		return nil, err
@@ -84,7 +85,7 @@ func (cm *ConnectionManager) getDIFromSingleVC(ctx context.Context,
 
 	// Get first ics Instance
 	var tmpVsi *ICSInstance
-	for _, tmpVsi = range cm.IcsInstanceMap {
+	for _, tmpVsi = range instances {
 		break //Grab the first one because there is only one
 	}
 
@@ -180,7 +181,7 @@ func (cm *ConnectionManager) getDIFromMultiVCorDC(ctx context.Context,
 	}
 
 	go func() {
-		for _, vsi := range cm.IcsInstanceMap {
+		for _, vsi := range cm.Instances() {
 //ics
 			var datacenterObjs []*icslib.Datacenter
 //ics
@@ -348,7 +349,7 @@ func (cm *ConnectionManager) LookupZoneByMoref(ctx context.Context, tenantRef st
 
 	result := make(map[string]string)
 
-	vsi := cm.IcsInstanceMap[tenantRef]
+	vsi := cm.Instance(tenantRef)
 	if vsi == nil {
 		err := ErrConnectionNotFound
 		klog.Errorf("Unable to find Connection for tenantRef=%s", tenantRef)
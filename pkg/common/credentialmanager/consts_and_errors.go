@@ -18,6 +18,17 @@ package credentialmanager
 
 import (
 	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultExecTimeout is the time a credential exec plugin may run.
+	DefaultExecTimeout = 30 * time.Second
+
+	// DefaultCredentialTTL is how long credentials returned without an
+	// expiration are cached by the exec and HTTP credential providers.
+	DefaultCredentialTTL = 5 * time.Minute
 )
 
 // Errors
@@ -30,4 +41,26 @@ var (
 
 	// ErrUnknownSecretKey is returned when the supplied key does not return a secret.
 	ErrUnknownSecretKey = errors.New("Unknown secret key")
+
+	// ErrInvalidSecretFormat is returned when a structured secret key does
+	// not hold a YAML or JSON map of tenant refs to credentials.
+	ErrInvalidSecretFormat = errors.New("Invalid structured secret format")
+
+	// ErrInvalidExecCredential is returned when a credential exec plugin does
+	// not print a valid ExecCredential.
+	ErrInvalidExecCredential = errors.New("Invalid credential returned by exec plugin")
+
+	// ErrCredentialServiceFailed is returned when the HTTP credential service
+	// does not answer with 200 OK.
+	ErrCredentialServiceFailed = errors.New("Credential service request failed")
 )
+
+// SecretKeyError names the secret key whose credentials could not be parsed.
+type SecretKeyError struct {
+	Key string
+	Err error
+}
+
+func (e *SecretKeyError) Error() string {
+	return fmt.Sprintf("secret key %q: %v", e.Key, e.Err)
+}
@@ -92,7 +92,7 @@ func (credentialManager *CredentialManager) updateCredentialsMapK8s() error {
 		return nil
 	}
 	credentialManager.Cache.UpdateSecret(secret)
-	err = credentialManager.Cache.parseSecret()
+	_, err = credentialManager.Cache.parseSecret()
 	if err != nil {
 		klog.Errorf("parseSecret failed with err=%q", err)
 	}
@@ -101,23 +101,39 @@ func (credentialManager *CredentialManager) updateCredentialsMapK8s() error {
 }
 
 func (credentialManager *CredentialManager) updateCredentialsMapFile() error {
-	//Secretsdirectory was parsed before, no need to do it again
+	credentialManager.secretsDirectoryLock.Lock()
+	defer credentialManager.secretsDirectoryLock.Unlock()
+
+	//Secretsdirectory was parsed before, no need to do it again. Changes
+	//are picked up by the secrets directory watcher.
 	if credentialManager.secretsDirectoryParsed {
 		return nil
 	}
 
+	_, err := credentialManager.parseSecretsDirectory()
+	return err
+}
+
+// parseSecretsDirectory reads the secrets directory and replaces the cached
+// credentials. It returns the servers whose credentials changed. The caller
+// must hold secretsDirectoryLock.
+func (credentialManager *CredentialManager) parseSecretsDirectory() ([]string, error) {
 	//take the mounted secrets in the form of files and make it looks like we
 	//parsed it from a k8s secret so we can reuse the SecretCache.parseSecret() func
 	data := make(map[string][]byte)
 
 	files, err := ioutil.ReadDir(credentialManager.SecretsDirectory)
 	if err != nil {
-		credentialManager.secretsDirectoryParsed = true
 		klog.Warningf("Failed to find secrets directory %s. error: %q", credentialManager.SecretsDirectory, err)
-		return err
+		return nil, err
 	}
 
 	for _, f := range files {
+		// Kubernetes mounts secrets through "..data" and timestamped
+		// directories, the keys themselves are symlinks into them.
+		if strings.HasPrefix(f.Name(), "..") {
+			continue
+		}
 		if f.IsDir() {
 			klog.Warningf("Skipping parse of directory: %s", f.Name())
 			continue
@@ -133,9 +149,14 @@ func (credentialManager *CredentialManager) updateCredentialsMapFile() error {
 		data[f.Name()] = contents
 	}
 
-	credentialManager.secretsDirectoryParsed = true
 	credentialManager.Cache.UpdateSecretFile(data)
-	return credentialManager.Cache.parseSecret()
+	changed, err := credentialManager.Cache.parseSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	credentialManager.secretsDirectoryParsed = true
+	return changed, nil
 }
 
 // GetSecret returns a Kubernetes secret.
@@ -171,7 +192,11 @@ func (cache *SecretCache) GetCredential(server string) (Credential, bool) {
 	return *credential, found
 }
 
-func (cache *SecretCache) parseSecret() error {
+// parseSecret rebuilds the credentials map from the cached secret data so
+// that credentials of servers no longer present are dropped. The previous
+// credentials are kept if parsing fails. It returns the servers whose
+// credentials were added, changed or removed.
+func (cache *SecretCache) parseSecret() ([]string, error) {
 	cache.cacheLock.Lock()
 	defer cache.cacheLock.Unlock()
 
@@ -184,37 +209,70 @@ func (cache *SecretCache) parseSecret() error {
 		data = cache.SecretFile
 	}
 
-	return parseConfig(data, cache.VirtualCenter)
+	config := make(map[string]*Credential)
+	if err := parseConfig(data, config); err != nil {
+		return nil, err
+	}
+	indexByServer(config)
+
+	var changed []string
+	for vcServer, credential := range config {
+		old, ok := cache.VirtualCenter[vcServer]
+		if !ok || *old != *credential {
+			changed = append(changed, vcServer)
+		}
+	}
+	for vcServer := range cache.VirtualCenter {
+		if _, ok := config[vcServer]; !ok {
+			klog.V(2).Infof("Invalidating cached credentials for server %s", vcServer)
+			changed = append(changed, vcServer)
+		}
+	}
+
+	cache.VirtualCenter = config
+	return changed, nil
 }
 
-// parseConfig returns iCenter ip/fdqn mapping to its credentials viz. Username and Password.
+// parseConfig returns iCenter ip/fdqn mapping to its credentials. Keys ending
+// in .yaml, .yml or .json hold the structured format, the remaining keys are
+// <server>.username and <server>.password pairs. Unknown keys are skipped.
 func parseConfig(data map[string][]byte, config map[string]*Credential) error {
 	if len(data) == 0 {
 		return ErrCredentialMissing
 	}
 	for credentialKey, credentialValue := range data {
-		credentialKey = strings.ToLower(credentialKey)
-		if strings.HasSuffix(credentialKey, "password") {
-			vcServer := strings.Split(credentialKey, ".password")[0]
+		lowerKey := strings.ToLower(credentialKey)
+		if isStructuredSecretKey(lowerKey) {
+			if err := parseStructuredSecret(credentialKey, credentialValue, config); err != nil {
+				return err
+			}
+		} else if strings.HasSuffix(lowerKey, ".password") {
+			vcServer := credentialKey[:len(credentialKey)-len(".password")]
 			if _, ok := config[vcServer]; !ok {
 				config[vcServer] = &Credential{}
 			}
 			config[vcServer].Password = string(credentialValue)
-		} else if strings.HasSuffix(credentialKey, "username") {
-			vcServer := strings.Split(credentialKey, ".username")[0]
+		} else if strings.HasSuffix(lowerKey, ".username") {
+			vcServer := credentialKey[:len(credentialKey)-len(".username")]
 			if _, ok := config[vcServer]; !ok {
 				config[vcServer] = &Credential{}
 			}
 			config[vcServer].User = string(credentialValue)
 		} else {
-			klog.Errorf("Unknown secret key %s", credentialKey)
-			return ErrUnknownSecretKey
+			klog.Warningf("Ignoring unknown secret key %s", credentialKey)
 		}
 	}
 	for vcServer, credential := range config {
-		if credential.User == "" || credential.Password == "" {
-			klog.Errorf("Username/Password is missing for server %s", vcServer)
-			return ErrCredentialMissing
+		if credential.Token != "" {
+			continue
+		}
+		if credential.User == "" {
+			klog.Errorf("Username is missing for server %s", vcServer)
+			return &SecretKeyError{Key: vcServer + ".username", Err: ErrCredentialMissing}
+		}
+		if credential.Password == "" {
+			klog.Errorf("Password is missing for server %s", vcServer)
+			return &SecretKeyError{Key: vcServer + ".password", Err: ErrCredentialMissing}
 		}
 	}
 	return nil
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// NewExecCredentialProvider returns a CredentialProvider running command with
+// args to obtain credentials. Credentials returned without an expiration are
+// cached for defaultTTL.
+func NewExecCredentialProvider(command string, args []string, defaultTTL time.Duration) *ExecCredentialProvider {
+	if defaultTTL <= 0 {
+		defaultTTL = DefaultCredentialTTL
+	}
+	return &ExecCredentialProvider{
+		Command:    command,
+		Args:       args,
+		Timeout:    DefaultExecTimeout,
+		DefaultTTL: defaultTTL,
+		cache:      newCredentialCache(),
+	}
+}
+
+// GetCredential returns the cached credentials for server, running the exec
+// plugin if there are none or they have expired.
+func (provider *ExecCredentialProvider) GetCredential(server string) (*Credential, error) {
+	if credential, ok := provider.cache.get(server); ok {
+		return credential, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), provider.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, provider.Command, provider.Args...)
+	cmd.Env = append(os.Environ(), "ICS_SERVER="+server)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	klog.V(4).Infof("Running credential exec plugin %s for server %s", provider.Command, server)
+	if err := cmd.Run(); err != nil {
+		klog.Errorf("Credential exec plugin %s failed for server %s. err=%v stderr=%q",
+			provider.Command, server, err, stderr.String())
+		return nil, err
+	}
+
+	var execCredential ExecCredential
+	if err := json.Unmarshal(stdout.Bytes(), &execCredential); err != nil {
+		klog.Errorf("Failed to decode the output of credential exec plugin %s. err=%v", provider.Command, err)
+		return nil, ErrInvalidExecCredential
+	}
+	credential := &Credential{
+		User:     execCredential.User,
+		Password: execCredential.Password,
+		Token:    execCredential.Token,
+	}
+	if err := validateCredential(credential); err != nil {
+		klog.Errorf("Credential exec plugin %s returned no token or username/password for server %s", provider.Command, server)
+		return nil, ErrInvalidExecCredential
+	}
+
+	expires := time.Now().Add(provider.DefaultTTL)
+	if execCredential.ExpirationTimestamp != nil {
+		expires = *execCredential.ExpirationTimestamp
+	}
+	provider.cache.set(server, credential, expires)
+	return credential, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecCredentialProvider(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		output  string
+		wantErr bool
+		// Number of plugin runs made by two GetCredential calls
+		wantRuns int
+	}{
+		{
+			name:     "cached until expiration",
+			output:   `{"user": "admin", "password": "secret", "expirationTimestamp": "` + future + `"}`,
+			wantRuns: 1,
+		},
+		{
+			name:     "cached for the default TTL without expiration",
+			output:   `{"token": "abc"}`,
+			wantRuns: 1,
+		},
+		{
+			name:     "expired credentials are not cached",
+			output:   `{"token": "abc", "expirationTimestamp": "` + past + `"}`,
+			wantRuns: 2,
+		},
+		{
+			name:     "missing credentials",
+			output:   `{"user": "admin"}`,
+			wantErr:  true,
+			wantRuns: 2,
+		},
+		{
+			name:     "invalid output",
+			output:   `not json`,
+			wantErr:  true,
+			wantRuns: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testTempDir(t)
+			defer os.RemoveAll(dir)
+			runs := filepath.Join(dir, "runs")
+			output := filepath.Join(dir, "output")
+			if err := ioutil.WriteFile(output, []byte(test.output), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			// The plugin records its runs and the server it was given
+			provider := NewExecCredentialProvider("/bin/sh",
+				[]string{"-c", `echo "$ICS_SERVER" >> "$0" && cat "$1"`, runs, output}, time.Minute)
+			for i := 0; i < 2; i++ {
+				credential, err := provider.GetCredential("10.0.0.1")
+				if test.wantErr {
+					if err == nil {
+						t.Fatalf("GetCredential() succeeded, want an error")
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("GetCredential() failed: %v", err)
+				}
+				if credential.User == "" && credential.Token == "" {
+					t.Errorf("GetCredential() = %+v, want credentials", credential)
+				}
+			}
+
+			data, err := ioutil.ReadFile(runs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Fields(string(data))
+			if len(lines) != test.wantRuns {
+				t.Errorf("plugin ran %d times, want %d", len(lines), test.wantRuns)
+			}
+			for _, server := range lines {
+				if server != "10.0.0.1" {
+					t.Errorf("plugin was given ICS_SERVER=%q, want 10.0.0.1", server)
+				}
+			}
+		})
+	}
+}
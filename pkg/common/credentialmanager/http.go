@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// NewHTTPCredentialProvider returns a CredentialProvider reading credentials
+// from the vault-style service at serviceURL.
+func NewHTTPCredentialProvider(serviceURL string, tokenFile string, defaultTTL time.Duration) *HTTPCredentialProvider {
+	if defaultTTL <= 0 {
+		defaultTTL = DefaultCredentialTTL
+	}
+	return &HTTPCredentialProvider{
+		URL:        serviceURL,
+		TokenFile:  tokenFile,
+		DefaultTTL: defaultTTL,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+		cache:      newCredentialCache(),
+	}
+}
+
+// GetCredential returns the cached credentials for server, asking the
+// credential service if there are none or their lease has expired.
+func (provider *HTTPCredentialProvider) GetCredential(server string) (*Credential, error) {
+	if credential, ok := provider.cache.get(server); ok {
+		return credential, nil
+	}
+
+	serviceURL, err := url.Parse(provider.URL)
+	if err != nil {
+		klog.Errorf("Invalid credential service URL %s. err=%v", provider.URL, err)
+		return nil, err
+	}
+	query := serviceURL.Query()
+	query.Set("server", server)
+	serviceURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, serviceURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if provider.TokenFile != "" {
+		token, err := ioutil.ReadFile(provider.TokenFile)
+		if err != nil {
+			klog.Errorf("Failed to read credential service token %s. err=%v", provider.TokenFile, err)
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	klog.V(4).Infof("Requesting credentials for server %s from %s", server, provider.URL)
+	resp, err := provider.Client.Do(req)
+	if err != nil {
+		klog.Errorf("Credential service request for server %s failed. err=%v", server, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		klog.Errorf("Credential service returned %s for server %s", resp.Status, server)
+		return nil, ErrCredentialServiceFailed
+	}
+
+	var response HTTPCredentialResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		klog.Errorf("Failed to decode credential service response for server %s. err=%v", server, err)
+		return nil, err
+	}
+	if err := validateCredential(response.Data); err != nil {
+		klog.Errorf("Credential service returned no token or username/password for server %s", server)
+		return nil, err
+	}
+
+	ttl := provider.DefaultTTL
+	if response.LeaseDuration > 0 {
+		ttl = time.Duration(response.LeaseDuration) * time.Second
+	}
+	provider.cache.set(server, response.Data, time.Now().Add(ttl))
+	return response.Data, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPCredentialProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		defaultTTL time.Duration
+		wantErr    bool
+		wantUser   string
+		wantToken  string
+		// Number of requests made by two GetCredential calls
+		wantRequests int32
+	}{
+		{
+			name:         "lease is cached",
+			status:       http.StatusOK,
+			body:         `{"lease_duration": 300, "data": {"user": "admin", "password": "secret"}}`,
+			wantUser:     "admin",
+			wantRequests: 1,
+		},
+		{
+			name:         "default TTL without lease",
+			status:       http.StatusOK,
+			body:         `{"data": {"token": "abc"}}`,
+			defaultTTL:   time.Minute,
+			wantToken:    "abc",
+			wantRequests: 1,
+		},
+		{
+			name:         "service error",
+			status:       http.StatusForbidden,
+			body:         `{}`,
+			wantErr:      true,
+			wantRequests: 2,
+		},
+		{
+			name:         "missing credentials",
+			status:       http.StatusOK,
+			body:         `{"data": {"user": "admin"}}`,
+			wantErr:      true,
+			wantRequests: 2,
+		},
+	}
+
+	dir := testTempDir(t)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("vault-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if got := r.URL.Query().Get("server"); got != "10.0.0.1" {
+					t.Errorf("server = %q, want 10.0.0.1", got)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer vault-token" {
+					t.Errorf("Authorization = %q, want the bearer token", got)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			provider := NewHTTPCredentialProvider(server.URL, tokenFile, test.defaultTTL)
+			for i := 0; i < 2; i++ {
+				credential, err := provider.GetCredential("10.0.0.1")
+				if test.wantErr {
+					if err == nil {
+						t.Fatalf("GetCredential() succeeded, want an error")
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("GetCredential() failed: %v", err)
+				}
+				if credential.User != test.wantUser || credential.Token != test.wantToken {
+					t.Errorf("GetCredential() = %+v, want user %q and token %q", credential, test.wantUser, test.wantToken)
+				}
+			}
+			if got := atomic.LoadInt32(&requests); got != test.wantRequests {
+				t.Errorf("made %d requests, want %d", got, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestHTTPCredentialProviderExpiredLease(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"data": {"user": "admin", "password": "secret"}}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPCredentialProvider(server.URL, "", time.Minute)
+	if _, err := provider.GetCredential("10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	provider.cache.entries["10.0.0.1"].expires = time.Now().Add(-time.Second)
+	if _, err := provider.GetCredential("10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("made %d requests, want 2 once the lease expired", got)
+	}
+}
+
+// testTempDir returns a temporary directory, removed by the caller.
+func testTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "credentialmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"time"
+)
+
+func newCredentialCache() *credentialCache {
+	return &credentialCache{
+		entries: make(map[string]*cachedCredential),
+	}
+}
+
+// get returns the cached credentials of server if they have not expired.
+func (cache *credentialCache) get(server string) (*Credential, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry, ok := cache.entries[server]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expires) {
+		delete(cache.entries, server)
+		return nil, false
+	}
+	credential := entry.credential
+	return &credential, true
+}
+
+// set caches the credentials of server until expires. Credentials that are
+// already expired are not cached.
+func (cache *credentialCache) set(server string, credential *Credential, expires time.Time) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if !time.Now().Before(expires) {
+		delete(cache.entries, server)
+		return
+	}
+	cache.entries[server] = &cachedCredential{
+		credential: *credential,
+		expires:    expires,
+	}
+}
+
+// validateCredential checks that either a token or a username and password
+// are present.
+func validateCredential(credential *Credential) error {
+	if credential == nil {
+		return ErrCredentialMissing
+	}
+	if credential.Token != "" {
+		return nil
+	}
+	if credential.User == "" || credential.Password == "" {
+		return ErrCredentialMissing
+	}
+	return nil
+}
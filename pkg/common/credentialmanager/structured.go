@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+)
+
+// A structured secret holds a single YAML or JSON document mapping tenant refs
+// to their credentials, for example:
+//
+//   10.0.0.1:
+//     user: admin
+//     password: secret
+//   prod:
+//     server: icenter.example.com
+//     port: 8443
+//     token: 4f1c...
+//     ca: |
+//       -----BEGIN CERTIFICATE-----
+//       ...
+
+// isStructuredSecretKey returns true if the lower-cased secret key holds a
+// structured secret.
+func isStructuredSecretKey(key string) bool {
+	return strings.HasSuffix(key, ".yaml") || strings.HasSuffix(key, ".yml") || strings.HasSuffix(key, ".json")
+}
+
+// parseStructuredSecret adds the credentials held by the structured secret
+// under key to config.
+func parseStructuredSecret(key string, value []byte, config map[string]*Credential) error {
+	var entries map[string]map[string]interface{}
+	if err := yaml.Unmarshal(value, &entries); err != nil {
+		klog.Errorf("Failed to parse secret key %s. err=%v", key, err)
+		return &SecretKeyError{Key: key, Err: ErrInvalidSecretFormat}
+	}
+
+	for tenantRef, fields := range entries {
+		entryKey := key + ":" + tenantRef
+		credential := &Credential{}
+		for field, fieldValue := range fields {
+			var str string
+			switch v := fieldValue.(type) {
+			case string:
+				str = v
+			case float64, bool:
+				str = fmt.Sprint(v)
+			default:
+				klog.Errorf("Field %s of secret key %s is not a scalar", field, entryKey)
+				return &SecretKeyError{Key: entryKey + "." + field, Err: ErrInvalidSecretFormat}
+			}
+
+			switch field {
+			case "user":
+				credential.User = str
+			case "password":
+				credential.Password = str
+			case "token":
+				credential.Token = str
+			case "server":
+				credential.Server = str
+			case "port":
+				credential.Port = str
+			case "ca":
+				credential.CACert = str
+			default:
+				klog.Warningf("Ignoring unknown field %s of secret key %s", field, entryKey)
+			}
+		}
+
+		if err := validateCredential(credential); err != nil {
+			klog.Errorf("Token or Username/Password is missing for secret key %s", entryKey)
+			return &SecretKeyError{Key: entryKey, Err: err}
+		}
+		if _, ok := config[tenantRef]; ok {
+			klog.Warningf("Credentials for %s in secret key %s replace the ones set by another key", tenantRef, key)
+		}
+		config[tenantRef] = credential
+	}
+	return nil
+}
+
+// indexByServer makes credentials that override the iCenter address also
+// available under that address.
+func indexByServer(config map[string]*Credential) {
+	for tenantRef, credential := range config {
+		if credential.Server == "" || credential.Server == tenantRef {
+			continue
+		}
+		if _, ok := config[credential.Server]; ok {
+			continue
+		}
+		config[credential.Server] = credential
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    map[string]Credential
+		wantKey string
+	}{
+		{
+			name: "username and password keys",
+			data: map[string]string{"10.0.0.1.username": "admin", "10.0.0.1.password": "secret"},
+			want: map[string]Credential{"10.0.0.1": {User: "admin", Password: "secret"}},
+		},
+		{
+			name: "YAML",
+			data: map[string]string{"credentials.yaml": "10.0.0.1:\n  user: admin\n  password: secret\n"},
+			want: map[string]Credential{"10.0.0.1": {User: "admin", Password: "secret"}},
+		},
+		{
+			name: "JSON token",
+			data: map[string]string{"credentials.json": `{"prod": {"token": "4f1c"}}`},
+			want: map[string]Credential{"prod": {Token: "4f1c"}},
+		},
+		{
+			name: "endpoint override",
+			data: map[string]string{"credentials.yml": "prod:\n  server: icenter.example.com\n  port: 8443\n  token: 4f1c\n  ca: |\n    PEM\n"},
+			want: map[string]Credential{
+				"prod":                {Server: "icenter.example.com", Port: "8443", Token: "4f1c", CACert: "PEM\n"},
+				"icenter.example.com": {Server: "icenter.example.com", Port: "8443", Token: "4f1c", CACert: "PEM\n"},
+			},
+		},
+		{
+			name: "partial override",
+			data: map[string]string{"credentials.yaml": "10.0.0.1:\n  user: admin\n  password: secret\n  port: 8443\n"},
+			want: map[string]Credential{"10.0.0.1": {User: "admin", Password: "secret", Port: "8443"}},
+		},
+		{
+			name: "structured and key pairs together",
+			data: map[string]string{
+				"credentials.yaml":  "prod:\n  token: 4f1c\n",
+				"10.0.0.1.username": "admin",
+				"10.0.0.1.password": "secret",
+			},
+			want: map[string]Credential{"prod": {Token: "4f1c"}, "10.0.0.1": {User: "admin", Password: "secret"}},
+		},
+		{
+			name: "unknown fields and keys are skipped",
+			data: map[string]string{"credentials.yaml": "prod:\n  token: 4f1c\n  color: blue\n", "README": "notes"},
+			want: map[string]Credential{"prod": {Token: "4f1c"}},
+		},
+		{
+			name:    "invalid YAML",
+			data:    map[string]string{"credentials.yaml": "prod: [user: admin"},
+			wantKey: "credentials.yaml",
+		},
+		{
+			name:    "not a map of tenant refs",
+			data:    map[string]string{"credentials.yaml": "- admin\n- secret\n"},
+			wantKey: "credentials.yaml",
+		},
+		{
+			name:    "field is not a scalar",
+			data:    map[string]string{"credentials.yaml": "prod:\n  user: [admin]\n  password: secret\n"},
+			wantKey: "credentials.yaml:prod.user",
+		},
+		{
+			name:    "structured password without user",
+			data:    map[string]string{"credentials.json": `{"prod": {"password": "secret"}}`},
+			wantKey: "credentials.json:prod",
+		},
+		{
+			name:    "password key without username key",
+			data:    map[string]string{"10.0.0.1.password": "secret"},
+			wantKey: "10.0.0.1.username",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := make(map[string][]byte)
+			for key, value := range test.data {
+				data[key] = []byte(value)
+			}
+			config := make(map[string]*Credential)
+			err := parseConfig(data, config)
+			if test.wantKey != "" {
+				keyErr, ok := err.(*SecretKeyError)
+				if !ok || keyErr.Key != test.wantKey {
+					t.Fatalf("parseConfig() error = %v, want an error for key %q", err, test.wantKey)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() failed: %v", err)
+			}
+
+			indexByServer(config)
+			got := make(map[string]Credential)
+			for server, credential := range config {
+				got[server] = *credential
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseConfig() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
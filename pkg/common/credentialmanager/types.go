@@ -17,7 +17,9 @@ limitations under the License.
 package credentialmanager
 
 import (
+	"net/http"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	clientv1 "k8s.io/client-go/listers/core/v1"
@@ -34,10 +36,22 @@ type SecretCache struct {
 // Credential is a iCenter credential that is retrieved or stored in a
 // Kubernetes secret.
 type Credential struct {
-	User     string `gcfg:"user"`
-	Password string `gcfg:"password"`
+	User     string `gcfg:"user" json:"user,omitempty"`
+	Password string `gcfg:"password" json:"password,omitempty"`
+	// Token is a pre-issued iCenter session token. When set it is used in
+	// place of User and Password.
+	Token string `gcfg:"token" json:"token,omitempty"`
+	// Server, Port and CACert optionally override the iCenter address, port
+	// and PEM encoded CA bundle. They are only read from structured secrets.
+	Server string `gcfg:"server" json:"server,omitempty"`
+	Port   string `gcfg:"port" json:"port,omitempty"`
+	CACert string `gcfg:"ca" json:"ca,omitempty"`
 }
 
+// CredentialsChangedFunc is called with the iCenter servers whose credentials
+// were added, rotated or removed when the secrets directory is re-parsed.
+type CredentialsChangedFunc func(servers []string)
+
 // CredentialManager is used to manage iCenter credentials stored as
 // Kubernetes secrets.
 type CredentialManager struct {
@@ -45,6 +59,74 @@ type CredentialManager struct {
 	SecretNamespace        string
 	SecretLister           clientv1.SecretLister
 	SecretsDirectory       string
+	secretsDirectoryLock   sync.Mutex
 	secretsDirectoryParsed bool // internal placeholder to identify we parsed the SecretsDirectory
 	Cache                  *SecretCache
+
+	// handlers notified when the secrets directory changes
+	handlersLock sync.Mutex
+	handlers     []CredentialsChangedFunc
+}
+
+// CredentialProvider returns the iCenter credentials for a server. The
+// CredentialManager backed by Kubernetes secrets or a secrets directory is one
+// implementation; ExecCredentialProvider and HTTPCredentialProvider obtain
+// them from an external plugin or service.
+type CredentialProvider interface {
+	GetCredential(server string) (*Credential, error)
+}
+
+// ExecCredentialProvider runs an external command to obtain credentials, much
+// like kubectl credential plugins. The command is given the iCenter server in
+// the ICS_SERVER environment variable and must print an ExecCredential as JSON
+// on stdout.
+type ExecCredentialProvider struct {
+	Command string
+	Args    []string
+	Timeout time.Duration
+	// DefaultTTL is used when the plugin does not return an expiration.
+	DefaultTTL time.Duration
+	cache      *credentialCache
+}
+
+// ExecCredential is the output of a credential exec plugin. The credentials
+// are cached until ExpirationTimestamp, or for the default TTL of the
+// provider if it is not set.
+type ExecCredential struct {
+	User                string     `json:"user,omitempty"`
+	Password            string     `json:"password,omitempty"`
+	Token               string     `json:"token,omitempty"`
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+// HTTPCredentialProvider reads credentials from a vault-style HTTP service.
+// The iCenter server is passed in the "server" query parameter and the
+// response is cached for its lease duration.
+type HTTPCredentialProvider struct {
+	URL string
+	// TokenFile, if set, holds the bearer token sent to the service. It is
+	// re-read on every request so the token can be rotated.
+	TokenFile string
+	// DefaultTTL is used when the service does not return a lease duration.
+	DefaultTTL time.Duration
+	Client     *http.Client
+	cache      *credentialCache
+}
+
+// HTTPCredentialResponse is the response of the credential service.
+type HTTPCredentialResponse struct {
+	// LeaseDuration is the number of seconds the credentials may be cached.
+	LeaseDuration int64       `json:"lease_duration,omitempty"`
+	Data          *Credential `json:"data"`
+}
+
+// credentialCache holds credentials until they expire.
+type credentialCache struct {
+	lock    sync.Mutex
+	entries map[string]*cachedCredential
+}
+
+type cachedCredential struct {
+	credential Credential
+	expires    time.Time
 }
@@ -0,0 +1,118 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog"
+)
+
+const (
+	// SecretsDirectoryResyncDelay is how long the watcher waits for the
+	// secrets directory to settle before re-parsing it. Rotating a mounted
+	// Secret produces a burst of events for the "..data" symlink swap.
+	SecretsDirectoryResyncDelay = 2 * time.Second
+)
+
+// AddCredentialsChangedHandler registers a handler that is notified when
+// credentials in the secrets directory are rotated or removed.
+func (credentialManager *CredentialManager) AddCredentialsChangedHandler(handler CredentialsChangedFunc) {
+	credentialManager.handlersLock.Lock()
+	defer credentialManager.handlersLock.Unlock()
+	credentialManager.handlers = append(credentialManager.handlers, handler)
+}
+
+// WatchSecretsDirectory watches the secrets directory and re-parses it on
+// every change until stopCh is closed.
+func (credentialManager *CredentialManager) WatchSecretsDirectory(stopCh <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		klog.Errorf("Failed to create secrets directory watcher. error: %q", err)
+		return err
+	}
+
+	// Watching the directory rather than the files survives the atomic
+	// symlink swap Kubernetes does when a mounted Secret is updated.
+	if err := watcher.Add(credentialManager.SecretsDirectory); err != nil {
+		klog.Errorf("Failed to watch secrets directory %s. error: %q", credentialManager.SecretsDirectory, err)
+		watcher.Close()
+		return err
+	}
+
+	klog.V(2).Infof("Watching secrets directory %s", credentialManager.SecretsDirectory)
+	go credentialManager.watchSecretsDirectory(watcher, stopCh)
+	return nil
+}
+
+func (credentialManager *CredentialManager) watchSecretsDirectory(watcher *fsnotify.Watcher, stopCh <-chan struct{}) {
+	defer watcher.Close()
+
+	resync := time.NewTimer(SecretsDirectoryResyncDelay)
+	resync.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			resync.Stop()
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			klog.V(4).Infof("Secrets directory event: %s", event)
+			// Drain a pending expiry so that it does not fire right after
+			// the reset
+			if !resync.Stop() {
+				select {
+				case <-resync.C:
+				default:
+				}
+			}
+			resync.Reset(SecretsDirectoryResyncDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			klog.Warningf("Secrets directory watcher error: %q", err)
+		case <-resync.C:
+			credentialManager.resyncSecretsDirectory()
+		}
+	}
+}
+
+func (credentialManager *CredentialManager) resyncSecretsDirectory() {
+	credentialManager.secretsDirectoryLock.Lock()
+	changed, err := credentialManager.parseSecretsDirectory()
+	credentialManager.secretsDirectoryLock.Unlock()
+	if err != nil {
+		klog.Warningf("Failed parsing SecretsDirectory %q: %q", credentialManager.SecretsDirectory, err)
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	klog.V(2).Infof("Credentials changed for servers %v", changed)
+	credentialManager.handlersLock.Lock()
+	handlers := credentialManager.handlers
+	credentialManager.handlersLock.Unlock()
+	for _, handler := range handlers {
+		handler(changed)
+	}
+}
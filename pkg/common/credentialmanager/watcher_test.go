@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatchSecretsDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeSecret := func(key string, value string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, key), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSecret("10.0.0.1.username", "admin")
+	writeSecret("10.0.0.1.password", "old")
+	writeSecret("10.0.0.2.username", "admin")
+	writeSecret("10.0.0.2.password", "other")
+	// Kubernetes mounts the keys through a ..data directory, it is skipped
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	credMgr := NewCredentialManager("", "", dir, nil)
+	credential, err := credMgr.GetCredential("10.0.0.1")
+	if err != nil || credential.Password != "old" {
+		t.Fatalf("GetCredential() = %+v, %v, want password old", credential, err)
+	}
+
+	changed := make(chan []string, 10)
+	credMgr.AddCredentialsChangedHandler(func(servers []string) {
+		changed <- servers
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := credMgr.WatchSecretsDirectory(stopCh); err != nil {
+		t.Fatalf("WatchSecretsDirectory() failed: %v", err)
+	}
+
+	expectChanged := func(want []string) {
+		select {
+		case servers := <-changed:
+			if !reflect.DeepEqual(servers, want) {
+				t.Errorf("changed servers = %v, want %v", servers, want)
+			}
+		case <-time.After(3 * SecretsDirectoryResyncDelay):
+			t.Fatalf("credentials change of %v not reported", want)
+		}
+	}
+
+	// A burst of writes, like the symlink swap of a rotated Secret, is
+	// reported once
+	writeSecret("10.0.0.1.password", "rotating")
+	writeSecret("10.0.0.1.password", "new")
+	expectChanged([]string{"10.0.0.1"})
+	select {
+	case servers := <-changed:
+		t.Errorf("unexpected second change of %v", servers)
+	case <-time.After(SecretsDirectoryResyncDelay + 500*time.Millisecond):
+	}
+
+	credential, err = credMgr.GetCredential("10.0.0.1")
+	if err != nil || credential.Password != "new" {
+		t.Errorf("GetCredential() = %+v, %v, want password new", credential, err)
+	}
+
+	// Removed credentials are reported and no longer returned
+	os.Remove(filepath.Join(dir, "10.0.0.2.username"))
+	os.Remove(filepath.Join(dir, "10.0.0.2.password"))
+	expectChanged([]string{"10.0.0.2"})
+	if _, err := credMgr.GetCredential("10.0.0.2"); err != ErrCredentialsNotFound {
+		t.Errorf("GetCredential() error = %v, want %v", err, ErrCredentialsNotFound)
+	}
+}
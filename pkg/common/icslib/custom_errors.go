@@ -26,10 +26,18 @@ const (
 	DiskNotFoundErrMsg             = "No vSphere disk ID/Name found"
 	InvalidVolumeOptionsErrMsg     = "VolumeOptions verification failed"
 	NoVMFoundErrMsg                = "No VM found"
+	NoHostFoundErrMsg              = "No host found"
+	NoClusterFoundErrMsg           = "No cluster found"
+	NoEventsFoundErrMsg            = "No events found"
 	NoZoneRegionFoundErrMsg        = "Unable to find the Zone/Region pair"
 	NoDatastoreFoundErrMsg         = "Datastore not found"
 	NoDatacenterFoundErrMsg        = "Datacenter not found"
 	NoDataStoreClustersFoundErrMsg = "No DatastoreClusters Found"
+	InvalidDatastoreErrMsg         = "Datastore is missing required fields"
+	NoStoragePoliciesFoundErrMsg   = "No storage policies found"
+	InvalidThumbprintErrMsg        = "Invalid certificate thumbprint"
+	ThumbprintMismatchErrMsg       = "Certificate thumbprint mismatch"
+	InvalidCACertErrMsg            = "No certificates found in CA file"
 )
 
 // Error constants
@@ -39,8 +47,16 @@ var (
 	ErrNoDiskIDFound            = errors.New(DiskNotFoundErrMsg)
 	ErrInvalidVolumeOptions     = errors.New(InvalidVolumeOptionsErrMsg)
 	ErrNoVMFound                = errors.New(NoVMFoundErrMsg)
+	ErrNoHostFound              = errors.New(NoHostFoundErrMsg)
+	ErrNoClusterFound           = errors.New(NoClusterFoundErrMsg)
+	ErrNoEventsFound            = errors.New(NoEventsFoundErrMsg)
 	ErrNoZoneRegionFound        = errors.New(NoZoneRegionFoundErrMsg)
 	ErrNoDatastoreFound         = errors.New(NoDatastoreFoundErrMsg)
 	ErrNoDatacenterFound        = errors.New(NoDatacenterFoundErrMsg)
 	ErrNoDataStoreClustersFound = errors.New(NoDataStoreClustersFoundErrMsg)
+	ErrInvalidDatastore         = errors.New(InvalidDatastoreErrMsg)
+	ErrNoStoragePoliciesFound   = errors.New(NoStoragePoliciesFoundErrMsg)
+	ErrInvalidThumbprint        = errors.New(InvalidThumbprintErrMsg)
+	ErrThumbprintMismatch       = errors.New(ThumbprintMismatchErrMsg)
+	ErrInvalidCACert            = errors.New(InvalidCACertErrMsg)
 )
\ No newline at end of file
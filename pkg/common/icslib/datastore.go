@@ -0,0 +1,277 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/inspur-ics/ics-go-sdk/client/methods"
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	"k8s.io/klog"
+)
+
+// DatastoreInfo is a datastore of an iCenter. The SDK has no datastore
+// model, so datastores are read from the REST API directly.
+//
+// The SDK only confirms the dataStoreType key, used by the datastore fields
+// of volumes, and the /datacenters/{id}/... layout of the datacenter
+// endpoints. The other keys, and the storages and storagepods endpoints, are
+// not covered by the SDK and are pinned by datastore_test.go. A datastore
+// without an ID, a capacity or a free space is rejected with
+// ErrInvalidDatastore rather than reported empty.
+type DatastoreInfo struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	DataCenterID string `json:"dataCenterId"`
+	// Type of the datastore, like LOCAL, NFS or ISCSI
+	Type string `json:"dataStoreType"`
+	// Capacity and free space in GB
+	Capacity  float64 `json:"capacity"`
+	FreeSpace float64 `json:"avail"`
+	// Hosts the datastore is mounted on
+	Hosts []DatastoreHost `json:"hosts"`
+	// ID of the datastore cluster of the datastore, if any
+	ClusterID string `json:"storagePodId"`
+	// Tags as returned by iCenter, see DatastoreTags
+	Tags interface{} `json:"tags"`
+}
+
+// datastoreRequiredFields are the keys every datastore and datastore
+// cluster read from iCenter must have.
+var datastoreRequiredFields = []string{"id", "capacity", "avail"}
+
+// DatastoreHost is a host a datastore is mounted on.
+type DatastoreHost struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DatastoreClusterInfo is a datastore cluster of an iCenter.
+type DatastoreClusterInfo struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	DataCenterID string `json:"dataCenterId"`
+	// Capacity and free space in GB, over all datastores of the cluster
+	Capacity  float64 `json:"capacity"`
+	FreeSpace float64 `json:"avail"`
+	// IDs of the datastores of the cluster
+	DatastoreIDs []string `json:"storageIds"`
+}
+
+// StoragePolicyInfo is a storage policy of a datacenter. iCenter has no
+// storage policy objects the SDK models: the policies are the volume
+// policies of the VM disks, as the SDK Volume type reports them, and their
+// datastores are the ones holding such disks.
+type StoragePolicyInfo struct {
+	Name string
+	// IDs of the datastores holding disks with the policy, sorted
+	DatastoreIDs []string
+}
+
+// decodeDatastoreObject decodes a datastore or a datastore cluster read
+// from iCenter into object, after checking that it has the required keys.
+func decodeDatastoreObject(data json.RawMessage, object interface{}) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return methods.JsonError(err)
+	}
+	for _, key := range datastoreRequiredFields {
+		if value, ok := fields[key]; !ok || string(value) == "null" {
+			klog.Errorf("Datastore object without %s: %s", key, data)
+			return ErrInvalidDatastore
+		}
+	}
+	if err := json.Unmarshal(data, object); err != nil {
+		return methods.JsonError(err)
+	}
+	return nil
+}
+
+// DatastoreTags returns the tags of the datastore, normalized like the tags
+// of VMs.
+func (ds *DatastoreInfo) DatastoreTags() []Tag {
+	return parseTags(ds.Tags)
+}
+
+// ZoneRegion returns the zone and region of the datastore, which are the
+// values of its tags in the zone and region categories. Either is empty if
+// the datastore has no tag in the category.
+func (ds *DatastoreInfo) ZoneRegion(zoneCategory string, regionCategory string) (string, string) {
+	tags := ds.DatastoreTags()
+	return tagValue(tags, zoneCategory), tagValue(tags, regionCategory)
+}
+
+// GetDatastoreByID returns the datastore with the given iCenter ID.
+func GetDatastoreByID(ctx context.Context, connection *ICSConnection, datastoreID string) (*DatastoreInfo, error) {
+	if datastoreID == "" || connection == nil || connection.Client == nil {
+		return nil, ErrNoDatastoreFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/storages/%s", datastoreID)
+	api.Token = true
+
+	resp, err := connection.Client.GetTrip(ctx, api, nil)
+	if err == nil && resp.StatusCode() == http.StatusNotFound {
+		return nil, ErrNoDatastoreFound
+	}
+	respBody, err := methods.HandleResponse(resp, err)
+	if err != nil {
+		klog.Errorf("Failed to get datastore %s. err: %+v", datastoreID, err)
+		return nil, err
+	}
+
+	datastore := &DatastoreInfo{}
+	if err := decodeDatastoreObject(respBody, datastore); err != nil {
+		return nil, err
+	}
+	if datastore.ID != datastoreID {
+		klog.Errorf("Datastore %s was read with ID %q", datastoreID, datastore.ID)
+		return nil, ErrNoDatastoreFound
+	}
+	return datastore, nil
+}
+
+// GetAllDatastores returns the datastores of the datacenter.
+func (dc *Datacenter) GetAllDatastores(ctx context.Context) ([]*DatastoreInfo, error) {
+	if dc.connection == nil || dc.connection.Client == nil {
+		return nil, ErrNoDatastoreFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/datacenters/%s/storages", dc.ID)
+	api.Token = true
+
+	resp, err := dc.connection.Client.GetTrip(ctx, api, nil)
+	respBody, err := methods.HandleResponse(resp, err)
+	if err != nil {
+		klog.Errorf("Failed to get datastores of datacenter %s. err: %+v", dc.Name(), err)
+		return nil, err
+	}
+
+	var page struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(respBody, &page); err != nil {
+		return nil, methods.JsonError(err)
+	}
+	if len(page.Items) == 0 {
+		return nil, ErrNoDatastoreFound
+	}
+	datastores := make([]*DatastoreInfo, 0, len(page.Items))
+	for _, item := range page.Items {
+		datastore := &DatastoreInfo{}
+		if err := decodeDatastoreObject(item, datastore); err != nil {
+			return nil, err
+		}
+		if datastore.DataCenterID == "" {
+			datastore.DataCenterID = dc.ID
+		}
+		datastores = append(datastores, datastore)
+	}
+	return datastores, nil
+}
+
+// GetAllDatastoreClusters returns the datastore clusters of the datacenter.
+func (dc *Datacenter) GetAllDatastoreClusters(ctx context.Context) ([]*DatastoreClusterInfo, error) {
+	if dc.connection == nil || dc.connection.Client == nil {
+		return nil, ErrNoDataStoreClustersFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/datacenters/%s/storagepods", dc.ID)
+	api.Token = true
+
+	resp, err := dc.connection.Client.GetTrip(ctx, api, nil)
+	respBody, err := methods.HandleResponse(resp, err)
+	if err != nil {
+		klog.Errorf("Failed to get datastore clusters of datacenter %s. err: %+v", dc.Name(), err)
+		return nil, err
+	}
+
+	var page struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(respBody, &page); err != nil {
+		return nil, methods.JsonError(err)
+	}
+	if len(page.Items) == 0 {
+		return nil, ErrNoDataStoreClustersFound
+	}
+	clusters := make([]*DatastoreClusterInfo, 0, len(page.Items))
+	for _, item := range page.Items {
+		cluster := &DatastoreClusterInfo{}
+		if err := decodeDatastoreObject(item, cluster); err != nil {
+			return nil, err
+		}
+		if cluster.DataCenterID == "" {
+			cluster.DataCenterID = dc.ID
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// GetAllStoragePolicies returns the storage policies of the datacenter,
+// sorted by name, read from the disks of its VMs.
+func (dc *Datacenter) GetAllStoragePolicies(ctx context.Context) ([]*StoragePolicyInfo, error) {
+	if dc.connection == nil || dc.connection.Client == nil {
+		return nil, ErrNoStoragePoliciesFound
+	}
+
+	page, err := methods.GetDatacenterVMById(ctx, dc.connection.Client, dc.ID)
+	if err != nil {
+		klog.Errorf("Failed to get VMs of datacenter %s. err: %+v", dc.Name(), err)
+		return nil, err
+	}
+
+	// policy -> datastore IDs
+	datastores := make(map[string]map[string]bool)
+	for _, vm := range page.Items {
+		for _, disk := range vm.Disks {
+			volume := disk.Volume
+			if volume.VolumePolicy == "" || volume.DataStoreID == "" {
+				continue
+			}
+			if datastores[volume.VolumePolicy] == nil {
+				datastores[volume.VolumePolicy] = make(map[string]bool)
+			}
+			datastores[volume.VolumePolicy][volume.DataStoreID] = true
+		}
+	}
+	if len(datastores) == 0 {
+		return nil, ErrNoStoragePoliciesFound
+	}
+
+	policies := make([]*StoragePolicyInfo, 0, len(datastores))
+	for name, ids := range datastores {
+		policy := &StoragePolicyInfo{Name: name, DatastoreIDs: make([]string, 0, len(ids))}
+		for id := range ids {
+			policy.DatastoreIDs = append(policy.DatastoreIDs, id)
+		}
+		sort.Strings(policy.DatastoreIDs)
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	return policies, nil
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+)
+
+func TestGetDatastoreByID(t *testing.T) {
+	api, connection := newFakeAPI(t, map[string]fakeResponse{
+		"GET /storages/ds-1": {body: `{"id":"ds-1","name":"datastore1","dataCenterId":"dc-1","dataStoreType":"NFS",
+			"capacity":1024,"avail":0,"hosts":[{"id":"host-1","name":"esx-1"}],"storagePodId":"pod-1","tags":["zone=zone-a"]}`},
+		"GET /storages/ds-2":       {body: `{"id":"ds-2","name":"datastore2","avail":10}`},
+		"GET /storages/ds-3":       {body: `{"id":"ds-3","capacity":null,"avail":10}`},
+		"GET /storages/ds-4":       {body: `{"id":"ds-5","capacity":10,"avail":10}`},
+		"GET /storages/ds-6":       {body: `{"name":"datastore6","capacity":10,"avail":10}`},
+		"GET /storages/broken":     {body: `{"id":`},
+		"GET /storages/failing":    {status: http.StatusInternalServerError},
+		"GET /storages/incomplete": {body: `{"id":"incomplete","capacity":10}`},
+	})
+	defer api.close()
+
+	tests := []struct {
+		name        string
+		datastoreID string
+		wantErr     error
+		anyErr      bool
+	}{
+		{name: "found", datastoreID: "ds-1"},
+		{name: "not found", datastoreID: "ds-0", wantErr: ErrNoDatastoreFound},
+		{name: "without capacity", datastoreID: "ds-2", wantErr: ErrInvalidDatastore},
+		{name: "null capacity", datastoreID: "ds-3", wantErr: ErrInvalidDatastore},
+		{name: "other ID", datastoreID: "ds-4", wantErr: ErrNoDatastoreFound},
+		{name: "without ID", datastoreID: "ds-6", wantErr: ErrInvalidDatastore},
+		{name: "without free space", datastoreID: "incomplete", wantErr: ErrInvalidDatastore},
+		{name: "invalid JSON", datastoreID: "broken", anyErr: true},
+		{name: "server error", datastoreID: "failing", anyErr: true},
+		{name: "empty ID", datastoreID: "", wantErr: ErrNoDatastoreFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			datastore, err := GetDatastoreByID(context.Background(), connection, test.datastoreID)
+			if test.wantErr != nil || test.anyErr {
+				if err == nil || (test.wantErr != nil && err != test.wantErr) {
+					t.Errorf("GetDatastoreByID() = %+v, %v, want error %v", datastore, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDatastoreByID() failed: %v", err)
+			}
+			want := &DatastoreInfo{
+				ID:           "ds-1",
+				Name:         "datastore1",
+				DataCenterID: "dc-1",
+				Type:         "NFS",
+				Capacity:     1024,
+				Hosts:        []DatastoreHost{{ID: "host-1", Name: "esx-1"}},
+				ClusterID:    "pod-1",
+				Tags:         []interface{}{"zone=zone-a"},
+			}
+			if !reflect.DeepEqual(datastore, want) {
+				t.Errorf("GetDatastoreByID() = %+v, want %+v", datastore, want)
+			}
+			if zone, _ := datastore.ZoneRegion("zone", "region"); zone != "zone-a" {
+				t.Errorf("Zone is %q, want zone-a", zone)
+			}
+		})
+	}
+
+	for _, request := range api.received() {
+		if request.method != http.MethodGet || request.authorization != fakeToken {
+			t.Errorf("Unexpected %s %s with authorization %q", request.method, request.path, request.authorization)
+		}
+	}
+}
+
+func TestGetAllDatastores(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]fakeResponse
+		want      []*DatastoreInfo
+		wantErr   error
+		anyErr    bool
+	}{
+		{
+			name: "datastores",
+			responses: map[string]fakeResponse{
+				"GET /datacenters/dc-1/storages": {body: `{"items":[
+					{"id":"ds-1","name":"datastore1","dataStoreType":"LOCAL","capacity":100,"avail":50},
+					{"id":"ds-2","name":"datastore2","dataCenterId":"dc-1","capacity":200,"avail":0}]}`},
+			},
+			want: []*DatastoreInfo{
+				{ID: "ds-1", Name: "datastore1", DataCenterID: "dc-1", Type: "LOCAL", Capacity: 100, FreeSpace: 50},
+				{ID: "ds-2", Name: "datastore2", DataCenterID: "dc-1", Capacity: 200},
+			},
+		},
+		{
+			name: "datastore without free space",
+			responses: map[string]fakeResponse{
+				"GET /datacenters/dc-1/storages": {body: `{"items":[
+					{"id":"ds-1","capacity":100,"avail":50},{"id":"ds-2","capacity":200}]}`},
+			},
+			wantErr: ErrInvalidDatastore,
+		},
+		{
+			name: "no datastore",
+			responses: map[string]fakeResponse{
+				"GET /datacenters/dc-1/storages": {body: `{"items":[]}`},
+			},
+			wantErr: ErrNoDatastoreFound,
+		},
+		{
+			name:      "not found",
+			responses: map[string]fakeResponse{},
+			anyErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, connection := newFakeAPI(t, test.responses)
+			defer api.close()
+			dc := &Datacenter{Datacenter: &tp.Datacenter{ID: "dc-1", Name: "dc1"}, connection: connection}
+
+			datastores, err := dc.GetAllDatastores(context.Background())
+			if test.wantErr != nil || test.anyErr {
+				if err == nil || (test.wantErr != nil && err != test.wantErr) {
+					t.Errorf("GetAllDatastores() = %+v, %v, want error %v", datastores, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAllDatastores() failed: %v", err)
+			}
+			if !reflect.DeepEqual(datastores, test.want) {
+				t.Errorf("GetAllDatastores() = %+v, want %+v", datastores, test.want)
+			}
+		})
+	}
+}
+
+func TestGetAllDatastoreClusters(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]fakeResponse
+		want      []*DatastoreClusterInfo
+		wantErr   error
+		anyErr    bool
+	}{
+		{
+			name: "clusters",
+			responses: map[string]fakeResponse{
+				"GET /datacenters/dc-1/storagepods": {body: `{"items":[
+					{"id":"pod-1","name":"pod1","capacity":300,"avail":50,"storageIds":["ds-1","ds-2"]}]}`},
+			},
+			want: []*DatastoreClusterInfo{
+				{ID: "pod-1", Name: "pod1", DataCenterID: "dc-1", Capacity: 300, FreeSpace: 50, DatastoreIDs: []string{"ds-1", "ds-2"}},
+			},
+		},
+		{
+			name: "cluster without ID",
+			responses: map[string]fakeResponse{
+				"GET /datacenters/dc-1/storagepods": {body: `{"items":[{"name":"pod1","capacity":300,"avail":50}]}`},
+			},
+			wantErr: ErrInvalidDatastore,
+		},
+		{
+			name: "no cluster",
+			responses: map[string]fakeResponse{
+				"GET /datacenters/dc-1/storagepods": {body: `{"items":[]}`},
+			},
+			wantErr: ErrNoDataStoreClustersFound,
+		},
+		{
+			name: "server error",
+			responses: map[string]fakeResponse{
+				"GET /datacenters/dc-1/storagepods": {status: http.StatusInternalServerError},
+			},
+			anyErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, connection := newFakeAPI(t, test.responses)
+			defer api.close()
+			dc := &Datacenter{Datacenter: &tp.Datacenter{ID: "dc-1", Name: "dc1"}, connection: connection}
+
+			clusters, err := dc.GetAllDatastoreClusters(context.Background())
+			if test.wantErr != nil || test.anyErr {
+				if err == nil || (test.wantErr != nil && err != test.wantErr) {
+					t.Errorf("GetAllDatastoreClusters() = %+v, %v, want error %v", clusters, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAllDatastoreClusters() failed: %v", err)
+			}
+			if !reflect.DeepEqual(clusters, test.want) {
+				t.Errorf("GetAllDatastoreClusters() = %+v, want %+v", clusters, test.want)
+			}
+		})
+	}
+}
+
+func TestGetAllStoragePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		vms     string
+		want    []*StoragePolicyInfo
+		wantErr error
+	}{
+		{
+			name: "policies of the disks",
+			vms: `{"items":[
+				{"id":"vm-1","disks":[
+					{"id":"disk-1","volume":{"id":"volume-1","volumePolicy":"THIN","dataStoreId":"ds-2"}},
+					{"id":"disk-2","volume":{"id":"volume-2","volumePolicy":"THICK","dataStoreId":"ds-1"}}]},
+				{"id":"vm-2","disks":[
+					{"id":"disk-3","volume":{"id":"volume-3","volumePolicy":"THIN","dataStoreId":"ds-1"}},
+					{"id":"disk-4","volume":{"id":"volume-4","volumePolicy":"THIN","dataStoreId":"ds-2"}},
+					{"id":"disk-5","volume":{"id":"volume-5","volumePolicy":"","dataStoreId":"ds-3"}},
+					{"id":"disk-6","volume":{"id":"volume-6","volumePolicy":"THIN"}}]}]}`,
+			want: []*StoragePolicyInfo{
+				{Name: "THICK", DatastoreIDs: []string{"ds-1"}},
+				{Name: "THIN", DatastoreIDs: []string{"ds-1", "ds-2"}},
+			},
+		},
+		{
+			name:    "disks without policies",
+			vms:     `{"items":[{"id":"vm-1","disks":[{"id":"disk-1","volume":{"id":"volume-1","dataStoreId":"ds-1"}}]}]}`,
+			wantErr: ErrNoStoragePoliciesFound,
+		},
+		{
+			name:    "no VM",
+			vms:     `{"items":[]}`,
+			wantErr: ErrNoStoragePoliciesFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, connection := newFakeAPI(t, map[string]fakeResponse{
+				"GET /datacenters/dc-1/vms": {body: test.vms},
+			})
+			defer api.close()
+			dc := &Datacenter{Datacenter: &tp.Datacenter{ID: "dc-1", Name: "dc1"}, connection: connection}
+
+			policies, err := dc.GetAllStoragePolicies(context.Background())
+			if err != test.wantErr {
+				t.Fatalf("GetAllStoragePolicies() = %+v, %v, want error %v", policies, err, test.wantErr)
+			}
+			if !reflect.DeepEqual(policies, test.want) {
+				t.Errorf("GetAllStoragePolicies() = %+v, want %+v", policies, test.want)
+			}
+			requests := api.received()
+			if len(requests) != 1 || requests[0].method != http.MethodGet || requests[0].path != "/datacenters/dc-1/vms" {
+				t.Errorf("Received %+v, want GET /datacenters/dc-1/vms", requests)
+			}
+		})
+	}
+
+	dc := &Datacenter{Datacenter: &tp.Datacenter{ID: "dc-1"}}
+	if _, err := dc.GetAllStoragePolicies(context.Background()); err != ErrNoStoragePoliciesFound {
+		t.Errorf("GetAllStoragePolicies() without a connection = %v, want %v", err, ErrNoStoragePoliciesFound)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/inspur-ics/ics-go-sdk/client/methods"
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	"k8s.io/klog"
+)
+
+// GetVolumeByID returns the volume with the given iCenter ID. The SDK has no
+// volume service, so the volume is read from the REST API directly, at
+// /volumes/{id} like the SDK reads VMs at /vms/{id}, and decoded as the SDK
+// Volume type of the VM disks. A volume that is not found, or whose ID does
+// not match, is reported as ErrNoDiskIDFound.
+func GetVolumeByID(ctx context.Context, connection *ICSConnection, volumeID string) (*tp.Volume, error) {
+	if volumeID == "" || connection == nil || connection.Client == nil {
+		return nil, ErrNoDiskIDFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/volumes/%s", volumeID)
+	api.Token = true
+
+	resp, err := connection.Client.GetTrip(ctx, api, nil)
+	if err == nil && resp.StatusCode() == http.StatusNotFound {
+		return nil, ErrNoDiskIDFound
+	}
+	respBody, err := methods.HandleResponse(resp, err)
+	if err != nil {
+		klog.Errorf("Failed to get volume %s. err: %+v", volumeID, err)
+		return nil, err
+	}
+
+	volume := &tp.Volume{}
+	if err := json.Unmarshal(respBody, volume); err != nil {
+		return nil, methods.JsonError(err)
+	}
+	if volume.ID != volumeID {
+		klog.Errorf("Volume %s was read with ID %q", volumeID, volume.ID)
+		return nil, ErrNoDiskIDFound
+	}
+	return volume, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGetVolumeByID(t *testing.T) {
+	api, connection := newFakeAPI(t, map[string]fakeResponse{
+		"GET /volumes/volume-1": {body: `{"id":"volume-1","uuid":"6000c29a","name":"pvc-1","size":10,
+			"dataStoreId":"ds-1","dataStoreName":"datastore1","volumeStatus":"AVAILABLE"}`},
+		"GET /volumes/volume-2":  {body: `{"id":"","name":"pvc-2"}`},
+		"GET /volumes/volume-3":  {body: `{"id":"volume-4"}`},
+		"GET /volumes/broken":    {body: `{"id":`},
+		"GET /volumes/failing":   {status: http.StatusInternalServerError},
+		"GET /volumes/forbidden": {status: http.StatusForbidden, body: `{"code":403,"message":"forbidden"}`},
+	})
+	defer api.close()
+
+	tests := []struct {
+		name     string
+		volumeID string
+		wantErr  error
+		anyErr   bool
+	}{
+		{name: "found", volumeID: "volume-1"},
+		{name: "not found", volumeID: "volume-0", wantErr: ErrNoDiskIDFound},
+		{name: "without ID", volumeID: "volume-2", wantErr: ErrNoDiskIDFound},
+		{name: "other ID", volumeID: "volume-3", wantErr: ErrNoDiskIDFound},
+		{name: "invalid JSON", volumeID: "broken", anyErr: true},
+		{name: "server error", volumeID: "failing", anyErr: true},
+		{name: "forbidden", volumeID: "forbidden", anyErr: true},
+		{name: "empty ID", volumeID: "", wantErr: ErrNoDiskIDFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			volume, err := GetVolumeByID(context.Background(), connection, test.volumeID)
+			if test.wantErr != nil || test.anyErr {
+				if err == nil || (test.wantErr != nil && err != test.wantErr) || (test.anyErr && err == ErrNoDiskIDFound) {
+					t.Errorf("GetVolumeByID() = %+v, %v, want error %v", volume, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetVolumeByID() failed: %v", err)
+			}
+			if volume.ID != "volume-1" || volume.UUID != "6000c29a" || volume.Name != "pvc-1" || volume.Size != 10 ||
+				volume.DataStoreID != "ds-1" || volume.DataStoreName != "datastore1" {
+				t.Errorf("GetVolumeByID() = %+v", volume)
+			}
+		})
+	}
+
+	requests := api.received()
+	if len(requests) == 0 {
+		t.Fatal("No request was received")
+	}
+	for _, request := range requests {
+		if request.method != http.MethodGet || request.authorization != fakeToken {
+			t.Errorf("Unexpected %s %s with authorization %q", request.method, request.path, request.authorization)
+		}
+	}
+	if requests[0].path != "/volumes/volume-1" {
+		t.Errorf("Volume read at %s, want /volumes/volume-1", requests[0].path)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"sort"
+	"strings"
+)
+
+// Disk bus models of the VM disks.
+const (
+	DiskBusVirtio = "VIRTIO"
+	DiskBusSCSI   = "SCSI"
+	DiskBusSATA   = "SATA"
+	DiskBusIDE    = "IDE"
+)
+
+// diskBusSlots is the number of disks a VM takes on each bus model. iCenter
+// does not report it, so these are estimates: IDE and SATA have the 4 and 6
+// ports of the emulated IDE and AHCI controllers, while VIRTIO, where each
+// disk takes a PCI slot, and SCSI are kept below the PCI slots and SCSI
+// targets of a VM. Free slots are a hint for scheduling, not a guarantee that
+// an attach succeeds.
+var diskBusSlots = map[string]int{
+	DiskBusVirtio: 16,
+	DiskBusSCSI:   15,
+	DiskBusSATA:   6,
+	DiskBusIDE:    4,
+}
+
+// DiskController is a disk controller of a VM. iCenter reports the bus model
+// of each disk rather than the controllers, so a VM has one controller per
+// bus model of its disks.
+type DiskController struct {
+	// Bus model, like VIRTIO or SCSI
+	Bus string
+	// Number of disks on the controller
+	Disks int
+	// Number of disks the controller takes, or 0 if the bus model is
+	// unknown
+	Slots int
+}
+
+// FreeSlots returns the number of disks the controller can still take. A
+// controller of an unknown bus model has none.
+func (c DiskController) FreeSlots() int {
+	if c.Slots <= c.Disks {
+		return 0
+	}
+	return c.Slots - c.Disks
+}
+
+// DiskControllers returns the disk controllers of the VM, sorted by bus
+// model. A VM always has a VIRTIO controller, where disks are attached by
+// default, even if its disks are on other buses.
+func (vm *VirtualMachine) DiskControllers() []DiskController {
+	if vm.VirtualMachine == nil {
+		return nil
+	}
+
+	disks := map[string]int{}
+	for _, disk := range vm.Disks {
+		bus := strings.ToUpper(strings.TrimSpace(disk.BusModel))
+		if bus == "" {
+			bus = DiskBusVirtio
+		}
+		disks[bus]++
+	}
+	if _, ok := disks[DiskBusVirtio]; !ok {
+		disks[DiskBusVirtio] = 0
+	}
+
+	controllers := make([]DiskController, 0, len(disks))
+	for bus, count := range disks {
+		controllers = append(controllers, DiskController{
+			Bus:   bus,
+			Disks: count,
+			Slots: diskBusSlots[bus],
+		})
+	}
+	sort.Slice(controllers, func(i, j int) bool {
+		return controllers[i].Bus < controllers[j].Bus
+	})
+	return controllers
+}
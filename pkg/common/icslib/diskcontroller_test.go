@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"reflect"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+)
+
+func TestDiskControllers(t *testing.T) {
+	disks := func(buses ...string) []tp.Disk {
+		var disks []tp.Disk
+		for _, bus := range buses {
+			disks = append(disks, tp.Disk{BusModel: bus})
+		}
+		return disks
+	}
+
+	tests := []struct {
+		name string
+		vm   *tp.VirtualMachine
+		want []DiskController
+	}{
+		{
+			name: "no disks",
+			vm:   &tp.VirtualMachine{},
+			want: []DiskController{{Bus: DiskBusVirtio, Disks: 0, Slots: 16}},
+		},
+		{
+			name: "virtio disks",
+			vm:   &tp.VirtualMachine{Disks: disks("VIRTIO", "virtio", "")},
+			want: []DiskController{{Bus: DiskBusVirtio, Disks: 3, Slots: 16}},
+		},
+		{
+			name: "other buses keep the default bus",
+			vm:   &tp.VirtualMachine{Disks: disks("SCSI", "IDE", "scsi")},
+			want: []DiskController{
+				{Bus: DiskBusIDE, Disks: 1, Slots: 4},
+				{Bus: DiskBusSCSI, Disks: 2, Slots: 15},
+				{Bus: DiskBusVirtio, Disks: 0, Slots: 16},
+			},
+		},
+		{
+			name: "unknown bus",
+			vm:   &tp.VirtualMachine{Disks: disks("NVME", "VIRTIO")},
+			want: []DiskController{
+				{Bus: "NVME", Disks: 1, Slots: 0},
+				{Bus: DiskBusVirtio, Disks: 1, Slots: 16},
+			},
+		},
+		{
+			name: "no VM",
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := &VirtualMachine{VirtualMachine: test.vm}
+			if got := vm.DiskControllers(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("DiskControllers() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiskControllerFreeSlots(t *testing.T) {
+	tests := []struct {
+		name       string
+		controller DiskController
+		want       int
+	}{
+		{name: "empty", controller: DiskController{Bus: DiskBusSATA, Slots: 6}, want: 6},
+		{name: "partly used", controller: DiskController{Bus: DiskBusSATA, Disks: 4, Slots: 6}, want: 2},
+		{name: "full", controller: DiskController{Bus: DiskBusIDE, Disks: 4, Slots: 4}, want: 0},
+		{name: "over the estimate", controller: DiskController{Bus: DiskBusIDE, Disks: 5, Slots: 4}, want: 0},
+		{name: "unknown bus", controller: DiskController{Bus: "NVME", Disks: 1}, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.controller.FreeSlots(); got != test.want {
+				t.Errorf("FreeSlots() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inspur-ics/ics-go-sdk/client/methods"
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	"k8s.io/klog"
+)
+
+// EventType is the kind of change reported by an iCenter event.
+type EventType string
+
+// Event types
+const (
+	// EventVMPoweredOn is a VM started or resumed.
+	EventVMPoweredOn EventType = "VMPoweredOn"
+	// EventVMPoweredOff is a VM stopped or shut down.
+	EventVMPoweredOff EventType = "VMPoweredOff"
+	// EventVMMigrated is a VM moved to another host.
+	EventVMMigrated EventType = "VMMigrated"
+	// EventVMDeleted is a VM removed from iCenter.
+	EventVMDeleted EventType = "VMDeleted"
+	// EventVMReconfigured is a change of the VM configuration, like its NICs.
+	EventVMReconfigured EventType = "VMReconfigured"
+	// EventVMIPChanged is a change of the IP addresses of a VM.
+	EventVMIPChanged EventType = "VMIPChanged"
+	// EventHostMaintenance is a host entering or exiting maintenance mode.
+	EventHostMaintenance EventType = "HostMaintenance"
+	// EventOther is any other event.
+	EventOther EventType = "Other"
+)
+
+// Event target types
+const (
+	// EventTargetVM is the target type of the events of a VM.
+	EventTargetVM = "VM"
+	// EventTargetHost is the target type of the events of a host.
+	EventTargetHost = "HOST"
+)
+
+// Event is a task of the iCenter task feed.
+type Event struct {
+	// ID of the task, unique within its iCenter.
+	ID string
+	// Type of the event, classified from RawType.
+	Type EventType
+	// Name of the task as reported by iCenter.
+	RawType string
+	// ID and type, like VM or HOST, of the object of the task.
+	TargetID   string
+	TargetType string
+	// Start time of the task.
+	Time time.Time
+}
+
+// eventTypes classify the iCenter task names. Matching is exact, ignoring
+// case: the names not listed are EventOther.
+var eventTypes = map[string]EventType{
+	"VM_POWER_ON":            EventVMPoweredOn,
+	"VM_RESUME":              EventVMPoweredOn,
+	"VM_POWER_OFF":           EventVMPoweredOff,
+	"VM_SHUTDOWN":            EventVMPoweredOff,
+	"VM_MIGRATE":             EventVMMigrated,
+	"VM_DELETE":              EventVMDeleted,
+	"VM_RECONFIGURE":         EventVMReconfigured,
+	"VM_IP_CHANGE":           EventVMIPChanged,
+	"HOST_ENTER_MAINTENANCE": EventHostMaintenance,
+	"HOST_EXIT_MAINTENANCE":  EventHostMaintenance,
+}
+
+// classifyEventType returns the type of an iCenter task name.
+func classifyEventType(rawType string) EventType {
+	if eventType, ok := eventTypes[strings.ToUpper(rawType)]; ok {
+		return eventType
+	}
+	return EventOther
+}
+
+const (
+	// eventsPageSize is the number of events read at once.
+	eventsPageSize = 200
+	// maxEventsPages is the number of pages read at most by GetEvents, so
+	// that a feed left unread for long does not page through its history.
+	maxEventsPages = 10
+)
+
+// taskPageResponse is a page of the task feed, laid out as the pages of the
+// SDK, like VMPageResponse.
+type taskPageResponse struct {
+	tp.PageResponse
+	Items []tp.TaskInfo `json:"items"`
+}
+
+// GetEvents returns the events of the iCenter at or after since, oldest
+// first. The pages of the task feed, newest first, are read until since is
+// reached, or for at most maxEventsPages pages. A zero since returns the most
+// recent page only.
+func GetEvents(ctx context.Context, connection *ICSConnection, since time.Time) ([]Event, error) {
+	if connection == nil || connection.Client == nil {
+		return nil, ErrNoEventsFound
+	}
+
+	return readEvents(func(page int) ([]tp.TaskInfo, error) {
+		return getEventsPage(ctx, connection, page)
+	}, since)
+}
+
+// getEventsPage returns the tasks of a page of the task feed, newest first.
+// Pages start at 1. The feed is the collection of the SDK /tasks/{id}
+// resource, listed like /vms/ with the paging fields of tp.PageReq.
+func getEventsPage(ctx context.Context, connection *ICSConnection, page int) ([]tp.TaskInfo, error) {
+	var api tp.ICSApi
+	api.Api = "/tasks/"
+	api.Token = true
+	query := map[string]string{
+		"pageSize":    strconv.Itoa(eventsPageSize),
+		"currentPage": strconv.Itoa(page),
+		"sortField":   "startTime",
+		"sort":        "desc",
+	}
+
+	resp, err := connection.Client.GetTrip(ctx, api, query)
+	respBody, err := methods.HandleResponse(resp, err)
+	if err != nil {
+		klog.Errorf("Failed to get page %d of the tasks. err: %+v", page, err)
+		return nil, err
+	}
+
+	var response taskPageResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, methods.JsonError(err)
+	}
+	return response.Items, nil
+}
+
+// readEvents returns the events at or after since of the pages returned by
+// getPage, oldest first. Tasks without an ID or a start time are logged and
+// skipped, as they cannot be ordered.
+func readEvents(getPage func(page int) ([]tp.TaskInfo, error), since time.Time) ([]Event, error) {
+	var events []Event
+	for page := 1; page <= maxEventsPages; page++ {
+		tasks, err := getPage(page)
+		if err != nil {
+			return nil, err
+		}
+
+		reached := false
+		for _, task := range tasks {
+			event, err := eventFromTask(task)
+			if err != nil {
+				klog.Warningf("Skipping task %q named %q: %v", task.Id, task.Name, err)
+				continue
+			}
+			if event.Time.Before(since) {
+				reached = true
+				continue
+			}
+			events = append(events, event)
+		}
+
+		if since.IsZero() || reached || len(tasks) < eventsPageSize {
+			break
+		}
+		if page == maxEventsPages {
+			klog.Warningf("Read the %d most recent events only, older events since %v are skipped",
+				maxEventsPages*eventsPageSize, since)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
+}
+
+// eventFromTask returns the event of a task returned by iCenter, whose start
+// time is in milliseconds since the epoch.
+func eventFromTask(task tp.TaskInfo) (Event, error) {
+	if task.Id == "" {
+		return Event{}, fmt.Errorf("no ID")
+	}
+	millis, err := strconv.ParseInt(strings.TrimSpace(task.StartTime), 10, 64)
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid start time %q", task.StartTime)
+	}
+
+	return Event{
+		ID:         task.Id,
+		Type:       classifyEventType(task.Name),
+		RawType:    task.Name,
+		TargetID:   task.TargetId,
+		TargetType: strings.ToUpper(task.TargetType),
+		Time:       time.Unix(0, millis*int64(time.Millisecond)),
+	}, nil
+}
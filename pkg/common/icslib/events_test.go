@@ -0,0 +1,231 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+)
+
+func TestReadEvents(t *testing.T) {
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	millis := func(offset int) string {
+		return strconv.FormatInt(base.Add(time.Duration(offset)*time.Second).UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	// feed returns n events, newest first, one second apart. The newest is
+	// at base+n-1 seconds and the oldest at base.
+	feed := func(n int) []tp.TaskInfo {
+		tasks := make([]tp.TaskInfo, 0, n)
+		for i := n - 1; i >= 0; i-- {
+			tasks = append(tasks, tp.TaskInfo{
+				Id:        strconv.Itoa(i),
+				Name:      "VM_POWER_ON",
+				StartTime: millis(i),
+			})
+		}
+		return tasks
+	}
+
+	tests := []struct {
+		name      string
+		items     []tp.TaskInfo
+		since     time.Time
+		wantPages int
+		wantFirst string
+		wantLen   int
+	}{
+		{
+			name:      "single page",
+			items:     feed(10),
+			since:     base,
+			wantPages: 1,
+			wantFirst: "0",
+			wantLen:   10,
+		},
+		{
+			name:      "pages until since",
+			items:     feed(3 * eventsPageSize),
+			since:     base.Add(50 * time.Second),
+			wantPages: 3,
+			wantFirst: "50",
+			wantLen:   3*eventsPageSize - 50,
+		},
+		{
+			name:      "stops at the page limit",
+			items:     feed((maxEventsPages + 2) * eventsPageSize),
+			since:     base,
+			wantPages: maxEventsPages,
+			wantFirst: strconv.Itoa(2 * eventsPageSize),
+			wantLen:   maxEventsPages * eventsPageSize,
+		},
+		{
+			name:      "zero since reads the most recent page",
+			items:     feed(3 * eventsPageSize),
+			wantPages: 1,
+			wantFirst: strconv.Itoa(2 * eventsPageSize),
+			wantLen:   eventsPageSize,
+		},
+		{
+			name: "tasks without an ID or a time are skipped",
+			items: []tp.TaskInfo{
+				{Id: "3", StartTime: millis(3)},
+				{Id: "", StartTime: millis(2)},
+				{Id: "1", StartTime: "yesterday"},
+				{Id: "0", StartTime: millis(0)},
+			},
+			since:     base,
+			wantPages: 1,
+			wantFirst: "0",
+			wantLen:   2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pages := 0
+			events, err := readEvents(func(page int) ([]tp.TaskInfo, error) {
+				pages++
+				start := (page - 1) * eventsPageSize
+				if start >= len(test.items) {
+					return nil, nil
+				}
+				end := start + eventsPageSize
+				if end > len(test.items) {
+					end = len(test.items)
+				}
+				return test.items[start:end], nil
+			}, test.since)
+			if err != nil {
+				t.Fatalf("readEvents() failed: %v", err)
+			}
+			if pages != test.wantPages {
+				t.Errorf("read %d pages, want %d", pages, test.wantPages)
+			}
+			if len(events) != test.wantLen {
+				t.Fatalf("got %d events, want %d", len(events), test.wantLen)
+			}
+			if events[0].ID != test.wantFirst {
+				t.Errorf("oldest event is %s, want %s", events[0].ID, test.wantFirst)
+			}
+			for i := 1; i < len(events); i++ {
+				if events[i].Time.Before(events[i-1].Time) {
+					t.Fatalf("events are not oldest first at %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyEventType(t *testing.T) {
+	tests := []struct {
+		rawType string
+		want    EventType
+	}{
+		{"VM_POWER_ON", EventVMPoweredOn},
+		{"vm_power_on", EventVMPoweredOn},
+		{"VM_RESUME", EventVMPoweredOn},
+		{"VM_POWER_OFF", EventVMPoweredOff},
+		{"VM_SHUTDOWN", EventVMPoweredOff},
+		{"VM_MIGRATE", EventVMMigrated},
+		{"VM_DELETE", EventVMDeleted},
+		{"VM_RECONFIGURE", EventVMReconfigured},
+		{"VM_IP_CHANGE", EventVMIPChanged},
+		{"HOST_ENTER_MAINTENANCE", EventHostMaintenance},
+		{"HOST_EXIT_MAINTENANCE", EventHostMaintenance},
+		// Names are matched exactly
+		{"VM_RESTART", EventOther},
+		{"VM_POWER_ON_BATCH", EventOther},
+		{"DELETE_SNAPSHOT", EventOther},
+		{"VM_IP_CHANGE ", EventOther},
+		{"", EventOther},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rawType, func(t *testing.T) {
+			if got := classifyEventType(test.rawType); got != test.want {
+				t.Errorf("classifyEventType(%q) = %s, want %s", test.rawType, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+	api, connection := newFakeAPI(t, map[string]fakeResponse{
+		"GET /tasks": {body: `{"totalPage":1,"currentPage":1,"totalSize":4,"items":[
+			{"id":"task-3","name":"HOST_ENTER_MAINTENANCE","state":"FINISHED","startTime":"1577880003000",
+				"targetId":"host-1","targetType":"host"},
+			{"id":"","name":"VM_POWER_ON","startTime":"1577880002000"},
+			{"id":"task-1","name":"VM_POWER_OFF","state":"FINISHED","startTime":"1577880001000",
+				"targetId":"vm-1","targetName":"node1","targetType":"VM"},
+			{"id":"task-0","name":"VM_RESTART","startTime":"not a time","targetId":"vm-1","targetType":"VM"}]}`},
+	})
+	defer api.close()
+
+	events, err := GetEvents(context.Background(), connection, time.Time{})
+	if err != nil {
+		t.Fatalf("GetEvents() failed: %v", err)
+	}
+	want := []Event{
+		{ID: "task-1", Type: EventVMPoweredOff, RawType: "VM_POWER_OFF", TargetID: "vm-1", TargetType: EventTargetVM,
+			Time: time.Unix(1577880001, 0)},
+		{ID: "task-3", Type: EventHostMaintenance, RawType: "HOST_ENTER_MAINTENANCE", TargetID: "host-1", TargetType: EventTargetHost,
+			Time: time.Unix(1577880003, 0)},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("GetEvents() = %+v, want %+v", events, want)
+	}
+
+	requests := api.received()
+	if len(requests) != 1 {
+		t.Fatalf("Received %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	if request.method != http.MethodGet || request.path != "/tasks" || request.authorization != fakeToken {
+		t.Errorf("Unexpected %s %s with authorization %q", request.method, request.path, request.authorization)
+	}
+	wantQuery := map[string]string{"pageSize": "200", "currentPage": "1", "sortField": "startTime", "sort": "desc"}
+	for key, value := range wantQuery {
+		if got := request.query.Get(key); got != value {
+			t.Errorf("Query parameter %s = %q, want %q", key, got, value)
+		}
+	}
+	if len(request.query) != len(wantQuery) {
+		t.Errorf("Query = %v, want %v", request.query, wantQuery)
+	}
+
+	if _, err := GetEvents(context.Background(), nil, time.Time{}); err != ErrNoEventsFound {
+		t.Errorf("GetEvents() without a connection = %v, want %v", err, ErrNoEventsFound)
+	}
+}
+
+func TestGetEventsFailure(t *testing.T) {
+	api, connection := newFakeAPI(t, map[string]fakeResponse{
+		"GET /tasks": {status: http.StatusInternalServerError},
+	})
+	defer api.close()
+
+	if events, err := GetEvents(context.Background(), connection, time.Time{}); err == nil {
+		t.Errorf("GetEvents() = %+v, want an error", events)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeToken is the session token the fakeAPI hands out on login.
+const fakeToken = "session"
+
+// fakeResponse is the canned response of a fakeAPI.
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+// fakeRequest is a request received by a fakeAPI.
+type fakeRequest struct {
+	method        string
+	path          string
+	query         url.Values
+	body          string
+	authorization string
+}
+
+// fakeAPI is an iCenter REST API answering with canned responses keyed by
+// "METHOD path". Unknown paths are not found. Paths are cleaned since the SDK
+// joins the base URL and the API path with a double slash.
+type fakeAPI struct {
+	server    *httptest.Server
+	responses map[string]fakeResponse
+
+	lock     sync.Mutex
+	requests []fakeRequest
+}
+
+// newFakeAPI starts a fakeAPI and returns it with a connection logged in to
+// it.
+func newFakeAPI(t *testing.T, responses map[string]fakeResponse) (*fakeAPI, *ICSConnection) {
+	api := &fakeAPI{responses: responses}
+	api.server = httptest.NewTLSServer(http.HandlerFunc(api.serveHTTP))
+
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(api.server.URL, "https://"))
+	if err != nil {
+		api.server.Close()
+		t.Fatal(err)
+	}
+	connection := &ICSConnection{}
+	connection.Hostname = host
+	connection.Port = port
+	connection.Insecure = true
+	connection.Username = "admin"
+	connection.Password = "secret"
+	if err := connection.Connect(context.Background()); err != nil {
+		api.server.Close()
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	return api, connection
+}
+
+func (api *fakeAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	requestPath := path.Clean(r.URL.Path)
+	if r.Method == http.MethodPost && requestPath == "/authentication" {
+		w.Write([]byte(`{"userId":"admin","sessonId":"` + fakeToken + `"}`))
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	api.lock.Lock()
+	api.requests = append(api.requests, fakeRequest{
+		method:        r.Method,
+		path:          requestPath,
+		query:         r.URL.Query(),
+		body:          string(body),
+		authorization: r.Header.Get("Authorization"),
+	})
+	api.lock.Unlock()
+
+	response, ok := api.responses[r.Method+" "+requestPath]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if response.status != 0 {
+		w.WriteHeader(response.status)
+	}
+	w.Write([]byte(response.body))
+}
+
+// received returns the requests received so far, except logins.
+func (api *fakeAPI) received() []fakeRequest {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	return append([]fakeRequest(nil), api.requests...)
+}
+
+// close stops the server.
+func (api *fakeAPI) close() {
+	api.server.Close()
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/inspur-ics/ics-go-sdk/client/methods"
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	"k8s.io/klog"
+)
+
+// GetHostByID returns the host with the given iCenter ID. The SDK has no
+// host service, so the host is read from the REST API directly, at
+// /hosts/{id} like the SDK reads VMs at /vms/{id}, and decoded as the SDK
+// Host type. A host that is not found, or whose ID does not match, is
+// reported as ErrNoHostFound.
+func GetHostByID(ctx context.Context, connection *ICSConnection, hostID string) (*Host, error) {
+	if hostID == "" || connection == nil || connection.Client == nil {
+		return nil, ErrNoHostFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/hosts/%s", hostID)
+	api.Token = true
+
+	resp, err := connection.Client.GetTrip(ctx, api, nil)
+	if err == nil && resp.StatusCode() == http.StatusNotFound {
+		return nil, ErrNoHostFound
+	}
+	respBody, err := methods.HandleResponse(resp, err)
+	if err != nil {
+		klog.Errorf("Failed to get host %s. err: %+v", hostID, err)
+		return nil, err
+	}
+
+	host := &tp.Host{}
+	if err := json.Unmarshal(respBody, host); err != nil {
+		return nil, methods.JsonError(err)
+	}
+	if host.ID != hostID {
+		klog.Errorf("Host %s was read with ID %q", hostID, host.ID)
+		return nil, ErrNoHostFound
+	}
+	return &Host{host}, nil
+}
+
+// HostSystem returns the host running the VM.
+func (vm *VirtualMachine) HostSystem(ctx context.Context, connection *ICSConnection) (*Host, error) {
+	if vm.VirtualMachine == nil {
+		return nil, ErrNoHostFound
+	}
+	return GetHostByID(ctx, connection, vm.HostID)
+}
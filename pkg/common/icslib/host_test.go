@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGetHostByID(t *testing.T) {
+	api, connection := newFakeAPI(t, map[string]fakeResponse{
+		"GET /hosts/host-1": {body: `{"id":"host-1","name":"esx-1","hostName":"esx-1.example.com",
+			"dataCenterId":"dc-1","clusterId":"cluster-1","clusterName":"cluster","status":"MAINTAIN"}`},
+		"GET /hosts/host-2":  {body: `{"id":"","name":"esx-2"}`},
+		"GET /hosts/host-3":  {body: `{"id":"host-4"}`},
+		"GET /hosts/broken":  {body: `{"id":`},
+		"GET /hosts/failing": {status: http.StatusInternalServerError},
+	})
+	defer api.close()
+
+	tests := []struct {
+		name    string
+		hostID  string
+		wantErr error
+		anyErr  bool
+	}{
+		{name: "found", hostID: "host-1"},
+		{name: "not found", hostID: "host-0", wantErr: ErrNoHostFound},
+		{name: "without ID", hostID: "host-2", wantErr: ErrNoHostFound},
+		{name: "other ID", hostID: "host-3", wantErr: ErrNoHostFound},
+		{name: "invalid JSON", hostID: "broken", anyErr: true},
+		{name: "server error", hostID: "failing", anyErr: true},
+		{name: "empty ID", hostID: "", wantErr: ErrNoHostFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			host, err := GetHostByID(context.Background(), connection, test.hostID)
+			if test.wantErr != nil || test.anyErr {
+				if err == nil || (test.wantErr != nil && err != test.wantErr) {
+					t.Errorf("GetHostByID() = %+v, %v, want error %v", host, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetHostByID() failed: %v", err)
+			}
+			if host.ID != "host-1" || host.Name != "esx-1" || host.ClusterID != "cluster-1" || host.ClusterName != "cluster" || host.DataCenterID != "dc-1" {
+				t.Errorf("GetHostByID() = %+v", host.Host)
+			}
+			if !host.InMaintenance() {
+				t.Error("The host is not in maintenance")
+			}
+		})
+	}
+
+	requests := api.received()
+	if len(requests) == 0 {
+		t.Fatal("No request was received")
+	}
+	for _, request := range requests {
+		if request.method != http.MethodGet || request.authorization != fakeToken {
+			t.Errorf("Unexpected %s %s with authorization %q", request.method, request.path, request.authorization)
+		}
+	}
+	if requests[0].path != "/hosts/host-1" {
+		t.Errorf("The host was read from %s", requests[0].path)
+	}
+}
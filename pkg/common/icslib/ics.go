@@ -18,6 +18,8 @@ package icslib
 
 import (
 	"context"
+	"net"
+	"net/url"
 	"sync"
 
 	//	"errors"
@@ -26,9 +28,13 @@ import (
 //	"strings"
 //	"sync"
 
-//	"k8s.io/klog"
+	"k8s.io/klog"
 	tp "github.com/inspur-ics/ics-go-sdk/client/types"
 	icssdk "github.com/inspur-ics/ics-go-sdk"
+	"github.com/inspur-ics/ics-go-sdk/client"
+	"github.com/inspur-ics/ics-go-sdk/client/methods"
+	"github.com/inspur-ics/ics-go-sdk/client/restful"
+	"github.com/inspur-ics/ics-go-sdk/session"
 )
 
 
@@ -40,13 +46,25 @@ type ICSConnection struct {
 //	Hostname          string
 //	Port              string
 //	Insecure          bool
+	// CACert is the path to a PEM encoded CA bundle used to verify iCenter.
+	CACert            string
+	// CAData is a PEM encoded CA bundle that takes precedence over CACert.
+	CAData            string
+	// Thumbprint pins the iCenter leaf certificate by SHA-1 or SHA-256 fingerprint.
+	Thumbprint        string
+	// Token is a pre-issued session token used instead of Username and Password.
+	Token             string
 	ICSCredentialsLock   sync.Mutex
 	RoundTripperCount uint
+	// clientLock serializes the creation and replacement of Client.
+	clientLock sync.Mutex
 }
 
 // Datacenter extends the govmomi Datacenter object
 type Datacenter struct {
 	*tp.Datacenter
+	// connection to the iCenter of the datacenter
+	connection *ICSConnection
 }
 
 type Host struct {
@@ -58,24 +76,138 @@ type VirtualMachine struct {
 	//	Datacenter *Datacenter
 }
 
-/*
+// FirstClassDiskInfo is an ICS volume and the datastore holding it.
 type FirstClassDiskInfo struct {
-	//	*FirstClassDisk
+	*tp.Volume
 
-	//	DatastoreInfo  *DatastoreInfo
-	//	StoragePodInfo *StoragePodInfo
-	//ics added
-	name          string
+	DatastoreInfo *DatastoreInfo
 }
- */
 
-// Connect makes connection to vCenter and sets VSphereConnection.Client.
+// Connect makes connection to iCenter and sets ICSConnection.Client.
 // If connection.Client is already set, it obtains the existing user session.
 // if user session is not valid, connection.Client will be set to the new client.
+//
+// Connect, GetClient and NewClient shadow the ones of the embedded SDK
+// connection, which always create their client without the TLS options.
 func (connection *ICSConnection) Connect(ctx context.Context) error {
+	var err error
+	connection.clientLock.Lock()
+	defer connection.clientLock.Unlock()
+
+	if connection.Client == nil {
+		connection.Client, err = connection.NewClient(ctx)
+		if err != nil {
+			klog.Errorf("Failed to create ics-go-sdk client. err: %+v", err)
+			return err
+		}
+		return nil
+	}
+	m := session.NewManager(connection.Client)
+	userSession, err := m.UserSession(ctx)
+	if err != nil {
+		klog.Errorf("Error while obtaining user session. err: %+v", err)
+		return err
+	}
+	if userSession != nil {
+		return nil
+	}
+	klog.Warning("Creating new client session since the existing session is not valid or not authenticated")
+
+	connection.Client, err = connection.NewClient(ctx)
+	if err != nil {
+		klog.Errorf("Failed to create ics-go-sdk client. err: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// Reconnect replaces the client of a connected ICSConnection with a new one
+// logged in with the current credentials. Requests in flight complete with
+// the previous client, which is never reset to nil. A connection without
+// client is left alone, it connects on its next use.
+func (connection *ICSConnection) Reconnect(ctx context.Context) error {
+	connection.clientLock.Lock()
+	connected := connection.Client != nil
+	connection.clientLock.Unlock()
+	if !connected {
+		return nil
+	}
+
+	client, err := connection.NewClient(ctx)
+	if err != nil {
+		klog.Errorf("Failed to create ics-go-sdk client. err: %+v", err)
+		return err
+	}
+
+	connection.clientLock.Lock()
+	connection.Client = client
+	connection.clientLock.Unlock()
 	return nil
 }
 
+// GetClient returns the client of the connection, connecting first if there
+// is no client or its session is no longer valid.
+func (connection *ICSConnection) GetClient(ctx context.Context) (*client.Client, error) {
+	if err := connection.Connect(ctx); err != nil {
+		return nil, err
+	}
+	connection.clientLock.Lock()
+	defer connection.clientLock.Unlock()
+	return connection.Client, nil
+}
+
+// login calls SessionManager.Login with user and password, or uses the
+// session token if one is set.
+func (connection *ICSConnection) login(ctx context.Context, client *client.Client) error {
+	m := session.NewManager(client)
+	connection.ICSCredentialsLock.Lock()
+	defer connection.ICSCredentialsLock.Unlock()
+
+	if connection.Token != "" {
+		klog.V(3).Infof("Using session token for %q", connection.Hostname)
+		client.SetToken(connection.Token)
+		return nil
+	}
+
+	klog.V(3).Infof("SessionManager.Login with username %q", connection.Username)
+	return m.Login(ctx, url.UserPassword(connection.Username, connection.Password))
+}
+
+// NewClient creates a new ics-go-sdk client for the ICSConnection obj
+// honoring the configured CA bundle, thumbprint and insecure flag. The SDK
+// client is created from a REST tripper whose TLS config is set first.
+func (connection *ICSConnection) NewClient(ctx context.Context) (*client.Client, error) {
+	// The endpoint may be overridden by the credentials concurrently
+	connection.ICSCredentialsLock.Lock()
+	hostname := connection.Hostname
+	url, err := restful.ParseURL(net.JoinHostPort(hostname, connection.Port))
+	if err != nil {
+		connection.ICSCredentialsLock.Unlock()
+		klog.Errorf("Failed to parse URL: %s. err: %+v", url, err)
+		return nil, err
+	}
+	tlsConfig, err := connection.TLSConfig()
+	connection.ICSCredentialsLock.Unlock()
+	if err != nil {
+		klog.Errorf("Failed to build TLS config for %s. err: %+v", hostname, err)
+		return nil, err
+	}
+
+	sc := restful.NewClient(url, connection.Insecure)
+	sc.HttpClient.SetTLSClientConfig(tlsConfig)
+
+	client, err := client.NewClient(ctx, sc)
+	if err != nil {
+		klog.Errorf("Failed to create new client. err: %+v", err)
+		return nil, err
+	}
+	err = connection.login(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // Logout calls SessionManager.Logout for the given connection.
 func (connection *ICSConnection) Logout(ctx context.Context) {
 	return
@@ -84,14 +216,7 @@ func (connection *ICSConnection) Logout(ctx context.Context) {
 // IsActive checks if the VM is active.
 // Returns true if VM is in poweredOn state.
 func (vm *VirtualMachine) IsActive(ctx context.Context) (bool, error) {
-	if vm.Status == "STARTED" {
-		return true, nil
-	}
-	return false, nil
-}
-
-func (vm *VirtualMachine) HostSystem(ctx context.Context) (*Host, error) {
-	return nil, nil
+	return vm.PowerState() == PowerStateOn, nil
 }
 
 // IsInvalidCredentialsError returns true if error is of type InvalidLogin
@@ -103,16 +228,36 @@ func IsInvalidCredentialsError(err error) bool {
 	return isInvalidCredentialsError
 }
 
-// GetDatacenter returns the DataCenter Object for the given datacenterPath
-// If datacenter is located in a folder, include full path to datacenter else just provide the datacenter name
+// GetDatacenter returns the DataCenter Object with the given name or ID
 func GetDatacenter(ctx context.Context, connection *ICSConnection, datacenterName string) (*Datacenter, error) {
-	dc := Datacenter{}
-	return &dc, nil
+	datacenters, err := GetAllDatacenter(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	for _, dc := range datacenters {
+		if dc.Name() == datacenterName || dc.ID == datacenterName {
+			return dc, nil
+		}
+	}
+	klog.Errorf("Failed to find datacenter %s", datacenterName)
+	return nil, ErrNoDatacenterFound
 }
 
 // GetAllDatacenter returns all the DataCenter Objects
 func GetAllDatacenter(ctx context.Context, connection *ICSConnection) ([]*Datacenter, error) {
+	if connection == nil || connection.Client == nil {
+		return nil, ErrNoDatacenterFound
+	}
+	page, err := methods.GetAllDatacenterList(ctx, connection.Client)
+	if err != nil {
+		klog.Errorf("Failed to get datacenters. err: %+v", err)
+		return nil, err
+	}
+
 	var dc []*Datacenter
+	for i := range page.Items {
+		dc = append(dc, &Datacenter{Datacenter: &page.Items[i], connection: connection})
+	}
 	return dc, nil
 }
 
@@ -121,32 +266,53 @@ func GetNumberOfDatacenters(ctx context.Context, connection *ICSConnection) (int
 	return 10, nil
 }
 
-/*
-// DoesFirstClassDiskExist returns information about an FCD if it exists.
-func (dc *Datacenter) DoesFirstClassDiskExist(ctx context.Context, fcdID string) (*FirstClassDiskInfo, error) {
-	klog.Infof("DoesFirstClassDiskExist(%s): NOT FOUND", fcdID)
-	return nil, ErrNoDiskIDFound
+// GetFirstClassDisk returns information about an FCD if it exists in the
+// iCenter. The volume and its datastore are read once, callers match the
+// result against each datacenter with HoldsFirstClassDisk.
+func GetFirstClassDisk(ctx context.Context, connection *ICSConnection, fcdID string) (*FirstClassDiskInfo, error) {
+	volume, err := GetVolumeByID(ctx, connection, fcdID)
+	if err != nil {
+		return nil, err
+	}
+	datastore, err := GetDatastoreByID(ctx, connection, volume.DataStoreID)
+	if err != nil {
+		return nil, err
+	}
+	return &FirstClassDiskInfo{Volume: volume, DatastoreInfo: datastore}, nil
+}
+
+// HoldsFirstClassDisk returns true if the FCD is held by a datastore of the
+// datacenter.
+func (dc *Datacenter) HoldsFirstClassDisk(fcd *FirstClassDiskInfo) bool {
+	return fcd.DatastoreInfo.DataCenterID == dc.ID
+}
+
+// DatastoreName returns the name of the datastore holding the FCD.
+func (fcd *FirstClassDiskInfo) DatastoreName() string {
+	if fcd.DataStoreName != "" {
+		return fcd.DataStoreName
+	}
+	return fcd.DatastoreInfo.Name
 }
- */
 
 // GetVMByIP gets the VM object from the given IP address
 func (dc *Datacenter) GetVMByIP(ctx context.Context, ipAddy string) (*VirtualMachine, error) {
-	return nil, nil
+	return nil, ErrNoVMFound
 }
 
 // GetVMByDNSName gets the VM object from the given dns name
 func (dc *Datacenter) GetVMByDNSName(ctx context.Context, dnsName string) (*VirtualMachine, error) {
-	return nil, nil
+	return nil, ErrNoVMFound
 }
 
 // GetVMByUUID gets the VM object from the given vmUUID
 func (dc *Datacenter) GetVMByUUID(ctx context.Context, vmUUID string) (*VirtualMachine, error) {
-	return nil, nil
+	return nil, ErrNoVMFound
 }
 
 // GetVMByUUID gets the VM object from the given vmUUID
 func (dc *Datacenter) Name() string {
-	return dc.dcname
+	return dc.Datacenter.Name
 }
 
 
@@ -158,4 +324,29 @@ func (connection *ICSConnection) UpdateCredentials(username string, password str
 	connection.Username = username
 	connection.Password = password
 }
+
+// UpdateEndpoint overrides the address, port and PEM encoded CA bundle of
+// iCenter. Empty values keep the current ones.
+// Note: The new endpoint will be used when there is no session active
+func (connection *ICSConnection) UpdateEndpoint(hostname string, port string, caData string) {
+	connection.ICSCredentialsLock.Lock()
+	defer connection.ICSCredentialsLock.Unlock()
+	if hostname != "" {
+		connection.Hostname = hostname
+	}
+	if port != "" {
+		connection.Port = port
+	}
+	if caData != "" {
+		connection.CAData = caData
+	}
+}
+
+// UpdateToken updates the session token.
+// Note: Updated token will be used when there is no session active
+func (connection *ICSConnection) UpdateToken(token string) {
+	connection.ICSCredentialsLock.Lock()
+	defer connection.ICSCredentialsLock.Unlock()
+	connection.Token = token
+}
 /********************************************************************************************/
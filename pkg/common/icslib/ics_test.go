@@ -0,0 +1,92 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+)
+
+func TestFirstClassDisk(t *testing.T) {
+	dc := &Datacenter{Datacenter: &tp.Datacenter{ID: "dc-1"}}
+
+	tests := []struct {
+		name      string
+		fcd       *FirstClassDiskInfo
+		held      bool
+		datastore string
+	}{
+		{
+			name: "volume names its datastore",
+			fcd: &FirstClassDiskInfo{
+				Volume:        &tp.Volume{DataStoreID: "ds-1", DataStoreName: "local-1"},
+				DatastoreInfo: &DatastoreInfo{ID: "ds-1", Name: "other", DataCenterID: "dc-1"},
+			},
+			held:      true,
+			datastore: "local-1",
+		},
+		{
+			name: "datastore name used as fallback",
+			fcd: &FirstClassDiskInfo{
+				Volume:        &tp.Volume{DataStoreID: "ds-2"},
+				DatastoreInfo: &DatastoreInfo{ID: "ds-2", Name: "nfs-2", DataCenterID: "dc-2"},
+			},
+			held:      false,
+			datastore: "nfs-2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := dc.HoldsFirstClassDisk(test.fcd); got != test.held {
+				t.Errorf("HoldsFirstClassDisk() = %v, want %v", got, test.held)
+			}
+			if got := test.fcd.DatastoreName(); got != test.datastore {
+				t.Errorf("DatastoreName() = %q, want %q", got, test.datastore)
+			}
+		})
+	}
+}
+
+func TestUpdateEndpoint(t *testing.T) {
+	tests := []struct {
+		name                   string
+		hostname, port, ca     string
+		wantHostname, wantPort string
+		wantCA                 string
+	}{
+		{name: "no override", wantHostname: "10.0.0.1", wantPort: "443", wantCA: "old"},
+		{name: "port only", port: "8443", wantHostname: "10.0.0.1", wantPort: "8443", wantCA: "old"},
+		{name: "server and CA", hostname: "icenter.example.com", ca: "new", wantHostname: "icenter.example.com", wantPort: "443", wantCA: "new"},
+		{name: "everything", hostname: "icenter.example.com", port: "8443", ca: "new", wantHostname: "icenter.example.com", wantPort: "8443", wantCA: "new"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connection := &ICSConnection{CAData: "old"}
+			connection.Hostname = "10.0.0.1"
+			connection.Port = "443"
+
+			connection.UpdateEndpoint(test.hostname, test.port, test.ca)
+			if connection.Hostname != test.wantHostname || connection.Port != test.wantPort || connection.CAData != test.wantCA {
+				t.Errorf("endpoint = %s:%s ca %q, want %s:%s ca %q", connection.Hostname, connection.Port, connection.CAData,
+					test.wantHostname, test.wantPort, test.wantCA)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	"k8s.io/klog"
+)
+
+// NIC is a network interface of a VirtualMachine, normalized from the
+// iCenter API which reports the addresses of a NIC as a single string.
+type NIC struct {
+	// MAC address, lowercased.
+	MAC string
+	// Name of the VM network the NIC is attached to.
+	NetworkName string
+	// IP addresses of the NIC with their prefix, in the order reported.
+	IPs []net.IPNet
+	// Default gateway configured on the NIC, if any.
+	Gateway net.IP
+	// True if the NIC is connected to its network.
+	Connected bool
+	// Position of the NIC in the sorted NICs of the VM, starting at 0. It
+	// only orders the NICs and does not identify the device.
+	DeviceIndex int
+	// iCenter device ID of the vNIC. Empty if the NIC is not backed by a
+	// vNIC of the VM configuration.
+	DeviceID string
+}
+
+// IsVirtual returns true if the NIC is backed by a vNIC of the VM
+// configuration, rather than only reported by the guest tools. Only vNICs
+// have an iCenter device ID.
+func (nic *NIC) IsVirtual() bool {
+	return nic.DeviceID != ""
+}
+
+// NICs returns the normalized network interfaces of the VM in device order.
+// The iCenter API does not return NICs in a stable order, so they are sorted
+// by the device number ending their name, then by MAC address.
+func (vm *VirtualMachine) NICs() []NIC {
+	if vm.VirtualMachine == nil {
+		return nil
+	}
+
+	sdkNics := make([]tp.Nic, len(vm.Nics))
+	copy(sdkNics, vm.Nics)
+	sort.SliceStable(sdkNics, func(i, j int) bool {
+		iNumber, jNumber := deviceNumber(sdkNics[i].Name), deviceNumber(sdkNics[j].Name)
+		if iNumber != jNumber {
+			return iNumber < jNumber
+		}
+		return strings.ToLower(sdkNics[i].Mac) < strings.ToLower(sdkNics[j].Mac)
+	})
+
+	nics := make([]NIC, 0, len(sdkNics))
+	for i, nic := range sdkNics {
+		nics = append(nics, newNIC(i, nic))
+	}
+	return nics
+}
+
+// deviceNumber returns the number ending a device name, like 1 for "nic1",
+// or -1 if there is none.
+func deviceNumber(name string) int {
+	end := len(name)
+	start := end
+	for start > 0 && name[start-1] >= '0' && name[start-1] <= '9' {
+		start--
+	}
+	number, err := strconv.Atoi(name[start:end])
+	if err != nil {
+		return -1
+	}
+	return number
+}
+
+// newNIC normalizes the index-th NIC of a VM.
+func newNIC(index int, nic tp.Nic) NIC {
+	normalized := NIC{
+		MAC:         strings.ToLower(strings.TrimSpace(nic.Mac)),
+		NetworkName: nic.NetworkName,
+		Connected:   nic.ConnectStatus,
+		DeviceIndex: index,
+		DeviceID:    strings.TrimSpace(nic.DeviceID),
+		Gateway:     net.ParseIP(strings.TrimSpace(nic.Gateway)),
+	}
+
+	netmasks := splitAddressList(nic.Netmask)
+	for i, addr := range splitAddressList(nic.IP) {
+		netmask := ""
+		if i < len(netmasks) {
+			netmask = netmasks[i]
+		}
+		ipNet, err := parseNICAddress(addr, netmask)
+		if err != nil {
+			klog.V(4).Infof("Skipping address %q of NIC %s: %v", addr, normalized.MAC, err)
+			continue
+		}
+		normalized.IPs = append(normalized.IPs, *ipNet)
+	}
+
+	return normalized
+}
+
+// splitAddressList splits a list of addresses separated by commas, semicolons
+// or whitespace.
+func splitAddressList(list string) []string {
+	return strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+// parseNICAddress parses an IP address, either in CIDR notation or with a
+// netmask given as a dotted quad or a prefix length. Without netmask the
+// address is a host address.
+func parseNICAddress(addr string, netmask string) (*net.IPNet, error) {
+	if strings.Contains(addr, "/") {
+		ip, ipNet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &net.IPNet{IP: ip, Mask: ipNet.Mask}, nil
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, &net.ParseError{Type: "IP address", Text: addr}
+	}
+	bits := net.IPv6len * 8
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = net.IPv4len * 8
+	}
+
+	mask := net.CIDRMask(bits, bits)
+	if prefix, err := strconv.Atoi(netmask); err == nil && prefix >= 0 && prefix <= bits {
+		mask = net.CIDRMask(prefix, bits)
+	} else if dotted := net.ParseIP(netmask).To4(); dotted != nil && bits == net.IPv4len*8 {
+		mask = net.IPMask(dotted)
+	}
+	return &net.IPNet{IP: ip, Mask: mask}, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"reflect"
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+)
+
+func TestNICsOrder(t *testing.T) {
+	nics := []tp.Nic{
+		{Name: "nic10", Mac: "FA:16:3E:00:00:0A", DeviceID: "device-10"},
+		{Name: "nic2", Mac: "fa:16:3e:00:00:02", DeviceID: "device-2"},
+		{Name: "eth", Mac: "fa:16:3e:00:00:ff"},
+		{Name: "nic1", Mac: "fa:16:3e:00:00:11", DeviceID: "device-1b"},
+		{Name: "nic1", Mac: "fa:16:3e:00:00:01", DeviceID: "device-1a"},
+	}
+	want := []string{
+		"fa:16:3e:00:00:ff",
+		"fa:16:3e:00:00:01",
+		"fa:16:3e:00:00:11",
+		"fa:16:3e:00:00:02",
+		"fa:16:3e:00:00:0a",
+	}
+
+	// Every rotation of the NICs must give the same order.
+	for shift := range nics {
+		shuffled := append(append([]tp.Nic{}, nics[shift:]...), nics[:shift]...)
+		vm := &VirtualMachine{VirtualMachine: &tp.VirtualMachine{Nics: shuffled}}
+
+		var got []string
+		for i, nic := range vm.NICs() {
+			if nic.DeviceIndex != i {
+				t.Errorf("shift %d: NIC %s has device index %d, want %d", shift, nic.MAC, nic.DeviceIndex, i)
+			}
+			got = append(got, nic.MAC)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("shift %d: NICs() = %v, want %v", shift, got, want)
+		}
+	}
+}
+
+func TestNICIsVirtual(t *testing.T) {
+	tests := []struct {
+		name string
+		nic  tp.Nic
+		want bool
+	}{
+		{name: "vNIC", nic: tp.Nic{Name: "nic0", Mac: "fa:16:3e:00:00:01", DeviceID: "device-1"}, want: true},
+		{name: "vNIC without MAC", nic: tp.Nic{Name: "nic0", DeviceID: "device-1"}, want: true},
+		{name: "guest interface with MAC", nic: tp.Nic{Name: "docker0", Mac: "02:42:ac:11:00:01"}, want: false},
+		{name: "blank device ID", nic: tp.Nic{Name: "nic0", Mac: "fa:16:3e:00:00:01", DeviceID: " "}, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nic := newNIC(0, test.nic)
+			if got := nic.IsVirtual(); got != test.want {
+				t.Errorf("IsVirtual() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
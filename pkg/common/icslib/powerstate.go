@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"strings"
+)
+
+// PowerState is the power state of a VirtualMachine.
+type PowerState string
+
+// Power states
+const (
+	// PowerStateOn is a running VM.
+	PowerStateOn PowerState = "poweredOn"
+	// PowerStateOff is a VM that is powered off.
+	PowerStateOff PowerState = "poweredOff"
+	// PowerStateUnknown is a VM of any other status, like suspended or
+	// being started, or whose status iCenter does not know.
+	PowerStateUnknown PowerState = "unknown"
+)
+
+// vmStatusPowerStates maps the statuses of the iCenter API to power states.
+// The SDK does not list the statuses, so only STARTED, which IsActive relies
+// on, and STOPPED are mapped. Any other status is unknown, which keeps the
+// taints of the node as they are.
+var vmStatusPowerStates = map[string]PowerState{
+	"STARTED": PowerStateOn,
+	"STOPPED": PowerStateOff,
+}
+
+// PowerState returns the power state of the VM.
+func (vm *VirtualMachine) PowerState() PowerState {
+	if vm.VirtualMachine == nil {
+		return PowerStateUnknown
+	}
+	if state, ok := vmStatusPowerStates[strings.ToUpper(strings.TrimSpace(vm.Status))]; ok {
+		return state
+	}
+	return PowerStateUnknown
+}
+
+// IsPoweredOff returns true if the VM is known to be powered off, as opposed
+// to being in an unknown state.
+func (vm *VirtualMachine) IsPoweredOff() bool {
+	return vm.PowerState() == PowerStateOff
+}
+
+// InMaintenance returns true if the host is in or entering maintenance mode.
+// The SDK does not list the host statuses either, so any status containing
+// MAINT is taken as maintenance mode.
+func (host *Host) InMaintenance() bool {
+	if host.Host == nil {
+		return false
+	}
+	return strings.Contains(strings.ToUpper(host.Status), "MAINT")
+}
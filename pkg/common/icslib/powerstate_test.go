@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"testing"
+
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+)
+
+func TestPowerState(t *testing.T) {
+	tests := []struct {
+		name       string
+		vm         *tp.VirtualMachine
+		want       PowerState
+		poweredOff bool
+	}{
+		{name: "started", vm: &tp.VirtualMachine{Status: "STARTED"}, want: PowerStateOn},
+		{name: "stopped", vm: &tp.VirtualMachine{Status: "STOPPED"}, want: PowerStateOff, poweredOff: true},
+		{name: "case and spaces", vm: &tp.VirtualMachine{Status: " stopped "}, want: PowerStateOff, poweredOff: true},
+		{name: "undocumented status", vm: &tp.VirtualMachine{Status: "SUSPENDED"}, want: PowerStateUnknown},
+		{name: "empty status", vm: &tp.VirtualMachine{}, want: PowerStateUnknown},
+		{name: "no VM", want: PowerStateUnknown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := &VirtualMachine{VirtualMachine: test.vm}
+			if got := vm.PowerState(); got != test.want {
+				t.Errorf("PowerState() = %q, want %q", got, test.want)
+			}
+			if got := vm.IsPoweredOff(); got != test.poweredOff {
+				t.Errorf("IsPoweredOff() = %v, want %v", got, test.poweredOff)
+			}
+		})
+	}
+}
+
+func TestInMaintenance(t *testing.T) {
+	tests := []struct {
+		name string
+		host *tp.Host
+		want bool
+	}{
+		{name: "connected", host: &tp.Host{Status: "CONNECTED"}, want: false},
+		{name: "maintenance", host: &tp.Host{Status: "MAINTAIN"}, want: true},
+		{name: "entering maintenance", host: &tp.Host{Status: "entering_maintenance"}, want: true},
+		{name: "no host", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			host := &Host{Host: test.host}
+			if got := host.InMaintenance(); got != test.want {
+				t.Errorf("InMaintenance() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inspur-ics/ics-go-sdk/client/methods"
+	tp "github.com/inspur-ics/ics-go-sdk/client/types"
+	"k8s.io/klog"
+)
+
+// VMRuleAntiAffinity is the type of the DRS rules keeping their VMs on
+// separate hosts.
+const VMRuleAntiAffinity = "VM_ANTI_AFFINITY"
+
+// VMRule is a DRS rule of a cluster. The SDK has no rule service, so rules
+// are managed through the REST API directly, as a sub-collection of the
+// cluster laid out like the /datacenters/{id}/vms collection of the SDK:
+// GET and POST /clusters/{id}/rules, PUT and DELETE
+// /clusters/{id}/rules/{ruleId}. The layout is not covered by the SDK and is
+// pinned by rules_test.go.
+type VMRule struct {
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type"`
+	Enabled     bool     `json:"enabled"`
+	ClusterID   string   `json:"clusterId,omitempty"`
+	VMIDs       []string `json:"vmIds"`
+}
+
+// vmRulePageResponse is a page of DRS rules, laid out as the pages of the
+// SDK.
+type vmRulePageResponse struct {
+	tp.PageResponse
+	Items []VMRule `json:"items"`
+}
+
+// ListVMRules returns the DRS rules of the cluster with the given iCenter ID.
+func ListVMRules(ctx context.Context, connection *ICSConnection, clusterID string) ([]VMRule, error) {
+	if clusterID == "" || connection == nil || connection.Client == nil {
+		return nil, ErrNoClusterFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/clusters/%s/rules", clusterID)
+	api.Token = true
+
+	resp, err := connection.Client.GetTrip(ctx, api, nil)
+	respBody, err := methods.HandleResponse(resp, err)
+	if err != nil {
+		klog.Errorf("Failed to get rules of cluster %s. err: %+v", clusterID, err)
+		return nil, err
+	}
+
+	var page vmRulePageResponse
+	if err := json.Unmarshal(respBody, &page); err != nil {
+		return nil, methods.JsonError(err)
+	}
+	for i := range page.Items {
+		if page.Items[i].ClusterID == "" {
+			page.Items[i].ClusterID = clusterID
+		}
+	}
+	return page.Items, nil
+}
+
+// CreateVMRule creates the DRS rule in its cluster.
+func CreateVMRule(ctx context.Context, connection *ICSConnection, rule *VMRule) error {
+	if rule.ClusterID == "" || connection == nil || connection.Client == nil {
+		return ErrNoClusterFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/clusters/%s/rules", rule.ClusterID)
+	api.Token = true
+
+	resp, err := connection.Client.PostTrip(ctx, api, rule)
+	if _, err := methods.HandleResponse(resp, err); err != nil {
+		klog.Errorf("Failed to create rule %s of cluster %s. err: %+v", rule.Name, rule.ClusterID, err)
+		return err
+	}
+	return nil
+}
+
+// UpdateVMRule replaces the DRS rule with the same ID in its cluster.
+func UpdateVMRule(ctx context.Context, connection *ICSConnection, rule *VMRule) error {
+	if rule.ClusterID == "" || connection == nil || connection.Client == nil {
+		return ErrNoClusterFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/clusters/%s/rules/%s", rule.ClusterID, rule.ID)
+	api.Token = true
+
+	resp, err := connection.Client.PutTrip(ctx, api, rule)
+	if _, err := methods.HandleResponse(resp, err); err != nil {
+		klog.Errorf("Failed to update rule %s of cluster %s. err: %+v", rule.Name, rule.ClusterID, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteVMRule deletes the DRS rule with the same ID from its cluster.
+func DeleteVMRule(ctx context.Context, connection *ICSConnection, rule *VMRule) error {
+	if rule.ClusterID == "" || connection == nil || connection.Client == nil {
+		return ErrNoClusterFound
+	}
+
+	var api tp.ICSApi
+	api.Api = fmt.Sprintf("/clusters/%s/rules/%s", rule.ClusterID, rule.ID)
+	api.Token = true
+
+	resp, err := connection.Client.DeleteTrip(ctx, api, nil)
+	if _, err := methods.HandleResponse(resp, err); err != nil {
+		klog.Errorf("Failed to delete rule %s of cluster %s. err: %+v", rule.Name, rule.ClusterID, err)
+		return err
+	}
+	return nil
+}
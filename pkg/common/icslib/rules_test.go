@@ -0,0 +1,162 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestListVMRules(t *testing.T) {
+	api, connection := newFakeAPI(t, map[string]fakeResponse{
+		"GET /clusters/cluster-1/rules": {body: `{"totalPage":1,"currentPage":1,"totalSize":2,"items":[
+			{"id":"rule-1","name":"kubernetes-masters","description":"managed","type":"VM_ANTI_AFFINITY",
+				"enabled":true,"vmIds":["vm-1","vm-2"]},
+			{"id":"rule-2","name":"other","type":"VM_AFFINITY","enabled":false,"clusterId":"cluster-1","vmIds":[]}]}`},
+		"GET /clusters/broken/rules":  {body: `{"items":`},
+		"GET /clusters/failing/rules": {status: http.StatusInternalServerError},
+	})
+	defer api.close()
+
+	rules, err := ListVMRules(context.Background(), connection, "cluster-1")
+	if err != nil {
+		t.Fatalf("ListVMRules() failed: %v", err)
+	}
+	want := []VMRule{
+		{ID: "rule-1", Name: "kubernetes-masters", Description: "managed", Type: VMRuleAntiAffinity, Enabled: true,
+			ClusterID: "cluster-1", VMIDs: []string{"vm-1", "vm-2"}},
+		{ID: "rule-2", Name: "other", Type: "VM_AFFINITY", ClusterID: "cluster-1", VMIDs: []string{}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ListVMRules() = %+v, want %+v", rules, want)
+	}
+
+	requests := api.received()
+	if len(requests) != 1 || requests[0].method != http.MethodGet || requests[0].path != "/clusters/cluster-1/rules" ||
+		requests[0].authorization != fakeToken {
+		t.Errorf("Received %+v, want GET /clusters/cluster-1/rules", requests)
+	}
+
+	for _, clusterID := range []string{"broken", "failing", "unknown"} {
+		if rules, err := ListVMRules(context.Background(), connection, clusterID); err == nil {
+			t.Errorf("ListVMRules(%s) = %+v, want an error", clusterID, rules)
+		}
+	}
+	if _, err := ListVMRules(context.Background(), connection, ""); err != ErrNoClusterFound {
+		t.Errorf("ListVMRules() without a cluster = %v, want %v", err, ErrNoClusterFound)
+	}
+}
+
+func TestWriteVMRule(t *testing.T) {
+	rule := VMRule{
+		ID:          "rule-1",
+		Name:        "kubernetes-masters",
+		Description: "managed",
+		Type:        VMRuleAntiAffinity,
+		Enabled:     true,
+		ClusterID:   "cluster-1",
+		VMIDs:       []string{"vm-1", "vm-2"},
+	}
+	newRule := rule
+	newRule.ID = ""
+
+	tests := []struct {
+		name       string
+		write      func(context.Context, *ICSConnection, *VMRule) error
+		rule       VMRule
+		wantMethod string
+		wantPath   string
+		// body sent, if any
+		wantBody map[string]interface{}
+	}{
+		{
+			name:       "create",
+			write:      CreateVMRule,
+			rule:       newRule,
+			wantMethod: http.MethodPost,
+			wantPath:   "/clusters/cluster-1/rules",
+			wantBody: map[string]interface{}{
+				"name": "kubernetes-masters", "description": "managed", "type": "VM_ANTI_AFFINITY",
+				"enabled": true, "clusterId": "cluster-1", "vmIds": []interface{}{"vm-1", "vm-2"},
+			},
+		},
+		{
+			name:       "update",
+			write:      UpdateVMRule,
+			rule:       rule,
+			wantMethod: http.MethodPut,
+			wantPath:   "/clusters/cluster-1/rules/rule-1",
+			wantBody: map[string]interface{}{
+				"id": "rule-1", "name": "kubernetes-masters", "description": "managed", "type": "VM_ANTI_AFFINITY",
+				"enabled": true, "clusterId": "cluster-1", "vmIds": []interface{}{"vm-1", "vm-2"},
+			},
+		},
+		{
+			name:       "delete",
+			write:      DeleteVMRule,
+			rule:       rule,
+			wantMethod: http.MethodDelete,
+			wantPath:   "/clusters/cluster-1/rules/rule-1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api, connection := newFakeAPI(t, map[string]fakeResponse{
+				test.wantMethod + " " + test.wantPath: {body: `{}`},
+			})
+			defer api.close()
+
+			rule := test.rule
+			if err := test.write(context.Background(), connection, &rule); err != nil {
+				t.Fatalf("%s failed: %v", test.name, err)
+			}
+
+			requests := api.received()
+			if len(requests) != 1 {
+				t.Fatalf("Received %d requests, want 1", len(requests))
+			}
+			request := requests[0]
+			if request.method != test.wantMethod || request.path != test.wantPath || request.authorization != fakeToken {
+				t.Errorf("Received %s %s with authorization %q, want %s %s",
+					request.method, request.path, request.authorization, test.wantMethod, test.wantPath)
+			}
+			if test.wantBody != nil {
+				var body map[string]interface{}
+				if err := json.Unmarshal([]byte(request.body), &body); err != nil {
+					t.Fatalf("Invalid body %q: %v", request.body, err)
+				}
+				if !reflect.DeepEqual(body, test.wantBody) {
+					t.Errorf("Body is %v, want %v", body, test.wantBody)
+				}
+			}
+
+			// Failures of iCenter are returned
+			rule.ClusterID = "other"
+			if err := test.write(context.Background(), connection, &rule); err == nil {
+				t.Errorf("%s of an unknown cluster succeeded", test.name)
+			}
+			rule.ClusterID = ""
+			if err := test.write(context.Background(), connection, &rule); err != ErrNoClusterFound {
+				t.Errorf("%s without a cluster = %v, want %v", test.name, err, ErrNoClusterFound)
+			}
+		})
+	}
+}
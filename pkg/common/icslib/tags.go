@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"encoding/json"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// Tag is a tag of a VirtualMachine. Tags with a value, named key=value or in
+// a category, are custom attributes.
+type Tag struct {
+	// Name of the tag, or of the category of the tag.
+	Name string
+	// Value of the attribute, or the tag in the category. Empty for plain
+	// tags.
+	Value string
+}
+
+// sdkTag is a tag as returned by iCenter. The SDK types the tags of VMs and
+// hosts as interface{} and has no tag type, so they are decoded like the tags
+// of the tag service: a name in a category. Tags may also be returned as
+// plain names.
+type sdkTag struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	CategoryID   string `json:"categoryId"`
+	CategoryName string `json:"categoryName"`
+}
+
+// VMTags returns the tags of the VM.
+func (vm *VirtualMachine) VMTags() []Tag {
+	if vm.VirtualMachine == nil {
+		return nil
+	}
+	return parseTags(vm.Tags)
+}
+
+// parseTags returns the tags of an object returned by iCenter, a list of tag
+// objects or names. A tag in a category is returned as an attribute named
+// after the category.
+func parseTags(value interface{}) []Tag {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		klog.V(4).Infof("Ignoring tags %s, they are not a list", data)
+		return nil
+	}
+
+	var tags []Tag
+	for _, item := range items {
+		var name string
+		if err := json.Unmarshal(item, &name); err == nil {
+			tags = appendTag(tags, parseTag(name))
+			continue
+		}
+		var tag sdkTag
+		if err := json.Unmarshal(item, &tag); err != nil {
+			klog.V(4).Infof("Ignoring tag %s. err: %v", item, err)
+			continue
+		}
+		if category := strings.TrimSpace(tag.CategoryName); category != "" {
+			tags = appendTag(tags, Tag{Name: category, Value: strings.TrimSpace(tag.Name)})
+			continue
+		}
+		tags = appendTag(tags, parseTag(tag.Name))
+	}
+	return tags
+}
+
+// tagValue returns the value of the attribute or category with the name, or
+// an empty string if there is none.
+func tagValue(tags []Tag, name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Name, name) {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// appendTag appends tag to tags unless it has no name.
+func appendTag(tags []Tag, tag Tag) []Tag {
+	if tag.Name == "" {
+		return tags
+	}
+	return append(tags, tag)
+}
+
+// parseTag parses a tag named name or name=value.
+func parseTag(tag string) Tag {
+	parts := strings.SplitN(tag, "=", 2)
+	parsed := Tag{Name: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 {
+		parsed.Value = strings.TrimSpace(parts[1])
+	}
+	return parsed
+}
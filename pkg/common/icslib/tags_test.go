@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name string
+		// JSON of the tags field
+		tags string
+		want []Tag
+	}{
+		{
+			name: "tags in categories",
+			tags: `[{"id":"t1","name":"zone-a","categoryId":"c1","categoryName":"k8s-zone"},
+				{"id":"t2","name":"gpu-pool","categoryId":"c2","categoryName":"role"}]`,
+			want: []Tag{{Name: "k8s-zone", Value: "zone-a"}, {Name: "role", Value: "gpu-pool"}},
+		},
+		{
+			name: "tags without a category",
+			tags: `[{"id":"t1","name":"ingress"},{"id":"t2","name":"pool=gpu"}]`,
+			want: []Tag{{Name: "ingress"}, {Name: "pool", Value: "gpu"}},
+		},
+		{
+			name: "names",
+			tags: `["maintenance"," tier = web ",""]`,
+			want: []Tag{{Name: "maintenance"}, {Name: "tier", Value: "web"}},
+		},
+		{
+			name: "not a list",
+			tags: `"ingress,gpu-pool"`,
+			want: nil,
+		},
+		{
+			name: "no tags",
+			tags: `null`,
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(test.tags), &value); err != nil {
+				t.Fatalf("invalid test tags: %v", err)
+			}
+			if got := parseTags(value); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseTags() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// TLSConfig returns the TLS configuration used to talk to iCenter.
+// A configured thumbprint pins the iCenter leaf certificate and takes
+// precedence over everything else. Otherwise the certificate chain is
+// verified against CAData, CACert, or the system roots, unless Insecure is set.
+// The caller holds ICSCredentialsLock, as CAData may be updated concurrently.
+func (connection *ICSConnection) TLSConfig() (*tls.Config, error) {
+	if connection.Thumbprint != "" {
+		expected, err := ParseThumbprint(connection.Thumbprint)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			// Chain verification is replaced by the thumbprint check below
+			InsecureSkipVerify: true,
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 {
+					return ErrThumbprintMismatch
+				}
+				return verifyThumbprint(rawCerts[0], expected)
+			},
+		}, nil
+	}
+
+	if connection.Insecure {
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if connection.CAData != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(connection.CAData)) {
+			klog.Errorf("No certificates found in the CA data of %s", connection.Hostname)
+			return nil, ErrInvalidCACert
+		}
+		tlsConfig.RootCAs = pool
+	} else if connection.CACert != "" {
+		pem, err := ioutil.ReadFile(connection.CACert)
+		if err != nil {
+			klog.Errorf("Failed to read CA file %s. err: %+v", connection.CACert, err)
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			klog.Errorf("No certificates found in CA file %s", connection.CACert)
+			return nil, ErrInvalidCACert
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// ParseThumbprint decodes a colon-separated hex fingerprint. Only SHA-1 and
+// SHA-256 sized fingerprints are accepted.
+func ParseThumbprint(thumbprint string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.Replace(strings.TrimSpace(thumbprint), ":", "", -1))
+	if err != nil {
+		return nil, ErrInvalidThumbprint
+	}
+	if len(raw) != sha1.Size && len(raw) != sha256.Size {
+		return nil, ErrInvalidThumbprint
+	}
+	return raw, nil
+}
+
+// verifyThumbprint compares the fingerprint of the DER encoded certificate
+// against the expected one, hashing with the algorithm implied by its size.
+func verifyThumbprint(cert []byte, expected []byte) error {
+	var actual []byte
+	if len(expected) == sha256.Size {
+		sum := sha256.Sum256(cert)
+		actual = sum[:]
+	} else {
+		sum := sha1.Sum(cert)
+		actual = sum[:]
+	}
+
+	if !bytes.Equal(actual, expected) {
+		klog.Errorf("iCenter certificate thumbprint %s does not match the configured one", hex.EncodeToString(actual))
+		return ErrThumbprintMismatch
+	}
+	return nil
+}
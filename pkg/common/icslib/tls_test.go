@@ -0,0 +1,243 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// colonHex formats a fingerprint as uppercase colon-separated hex.
+func colonHex(raw []byte) string {
+	var parts []string
+	for _, b := range raw {
+		parts = append(parts, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+	return strings.Join(parts, ":")
+}
+
+func TestParseThumbprint(t *testing.T) {
+	sha1Sum := sha1.Sum([]byte("cert"))
+	sha256Sum := sha256.Sum256([]byte("cert"))
+
+	tests := []struct {
+		name       string
+		thumbprint string
+		want       []byte
+		wantErr    bool
+	}{
+		{name: "SHA-1 with colons", thumbprint: colonHex(sha1Sum[:]), want: sha1Sum[:]},
+		{name: "SHA-1 lowercase without colons", thumbprint: hex.EncodeToString(sha1Sum[:]), want: sha1Sum[:]},
+		{name: "SHA-256 with colons", thumbprint: colonHex(sha256Sum[:]), want: sha256Sum[:]},
+		{name: "surrounding whitespace", thumbprint: " " + colonHex(sha256Sum[:]) + "\n", want: sha256Sum[:]},
+		{name: "MD5 size", thumbprint: colonHex(sha1Sum[:16]), wantErr: true},
+		{name: "not hex", thumbprint: "zz:" + colonHex(sha1Sum[1:]), wantErr: true},
+		{name: "empty", thumbprint: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseThumbprint(test.thumbprint)
+			if test.wantErr {
+				if err != ErrInvalidThumbprint {
+					t.Fatalf("ParseThumbprint() error = %v, want %v", err, ErrInvalidThumbprint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseThumbprint() failed: %v", err)
+			}
+			if hex.EncodeToString(got) != hex.EncodeToString(test.want) {
+				t.Errorf("ParseThumbprint() = %x, want %x", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	cert := server.Certificate()
+	caData := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	otherSum := sha256.Sum256([]byte("other"))
+
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	notPEMFile := filepath.Join(dir, "not-pem.pem")
+	if err := ioutil.WriteFile(caFile, []byte(caData), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(notPEMFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		connection *ICSConnection
+		wantErr    error
+		wantAnyErr bool
+		// whether a request to the test server succeeds with the config
+		wantConnect bool
+	}{
+		{
+			name:        "CA data",
+			connection:  &ICSConnection{CAData: caData},
+			wantConnect: true,
+		},
+		{
+			name:        "CA file",
+			connection:  &ICSConnection{CACert: caFile},
+			wantConnect: true,
+		},
+		{
+			name:        "CA data takes precedence over CA file",
+			connection:  &ICSConnection{CAData: caData, CACert: filepath.Join(dir, "missing.pem")},
+			wantConnect: true,
+		},
+		{
+			name:       "system roots",
+			connection: &ICSConnection{},
+		},
+		{
+			name:        "insecure takes precedence over CA",
+			connection:  insecureConnection(&ICSConnection{CAData: "not a certificate"}),
+			wantConnect: true,
+		},
+		{
+			name:        "SHA-1 thumbprint",
+			connection:  &ICSConnection{Thumbprint: colonHex(sha1Sum[:])},
+			wantConnect: true,
+		},
+		{
+			name:        "SHA-256 thumbprint takes precedence over CA",
+			connection:  &ICSConnection{Thumbprint: hex.EncodeToString(sha256Sum[:]), CAData: "not a certificate"},
+			wantConnect: true,
+		},
+		{
+			name:       "thumbprint mismatch even if insecure",
+			connection: insecureConnection(&ICSConnection{Thumbprint: colonHex(otherSum[:])}),
+		},
+		{
+			name:       "invalid thumbprint",
+			connection: &ICSConnection{Thumbprint: "00:11"},
+			wantErr:    ErrInvalidThumbprint,
+		},
+		{
+			name:       "invalid CA data",
+			connection: &ICSConnection{CAData: "not a certificate"},
+			wantErr:    ErrInvalidCACert,
+		},
+		{
+			name:       "CA file without certificates",
+			connection: &ICSConnection{CACert: notPEMFile},
+			wantErr:    ErrInvalidCACert,
+		},
+		{
+			name:       "missing CA file",
+			connection: &ICSConnection{CACert: filepath.Join(dir, "missing.pem")},
+			wantAnyErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig, err := test.connection.TLSConfig()
+			if test.wantErr != nil || test.wantAnyErr {
+				if err == nil || (test.wantErr != nil && err != test.wantErr) {
+					t.Fatalf("TLSConfig() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TLSConfig() failed: %v", err)
+			}
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if connected := err == nil; connected != test.wantConnect {
+				t.Errorf("request succeeded = %v, want %v: %v", connected, test.wantConnect, err)
+			}
+		})
+	}
+}
+
+// insecureConnection sets the insecure flag of the embedded SDK connection.
+func insecureConnection(connection *ICSConnection) *ICSConnection {
+	connection.Insecure = true
+	return connection
+}
+
+func TestNewClientTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/authentication") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"userId":"user","sessonId":"session"}`))
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "https://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(server.Certificate().Raw)
+
+	tests := []struct {
+		name       string
+		thumbprint string
+		wantErr    bool
+	}{
+		{name: "pinned certificate", thumbprint: colonHex(sum[:])},
+		{name: "unknown authority", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connection := &ICSConnection{Thumbprint: test.thumbprint}
+			connection.Hostname = host
+			connection.Port = port
+
+			client, err := connection.GetClient(context.Background())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("GetClient() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err == nil && client.GetToken() != "session" {
+				t.Errorf("GetClient() token = %q, want %q", client.GetToken(), "session")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package icslib
+
+import (
+	"context"
+
+	"github.com/inspur-ics/ics-go-sdk/client/methods"
+	"k8s.io/klog"
+)
+
+// GetVMByID returns the VM with the given iCenter ID, as currently known by
+// iCenter.
+func GetVMByID(ctx context.Context, connection *ICSConnection, vmID string) (*VirtualMachine, error) {
+	if vmID == "" || connection == nil || connection.Client == nil {
+		return nil, ErrNoVMFound
+	}
+
+	vm, err := methods.GetVMById(ctx, connection.Client, vmID)
+	if err != nil {
+		klog.Errorf("Failed to get VM %s. err: %+v", vmID, err)
+		return nil, err
+	}
+	if vm == nil || vm.ID == "" {
+		return nil, ErrNoVMFound
+	}
+	return &VirtualMachine{vm}, nil
+}
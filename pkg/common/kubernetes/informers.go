@@ -20,6 +20,8 @@ import (
 	"sync"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	listerv1 "k8s.io/client-go/listers/core/v1"
@@ -33,24 +35,74 @@ func noResyncPeriodFunc() time.Duration {
 
 var (
 	signalHandler   <-chan struct{}
+	onceForSignal   sync.Once
 	informerFactory informers.SharedInformerFactory
 	onceForInformer sync.Once
 )
 
+// stopChannel returns the process-wide signal handler. It can only be set up
+// once, so every informer shares it.
+func stopChannel() <-chan struct{} {
+	onceForSignal.Do(func() {
+		signalHandler = signals.SetupSignalHandler()
+	})
+	return signalHandler
+}
+
 // NewInformer creates a newk8s client based on a service account
 func NewInformer(client clientset.Interface, singleWatcher bool) *InformerManager {
 	onceForInformer.Do(func() {
-		signalHandler = signals.SetupSignalHandler()
 		informerFactory = informers.NewSharedInformerFactory(client, noResyncPeriodFunc())
 	})
 
 	return &InformerManager{
 		client:          client,
-		stopCh:          signalHandler,
+		stopCh:          stopChannel(),
 		informerFactory: informerFactory,
 	}
 }
 
+// NewSecretInformer creates an InformerManager whose informers only watch the
+// given namespace. If name is set, the Secret informer is further restricted
+// to that Secret with a field selector, which allows RBAC to grant access by
+// resource name.
+func NewSecretInformer(client clientset.Interface, namespace string, name string) *InformerManager {
+	options := []informers.SharedInformerOption{informers.WithNamespace(namespace)}
+	if name != "" {
+		selector := fields.OneTermEqualSelector("metadata.name", name).String()
+		options = append(options, informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = selector
+		}))
+	}
+
+	// The informers can be stopped on their own when the secret is no longer
+	// referenced, and stop with the process otherwise.
+	stopCh := make(chan struct{})
+	signalCh := stopChannel()
+	im := &InformerManager{
+		client:          client,
+		stopCh:          stopCh,
+		stop:            func() { close(stopCh) },
+		informerFactory: informers.NewSharedInformerFactoryWithOptions(client, noResyncPeriodFunc(), options...),
+	}
+	go func() {
+		select {
+		case <-signalCh:
+			im.Stop()
+		case <-stopCh:
+		}
+	}()
+	return im
+}
+
+// Stop stops the informers of an InformerManager created by
+// NewSecretInformer. It is a no-op for the shared InformerManager.
+func (im *InformerManager) Stop() {
+	if im.stop != nil {
+		im.stopOnce.Do(im.stop)
+	}
+}
+
 // GetSecretLister creates a lister to use
 func (im *InformerManager) GetSecretLister() listerv1.SecretLister {
 	if im.secretInformer == nil {
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func secret(namespace, name string) *v1.Secret {
+	return &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+func TestNewSecretInformerScoping(t *testing.T) {
+	tests := []struct {
+		name          string
+		namespace     string
+		secretName    string
+		fieldSelector string
+		listed        []string
+	}{
+		{
+			name:      "namespace only",
+			namespace: "kube-system",
+			listed:    []string{"icsccm", "other"},
+		},
+		{
+			name:          "namespace and name",
+			namespace:     "ics",
+			secretName:    "icsccm",
+			fieldSelector: "metadata.name=icsccm",
+			listed:        []string{"icsccm"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(
+				secret("kube-system", "icsccm"),
+				secret("kube-system", "other"),
+				secret("ics", "icsccm"),
+				secret("default", "icsccm"),
+			)
+
+			im := NewSecretInformer(client, test.namespace, test.secretName)
+			defer im.Stop()
+			lister := im.GetSecretLister()
+			im.Listen()
+			if !cache.WaitForCacheSync(im.stopCh, im.secretInformer.Informer().HasSynced) {
+				t.Fatal("Secret informer did not sync")
+			}
+
+			var lists int
+			for _, action := range client.Actions() {
+				if action.GetResource().Resource != "secrets" {
+					continue
+				}
+				if action.GetNamespace() != test.namespace {
+					t.Errorf("%s of secrets in namespace %q, expected %q", action.GetVerb(), action.GetNamespace(), test.namespace)
+				}
+				if list, ok := action.(k8stesting.ListAction); ok {
+					lists++
+					if fields := list.GetListRestrictions().Fields.String(); fields != test.fieldSelector {
+						t.Errorf("List field selector is %q, expected %q", fields, test.fieldSelector)
+					}
+				}
+			}
+			if lists == 0 {
+				t.Error("Secrets were never listed")
+			}
+
+			// The fake clientset ignores field selectors, so the lister only
+			// shows the namespace scoping.
+			secrets, err := lister.List(labels.Everything())
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			for _, s := range secrets {
+				if s.Namespace != test.namespace {
+					t.Errorf("Lister returned secret %s/%s outside of %q", s.Namespace, s.Name, test.namespace)
+				}
+			}
+			if test.secretName == "" {
+				var names []string
+				for _, s := range secrets {
+					names = append(names, s.Name)
+				}
+				sort.Strings(names)
+				if !reflect.DeepEqual(names, test.listed) {
+					t.Errorf("Lister returned %v, expected %v", names, test.listed)
+				}
+			}
+		})
+	}
+}
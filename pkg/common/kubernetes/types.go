@@ -17,6 +17,8 @@ limitations under the License.
 package kubernetes
 
 import (
+	"sync"
+
 	"k8s.io/client-go/informers"
 	v1 "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
@@ -32,6 +34,9 @@ type InformerManager struct {
 	informerFactory informers.SharedInformerFactory
 	// main signal
 	stopCh (<-chan struct{})
+	// stops the informers, only set for informers that can be stopped
+	stop     func()
+	stopOnce sync.Once
 
 	// secret informer
 	secretInformer v1.SecretInformer